@@ -74,19 +74,25 @@ func newApplyCommand(config *specs.LxdComposeConfig) *cobra.Command {
 			}
 
 			skipSync, _ := cmd.Flags().GetBool("skip-sync")
+			skipReconcile, _ := cmd.Flags().GetBool("skip-reconcile")
 			destroy, _ := cmd.Flags().GetBool("destroy")
 			upgrade, _ := cmd.Flags().GetBool("upgrade")
 			ask, _ := cmd.Flags().GetBool("ask")
 			prefix, _ := cmd.Flags().GetString("nodes-prefix")
+			resume, _ := cmd.Flags().GetBool("resume")
+			restartFrom, _ := cmd.Flags().GetString("restart-from")
 
 			composer.SetFlagsDisabled(disabledFlags)
 			composer.SetFlagsEnabled(enabledFlags)
 			composer.SetGroupsDisabled(disabledGroups)
 			composer.SetGroupsEnabled(enabledGroups)
 			composer.SetSkipSync(skipSync)
+			composer.SetSkipReconcile(skipReconcile)
 			composer.SetNodesPrefix(prefix)
 			composer.SetUpgradeMode(upgrade)
 			composer.SetAskMode(ask)
+			composer.SetResumeMode(resume)
+			composer.SetRestartFrom(restartFrom)
 
 			projects := args[0:]
 
@@ -131,6 +137,7 @@ func newApplyCommand(config *specs.LxdComposeConfig) *cobra.Command {
 				err = composer.ApplyProject(proj)
 				if err != nil {
 					fmt.Println("Error on apply project " + proj + ": " + err.Error())
+					fmt.Println("Use --resume to continue from the last step completed.")
 					os.Exit(1)
 				}
 
@@ -157,10 +164,16 @@ func newApplyCommand(config *specs.LxdComposeConfig) *cobra.Command {
 	flags.StringSliceVar(&varsFiles, "vars-file", []string{},
 		"Add additional environments vars file.")
 	flags.Bool("skip-sync", false, "Disable sync of files.")
+	flags.Bool("skip-reconcile", false,
+		"Disable the update of profiles and config of the existing nodes.")
 	flags.Bool("upgrade", false, "Enable upgrade mode.")
 	flags.Bool("ask", false, "Ask confirm before upgrade every single node.")
 	flags.Bool("destroy", false, "Destroy the selected groups at the end.")
 	flags.String("nodes-prefix", "", "Customize project nodes name with a prefix")
+	flags.Bool("resume", false,
+		"Resume the last apply failed skipping the steps already completed.")
+	flags.String("restart-from", "",
+		"Resume the last apply failed from the specified step in the format node/event.")
 
 	return cmd
 }
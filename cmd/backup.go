@@ -7,7 +7,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -26,8 +25,17 @@ func newBackupCommand(config *specs.LxdComposeConfig) *cobra.Command {
 	var profiles2remove []string
 
 	var cmd = &cobra.Command{
-		Use:     "backup [list-of-projects]",
-		Short:   "Backup the container of the listed projects.",
+		Use:   "backup [list-of-projects]",
+		Short: "Backup the container of the listed projects.",
+		Long: `Backup the container of the listed projects.
+
+Without --export-dir the containers are copied on the same remote with
+the name <node>-YYYYMMDD. With --export-dir the containers are exported
+as tarballs under the directory <export-dir>/<project>/<date> with
+a manifest of the nodes that could be used by the restore command.
+
+The backups that don't match any of --max-backups, --keep-daily and
+--keep-weekly are removed.`,
 		Aliases: []string{"f"},
 		PreRun: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
@@ -55,8 +63,19 @@ func newBackupCommand(config *specs.LxdComposeConfig) *cobra.Command {
 
 			prefix, _ := cmd.Flags().GetString("nodes-prefix")
 			maxBackups, _ := cmd.Flags().GetInt("max-backups")
+			keepDaily, _ := cmd.Flags().GetInt("keep-daily")
+			keepWeekly, _ := cmd.Flags().GetInt("keep-weekly")
+			exportDir, _ := cmd.Flags().GetString("export-dir")
+			optimized, _ := cmd.Flags().GetBool("optimized")
+			compression, _ := cmd.Flags().GetString("compression")
 			ret := 0
 
+			retention := &specs.LxdCBackupRetention{
+				KeepLast:   maxBackups,
+				KeepDaily:  keepDaily,
+				KeepWeekly: keepWeekly,
+			}
+
 			composer.SetGroupsDisabled(disabledGroups)
 			composer.SetGroupsEnabled(enabledGroups)
 			composer.SetNodesPrefix(prefix)
@@ -65,7 +84,40 @@ func newBackupCommand(config *specs.LxdComposeConfig) *cobra.Command {
 			mapExecutors := make(map[string]lxd_executor.LxdCExecutor, 0)
 
 			t := time.Now()
-			containerPostfix := t.Format("20060102")
+			containerPostfix := t.Format(specs.BackupCopyDateFormat)
+
+			if exportDir != "" {
+				for _, proj := range projects {
+					composer.Logger.Info(":rocket:Export containers for project " + proj + "...")
+
+					backupDir, err := composer.ExportProjectBackup(proj, exportDir,
+						optimized, compression)
+					if err != nil {
+						fmt.Println("Error on export project " + proj + ": " + err.Error())
+						os.Exit(1)
+					}
+
+					composer.Logger.InfoC(
+						fmt.Sprintf(":icecream:%s Backup stored in %s. :check_mark:",
+							composer.Logger.Aurora.Bold(
+								composer.Logger.Aurora.BrightCyan(
+									fmt.Sprintf("[%s]", proj))), backupDir))
+
+					if retention.IsEnabled() {
+						err = composer.PurgeProjectBackups(proj, exportDir, retention)
+						if err != nil {
+							fmt.Println("Error on purge backups of project " + proj + ": " + err.Error())
+							os.Exit(1)
+						}
+					}
+				}
+
+				composer.Logger.InfoC(
+					fmt.Sprintf(":chequered_flag:%s :chequered_flag:",
+						composer.Logger.Aurora.Bold("All done!")),
+				)
+				return
+			}
 
 			for _, proj := range projects {
 
@@ -197,11 +249,11 @@ func newBackupCommand(config *specs.LxdComposeConfig) *cobra.Command {
 
 						}
 
-						if maxBackups > 0 {
+						if retention.IsEnabled() {
 							// POST: We need to maintain limited number of backups.
 
 							// Retrieve the list of container.
-							list, err := executor.GetContainerList()
+							list, err := executor.GetInstanceList()
 							if err != nil {
 								composer.Logger.Error(
 									fmt.Sprintf(
@@ -223,12 +275,9 @@ func newBackupCommand(config *specs.LxdComposeConfig) *cobra.Command {
 								}
 							}
 
-							if len(matchedDates) > 0 && len(matchedDates) > maxBackups {
-								sort.Strings(matchedDates)
-
-								backup2remove := len(matchedDates) - maxBackups
-								for i := 0; i < backup2remove; i++ {
-									cname := node.GetName() + "-" + matchedDates[i]
+							if len(matchedDates) > 0 {
+								for _, date := range retention.GetBackupsToRemove(matchedDates) {
+									cname := node.GetName() + "-" + date
 									composer.Logger.InfoC(
 										composer.Logger.Aurora.Bold(
 											fmt.Sprintf(":knife:[%s] Removing container...",
@@ -274,7 +323,15 @@ func newBackupCommand(config *specs.LxdComposeConfig) *cobra.Command {
 	flags.StringSliceVar(&renderEnvs, "render-env", []string{},
 		"Append render engine environments in the format key=value.")
 	flags.String("nodes-prefix", "", "Customize project nodes name with a prefix")
-	flags.Int("max-backups", 0, "Number of max backups to keep. 0 means no limit.")
+	flags.Int("max-backups", 0,
+		"Number of max backups to keep. 0 means no limit if --keep-daily and --keep-weekly are not set.")
+	flags.Int("keep-daily", 0, "Number of days with the last backup of the day to keep.")
+	flags.Int("keep-weekly", 0, "Number of weeks with the last backup of the week to keep.")
+	flags.String("export-dir", "", "Export the containers as tarballs in the directory.")
+	flags.Bool("optimized", false, "Export the containers with the storage pool optimized format.")
+	flags.String("compression", specs.BackupDefaultCompression, "Compression algorithm of the exported tarballs (gzip, xz, zstd, none).")
+
+	cmd.AddCommand(newBackupRestoreCommand(config))
 
 	return cmd
 }
@@ -0,0 +1,106 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	loader "github.com/MottainaiCI/lxd-compose/pkg/loader"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	"github.com/spf13/cobra"
+)
+
+func newBackupRestoreCommand(config *specs.LxdComposeConfig) *cobra.Command {
+	var renderEnvs []string
+	var nodes []string
+
+	var cmd = &cobra.Command{
+		Use:   "restore [backup-dir]",
+		Short: "Restore the containers of a backup exported with --export-dir.",
+		Long: `Restore the containers of a backup exported with --export-dir.
+
+By default the containers are restored on the connection of the group
+available in the manifest. With --to-group the containers are restored
+on the connection of another group of the project.
+
+The restore fails without restore any container if a container of the
+backup is already present.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			toGroup, _ := cmd.Flags().GetString("to-group")
+			pool, _ := cmd.Flags().GetString("pool")
+
+			// Create Instance
+			composer := loader.NewLxdCInstance(config)
+
+			// We need set this before loading phase
+			err := config.SetRenderEnvs(renderEnvs)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			err = composer.LoadEnvironments()
+			if err != nil {
+				fmt.Println("Error on load environments:" + err.Error() + "\n")
+				os.Exit(1)
+			}
+
+			backupDir := args[0]
+			opts := &loader.LxdCBackupRestoreOpts{
+				Nodes: nodes,
+				Pool:  pool,
+			}
+
+			if toGroup != "" {
+				manifest, err := specs.BackupManifestFromFile(
+					filepath.Join(backupDir, specs.BackupManifestFile))
+				if err != nil {
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
+
+				env := composer.GetEnvByProjectName(manifest.Project)
+				if env == nil {
+					fmt.Println("Project " + manifest.Project + " not found")
+					os.Exit(1)
+				}
+
+				grp := env.GetProjectByName(manifest.Project).GetGroupByName(toGroup)
+				if grp == nil {
+					fmt.Println("Group " + toGroup + " not found")
+					os.Exit(1)
+				}
+
+				opts.Connection = grp.Connection
+				opts.ConnectionType = grp.ConnectionType
+			}
+
+			err = composer.RestoreProjectBackup(backupDir, opts)
+			if err != nil {
+				fmt.Println("Error on restore backup " + backupDir + ": " + err.Error())
+				os.Exit(1)
+			}
+
+			composer.Logger.InfoC(
+				fmt.Sprintf(":chequered_flag:%s :chequered_flag:",
+					composer.Logger.Aurora.Bold("All done!")),
+			)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringSliceVar(&nodes, "node", []string{},
+		"Restore only the selected nodes.")
+	flags.StringSliceVar(&renderEnvs, "render-env", []string{},
+		"Append render engine environments in the format key=value.")
+	flags.String("to-group", "", "Restore the containers on the connection of the group.")
+	flags.String("pool", "", "Storage pool of the restored containers.")
+
+	return cmd
+}
@@ -36,6 +36,7 @@ func newDiagnoseCommand(config *specs.LxdComposeConfig) *cobra.Command {
 
 	cmd.AddCommand(
 		NewVarsCommand(config),
+		NewHooksCommand(config),
 	)
 
 	return cmd
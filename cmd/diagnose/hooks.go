@@ -0,0 +1,149 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd_diagnose
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	loader "github.com/MottainaiCI/lxd-compose/pkg/loader"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/spf13/cobra"
+)
+
+func NewHooksCommand(config *specs.LxdComposeConfig) *cobra.Command {
+	var renderEnvs []string
+	var envs []string
+	var varsFiles []string
+	var enabledFlags []string
+	var disabledFlags []string
+
+	var cmd = &cobra.Command{
+		Use:   "hooks [project]",
+		Short: "Show the hooks of the project with the evaluation of the when expressions.",
+		Args:  cobra.MaximumNArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println("Missing project name param")
+				os.Exit(1)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			jsonFormat, _ := cmd.Flags().GetBool("json")
+
+			// Create Instance
+			composer := loader.NewLxdCInstance(config)
+
+			// We need set this before loading phase
+			err := config.SetRenderEnvs(renderEnvs)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			err = composer.LoadEnvironments()
+			if err != nil {
+				fmt.Println("Error on load environments:" + err.Error() + "\n")
+				os.Exit(1)
+			}
+
+			composer.SetFlagsEnabled(enabledFlags)
+			composer.SetFlagsDisabled(disabledFlags)
+
+			pName := args[0]
+
+			env := composer.GetEnvByProjectName(pName)
+			if env == nil {
+				fmt.Println("Project " + pName + " not found")
+				os.Exit(1)
+			}
+
+			proj := env.GetProjectByName(pName)
+
+			for _, varFile := range varsFiles {
+				err := proj.LoadEnvVarsFile(varFile, config)
+				if err != nil {
+					fmt.Println(fmt.Sprintf(
+						"Error on load additional envs var file %s: %s",
+						varFile, err.Error()))
+					os.Exit(1)
+				}
+			}
+
+			if len(envs) > 0 {
+				evars := specs.NewEnvVars()
+				for _, e := range envs {
+					err := evars.AddKVAggregated(e)
+					if err != nil {
+						fmt.Println(
+							fmt.Sprintf(
+								"Error on elaborate var string %s: %s",
+								e, err.Error(),
+							))
+						os.Exit(1)
+					}
+				}
+
+				proj.AddEnvironment(evars)
+			}
+
+			list := composer.DiagnoseHooksWhen(proj)
+
+			if jsonFormat {
+				data, _ := json.Marshal(list)
+				fmt.Println(string(data))
+				return
+			}
+
+			table := tablewriter.NewTable(os.Stdout,
+				tablewriter.WithRendition(tw.Rendition{
+					Borders: tw.Border{
+						Left:   tw.On,
+						Top:    tw.Off,
+						Right:  tw.On,
+						Bottom: tw.Off,
+					},
+					Symbols: tw.NewSymbols(tw.StyleASCII),
+				}),
+			)
+			table.Header([]string{
+				"Event", "Group", "Node", "When", "Result",
+			})
+
+			for _, s := range list {
+				result := fmt.Sprintf("%v", s.Result)
+				if s.Error != "" {
+					result = "error: " + s.Error
+				}
+
+				table.Append([]string{
+					s.Event, s.Group, s.Node, s.When, result,
+				})
+			}
+
+			table.Render()
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.Bool("json", false, "JSON output.")
+	flags.StringArrayVar(&renderEnvs, "render-env", []string{},
+		"Append render engine environments in the format key=value.")
+	flags.StringArrayVar(&envs, "env", []string{},
+		"Append project environments in the format key=value.")
+	flags.StringSliceVar(&varsFiles, "vars-file", []string{},
+		"Add additional environments vars file.")
+	flags.StringSliceVar(&enabledFlags, "enable-flag", []string{},
+		"Show hooks of only specified flags.")
+	flags.StringSliceVar(&disabledFlags, "disable-flag", []string{},
+		"Hide the hooks with the specified flags.")
+
+	return cmd
+}
@@ -83,8 +83,9 @@ func newFetchCommand(config *specs.LxdComposeConfig) *cobra.Command {
 					for _, node := range grp.Nodes {
 
 						key := fmt.Sprintf(
-							"%s|%s|%s",
+							"%s|%s|%s|%s",
 							grp.Connection, node.ImageSource, node.ImageRemoteServer,
+							node.GetType(),
 						)
 
 						if _, ok := mapExecutors[key]; !ok {
@@ -119,7 +120,7 @@ func newFetchCommand(config *specs.LxdComposeConfig) *cobra.Command {
 
 					// Split key to retrieve needed informations
 					imageData := strings.Split(key, "|")
-					_, err := executor.PullImage(imageData[1], imageData[2])
+					_, err := executor.PullImageType(imageData[1], imageData[3], imageData[2])
 					if err != nil {
 						composer.Logger.Error(
 							fmt.Sprintf("Error on fetch image %s from server %s.",
@@ -131,8 +132,9 @@ func newFetchCommand(config *specs.LxdComposeConfig) *cobra.Command {
 							"[%s] Testing image %s fetched from server %s...",
 							imageData[0], imageData[1], imageData[2]))
 
-						err = executor.CreateContainer("test-image",
-							imageData[1], imageData[0], testProfiles)
+						err = executor.CreateInstanceWithConfig("test-image",
+							imageData[1], imageData[0], imageData[3], testProfiles,
+							map[string]string{}, nil)
 						if err != nil {
 							composer.Logger.Error(
 								fmt.Sprintf("Error on create container with image %s for group %s: %s",
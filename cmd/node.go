@@ -37,9 +37,11 @@ func newNodeCommand(config *specs.LxdComposeConfig) *cobra.Command {
 	cmd.AddCommand(
 		NewCreateCommand(config),
 		NewExecCommand(config),
+		NewHealthCommand(config),
 		NewSyncCommand(config),
 		NewListCommand(config),
 		NewPushCommand(config),
+		NewMoveCommand(config),
 	)
 
 	return cmd
@@ -100,14 +100,26 @@ func NewCreateCommand(config *specs.LxdComposeConfig) *cobra.Command {
 				profiles = append(profiles, nodeConf.Profiles...)
 
 				configMap := nodeConf.GetLxdConfig(grp.GetLxdConfig())
+				specs.SetManagedConfigKeys(configMap)
+				devicesMap := nodeConf.GetLxdDevices(grp.GetLxdDevices())
+				specs.SetManagedDevices(configMap, devicesMap)
 
-				err := executor.CreateContainerWithConfig(n, nodeConf.ImageSource,
-					nodeConf.ImageRemoteServer, profiles, configMap)
+				err := executor.CreateInstanceWithConfig(n, nodeConf.ImageSource,
+					nodeConf.ImageRemoteServer, nodeConf.GetType(), profiles, configMap,
+					devicesMap)
 				if err != nil {
 					fmt.Println("Error on create container "+n+":", err.Error())
 					os.Exit(1)
 				}
 
+				if nodeConf.IsVirtualMachine() {
+					err = executor.WaitAgentOfInstance(n, nodeConf.Wait4Agent())
+					if err != nil {
+						fmt.Println("Error on waiting the agent of "+n+":", err.Error())
+						os.Exit(1)
+					}
+				}
+
 				envs, err := proj.GetEnvsMap()
 				if err != nil {
 					fmt.Println("Error on convert variables in envs:" + err.Error() + "\n")
@@ -0,0 +1,131 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd_node
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	loader "github.com/MottainaiCI/lxd-compose/pkg/loader"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	tablewriter "github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/spf13/cobra"
+)
+
+func NewHealthCommand(config *specs.LxdComposeConfig) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "health node1 node2",
+		Short: "Show the status of the readiness probes of the nodes.",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			prefix, _ := cmd.Flags().GetString("nodes-prefix")
+
+			// Create Instance
+			composer := loader.NewLxdCInstance(config)
+
+			err := composer.LoadEnvironments()
+			if err != nil {
+				fmt.Println("Error on load environments:" + err.Error() + "\n")
+				os.Exit(1)
+			}
+
+			composer.SetNodesPrefix(prefix)
+
+			ans := []specs.LxdCProbeStatus{}
+			allReady := true
+
+			for _, n := range args {
+
+				_, proj, grp, nodeConf := composer.GetEntitiesByNodeName(n)
+				if proj == nil && prefix != "" {
+					// Check if i find the node with prefix
+					_, proj, grp, nodeConf = composer.GetEntitiesByNodeName(
+						fmt.Sprintf("%s-%s", prefix, n))
+				}
+
+				if proj == nil {
+					fmt.Println("Node " + n + " not found")
+					os.Exit(1)
+				}
+
+				if nodeConf.Readiness == nil || len(nodeConf.Readiness.Probes) == 0 {
+					ans = append(ans, specs.LxdCProbeStatus{
+						Node:    n,
+						Probe:   "-",
+						Ready:   true,
+						Message: "no probes defined",
+					})
+					continue
+				}
+
+				executor := lxd_executor.NewLxdCExecutor(
+					grp.ConnectionType, grp.Connection,
+					config.GetGeneral().LxdConfDir,
+					nodeConf.Entrypoint, grp.Ephemeral,
+					config.GetLogging().CmdsOutput,
+					config.GetLogging().RuntimeCmdsOutput)
+				err = executor.Setup()
+				if err != nil {
+					fmt.Println("Error on setup executor:" + err.Error() + "\n")
+					os.Exit(1)
+				}
+				executor.SetP2PMode(config.GetGeneral().P2PMode)
+
+				status, ready := composer.CheckNodeReadiness(proj, nodeConf, executor)
+				if !ready {
+					allReady = false
+				}
+				ans = append(ans, status...)
+			}
+
+			if jsonOutput {
+				data, _ := json.Marshal(ans)
+				fmt.Println(string(data))
+			} else {
+				table := tablewriter.NewTable(os.Stdout,
+					tablewriter.WithRendition(tw.Rendition{
+						Borders: tw.Border{
+							Left:   tw.On,
+							Top:    tw.Off,
+							Right:  tw.On,
+							Bottom: tw.Off,
+						},
+						Symbols: tw.NewSymbols(tw.StyleASCII),
+					}),
+				)
+				table.Header([]string{
+					"Node Name", "Probe", "Ready", "Message",
+				})
+
+				for _, s := range ans {
+					table.Append([]string{
+						s.Node,
+						s.Probe,
+						fmt.Sprintf("%v", s.Ready),
+						s.Message,
+					})
+				}
+
+				table.Render()
+			}
+
+			if !allReady {
+				os.Exit(1)
+			}
+		},
+	}
+
+	pflags := cmd.Flags()
+	pflags.Bool("json", false, "JSON output")
+	pflags.String("nodes-prefix", "", "Customize project nodes name with a prefix")
+
+	return cmd
+}
@@ -56,7 +56,7 @@ func NewListCommand(config *specs.LxdComposeConfig) *cobra.Command {
 				os.Exit(1)
 			}
 
-			list, err := executor.GetContainerList()
+			list, err := executor.GetInstanceList()
 			if err != nil {
 				fmt.Println("Error on retrieve container list: " + err.Error() + "\n")
 				os.Exit(1)
@@ -0,0 +1,91 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd_node
+
+import (
+	"fmt"
+	"os"
+
+	loader "github.com/MottainaiCI/lxd-compose/pkg/loader"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	"github.com/spf13/cobra"
+)
+
+func NewMoveCommand(config *specs.LxdComposeConfig) *cobra.Command {
+	var renderEnvs []string
+
+	var cmd = &cobra.Command{
+		Use:   "move <node> [--to-group <group>|--to-remote <remote>]",
+		Short: "Move the instance of a node to another group or remote.",
+		Long: `Move the instance of a node to another group or remote.
+
+The instance is copied with profiles and config to the target server
+and removed from the source. The environment file is not modified: the
+YAML change to record the new placement of the node is printed at the end.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			prefix, _ := cmd.Flags().GetString("nodes-prefix")
+			toGroup, _ := cmd.Flags().GetString("to-group")
+			toRemote, _ := cmd.Flags().GetString("to-remote")
+			cutover, _ := cmd.Flags().GetBool("cutover")
+			keepSource, _ := cmd.Flags().GetBool("keep-source")
+
+			if (toGroup == "") == (toRemote == "") {
+				fmt.Println("One of --to-group or --to-remote is needed.")
+				os.Exit(1)
+			}
+
+			// Create Instance
+			composer := loader.NewLxdCInstance(config)
+
+			// We need set this before loading phase
+			err := config.SetRenderEnvs(renderEnvs)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			err = composer.LoadEnvironments()
+			if err != nil {
+				fmt.Println("Error on load environments:" + err.Error() + "\n")
+				os.Exit(1)
+			}
+
+			composer.SetNodesPrefix(prefix)
+
+			placement, err := composer.MoveNode(args[0], &loader.LxdCNodeMoveOpts{
+				ToGroup:    toGroup,
+				ToRemote:   toRemote,
+				Cutover:    cutover,
+				KeepSource: keepSource,
+			})
+			if err != nil {
+				fmt.Println("Error on move node " + args[0] + ": " + err.Error())
+				os.Exit(1)
+			}
+
+			change, err := placement.ToYaml()
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			fmt.Println(change)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.String("nodes-prefix", "", "Customize project nodes name with a prefix")
+	flags.String("to-group", "", "Move the node on the connection of the group.")
+	flags.String("to-remote", "", "Move the node on the remote.")
+	flags.Bool("cutover", false,
+		"Copy the running instance and stop it only for the final refresh.")
+	flags.Bool("keep-source", false, "Don't remove the instance from the source.")
+	flags.StringSliceVar(&renderEnvs, "render-env", []string{},
+		"Append render engine environments in the format key=value.")
+
+	return cmd
+}
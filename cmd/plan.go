@@ -0,0 +1,231 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	loader "github.com/MottainaiCI/lxd-compose/pkg/loader"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	"github.com/spf13/cobra"
+)
+
+func printPlanHooks(hooks []specs.LxdCPlanHook, indent string) {
+	for _, h := range hooks {
+		flags := ""
+		if len(h.Flags) > 0 {
+			flags = fmt.Sprintf(" (flags: %s)", strings.Join(h.Flags, ","))
+		}
+		fmt.Println(fmt.Sprintf("%s- [%s] %s: %s%s", indent,
+			h.Event, h.Node, h.Command, flags))
+	}
+}
+
+func printPlan(plan *specs.LxdCProjectPlan) {
+	summary := plan.GetActionsSummary()
+
+	fmt.Println(fmt.Sprintf("Project %s:", plan.Name))
+	if len(plan.PreHooks) > 0 {
+		fmt.Println("  Hooks:")
+		printPlanHooks(plan.PreHooks, "    ")
+	}
+	for _, t := range plan.ConfigTemplates {
+		fmt.Println(fmt.Sprintf("  Template: %s => %s", t.Source, t.Destination))
+	}
+
+	for _, g := range plan.Groups {
+		fmt.Println(fmt.Sprintf("  Group %s (%s):", g.Name, g.Connection))
+		if len(g.PreHooks) > 0 {
+			fmt.Println("    Hooks:")
+			printPlanHooks(g.PreHooks, "      ")
+		}
+		for _, t := range g.ConfigTemplates {
+			fmt.Println(fmt.Sprintf("    Template: %s => %s", t.Source, t.Destination))
+		}
+
+		for _, n := range g.Nodes {
+			fmt.Println(fmt.Sprintf("    Node %s: %s", n.Name, n.Action))
+			for _, op := range n.Operations {
+				fmt.Println(fmt.Sprintf("      - %s", op))
+			}
+			if len(n.Hooks) > 0 {
+				fmt.Println("      Hooks:")
+				printPlanHooks(n.Hooks, "        ")
+			}
+			for _, t := range n.ConfigTemplates {
+				fmt.Println(fmt.Sprintf("      Template: %s => %s",
+					t.Source, t.Destination))
+			}
+			for _, r := range n.SyncResources {
+				fmt.Println(fmt.Sprintf("      Sync: %s => %s",
+					r.Source, r.Destination))
+			}
+			for _, r := range n.FetchResources {
+				fmt.Println(fmt.Sprintf("      Fetch: %s => %s",
+					r.Source, r.Destination))
+			}
+		}
+
+		if len(g.PostHooks) > 0 {
+			fmt.Println("    Post Hooks:")
+			printPlanHooks(g.PostHooks, "      ")
+		}
+	}
+
+	if len(plan.PostHooks) > 0 {
+		fmt.Println("  Post Hooks:")
+		printPlanHooks(plan.PostHooks, "    ")
+	}
+
+	fmt.Println(fmt.Sprintf(
+		"Plan: %d to create, %d to upgrade, %d to start, %d to update, %d unchanged.",
+		summary[specs.PlanActionCreate], summary[specs.PlanActionUpgrade],
+		summary[specs.PlanActionStart], summary[specs.PlanActionUpdate],
+		summary[specs.PlanActionNone]))
+}
+
+func newPlanCommand(config *specs.LxdComposeConfig) *cobra.Command {
+	var enabledFlags []string
+	var disabledFlags []string
+	var enabledGroups []string
+	var disabledGroups []string
+	var envs []string
+	var renderEnvs []string
+	var varsFiles []string
+
+	var cmd = &cobra.Command{
+		Use:   "plan [list-of-projects]",
+		Short: "Show the execution plan of the apply of the projects.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println("No project selected.")
+				os.Exit(1)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Create Instance
+			composer := loader.NewLxdCInstance(config)
+
+			// We need set this before loading phase
+			err := config.SetRenderEnvs(renderEnvs)
+			if err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+
+			err = composer.LoadEnvironments()
+			if err != nil {
+				fmt.Println("Error on load environments:" + err.Error() + "\n")
+				os.Exit(1)
+			}
+
+			skipSync, _ := cmd.Flags().GetBool("skip-sync")
+			skipReconcile, _ := cmd.Flags().GetBool("skip-reconcile")
+			upgrade, _ := cmd.Flags().GetBool("upgrade")
+			prefix, _ := cmd.Flags().GetString("nodes-prefix")
+			resume, _ := cmd.Flags().GetBool("resume")
+			restartFrom, _ := cmd.Flags().GetString("restart-from")
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+
+			composer.SetFlagsDisabled(disabledFlags)
+			composer.SetFlagsEnabled(enabledFlags)
+			composer.SetGroupsDisabled(disabledGroups)
+			composer.SetGroupsEnabled(enabledGroups)
+			composer.SetSkipSync(skipSync)
+			composer.SetSkipReconcile(skipReconcile)
+			composer.SetNodesPrefix(prefix)
+			composer.SetUpgradeMode(upgrade)
+			composer.SetResumeMode(resume)
+			composer.SetRestartFrom(restartFrom)
+
+			plans := []*specs.LxdCProjectPlan{}
+
+			for _, proj := range args {
+
+				env := composer.GetEnvByProjectName(proj)
+				if env == nil {
+					fmt.Println("Project " + proj + " not found")
+					os.Exit(1)
+				}
+
+				pObj := env.GetProjectByName(proj)
+				for _, varFile := range varsFiles {
+					err := pObj.LoadEnvVarsFile(varFile, config)
+					if err != nil {
+						fmt.Println(fmt.Sprintf(
+							"Error on load additional envs var file %s: %s",
+							varFile, err.Error()))
+						os.Exit(1)
+					}
+				}
+
+				if len(envs) > 0 {
+
+					evars := specs.NewEnvVars()
+					for _, e := range envs {
+						err := evars.AddKVAggregated(e)
+						if err != nil {
+							fmt.Println(err)
+							os.Exit(1)
+						}
+					}
+
+					pObj.AddEnvironment(evars)
+				}
+
+				plan, err := composer.PlanProject(proj)
+				if err != nil {
+					fmt.Println("Error on plan project " + proj + ": " + err.Error())
+					os.Exit(1)
+				}
+
+				plans = append(plans, plan)
+			}
+
+			if jsonOutput {
+				data, _ := json.Marshal(plans)
+				fmt.Println(string(data))
+			} else {
+				for _, plan := range plans {
+					printPlan(plan)
+				}
+			}
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringSliceVar(&enabledFlags, "enable-flag", []string{},
+		"Run hooks of only specified flags.")
+	flags.StringSliceVar(&disabledFlags, "disable-flag", []string{},
+		"Disable execution of the hooks with the specified flags.")
+
+	flags.StringSliceVar(&disabledGroups, "disable-group", []string{},
+		"Skip selected group from deploy.")
+	flags.StringSliceVar(&enabledGroups, "enable-group", []string{},
+		"Apply only selected groups.")
+	flags.StringArrayVar(&envs, "env", []string{},
+		"Append project environments in the format key=value.")
+	flags.StringArrayVar(&renderEnvs, "render-env", []string{},
+		"Append render engine environments in the format key=value.")
+	flags.StringSliceVar(&varsFiles, "vars-file", []string{},
+		"Add additional environments vars file.")
+	flags.Bool("skip-sync", false, "Disable sync of files.")
+	flags.Bool("skip-reconcile", false,
+		"Disable the update of profiles and config of the existing nodes.")
+	flags.Bool("upgrade", false, "Enable upgrade mode.")
+	flags.String("nodes-prefix", "", "Customize project nodes name with a prefix")
+	flags.Bool("resume", false,
+		"Plan the resume of the last apply failed.")
+	flags.String("restart-from", "",
+		"Plan the resume of the last apply failed from the specified step in the format node/event.")
+	flags.Bool("json", false, "JSON output")
+
+	return cmd
+}
@@ -109,6 +109,7 @@ func initCommand(rootCmd *cobra.Command, config *specs.LxdComposeConfig) {
 		newNodeCommand(config),
 		newNetworkCommand(config),
 		newPackCommand(config),
+		newPlanCommand(config),
 		newUnpackCommand(config),
 		newProfileCommand(config),
 		newDiagnoseCommand(config),
@@ -116,6 +117,8 @@ func initCommand(rootCmd *cobra.Command, config *specs.LxdComposeConfig) {
 		newCommandCommand(config),
 		newFetchCommand(config),
 		newSecurityCommand(config),
+		newSnapshotCommand(config),
+		newStatusCommand(config),
 		newStopCommand(config),
 		newStorageCommand(config),
 		newTrustCommand(config),
@@ -0,0 +1,30 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	. "github.com/MottainaiCI/lxd-compose/cmd/snapshot"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	"github.com/spf13/cobra"
+)
+
+func newSnapshotCommand(config *specs.LxdComposeConfig) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "snapshot [command] [OPTIONS]",
+		Aliases: []string{"sn"},
+		Short:   "Manage the snapshots of the nodes of the projects.",
+		Args:    cobra.NoArgs,
+	}
+
+	cmd.AddCommand(
+		NewCreateCommand(config),
+		NewListCommand(config),
+		NewRestoreCommand(config),
+		NewDeleteCommand(config),
+	)
+
+	return cmd
+}
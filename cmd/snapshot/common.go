@@ -0,0 +1,53 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd_snapshot
+
+import (
+	"fmt"
+	"os"
+
+	loader "github.com/MottainaiCI/lxd-compose/pkg/loader"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	"github.com/spf13/cobra"
+)
+
+func addCommonFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringSlice("disable-group", []string{}, "Skip selected groups.")
+	flags.StringSlice("enable-group", []string{}, "Process only selected groups.")
+	flags.StringArray("render-env", []string{},
+		"Append render engine environments in the format key=value.")
+	flags.String("nodes-prefix", "", "Customize project nodes name with a prefix")
+}
+
+func newComposer(cmd *cobra.Command, config *specs.LxdComposeConfig) *loader.LxdCInstance {
+	renderEnvs, _ := cmd.Flags().GetStringArray("render-env")
+	enabledGroups, _ := cmd.Flags().GetStringSlice("enable-group")
+	disabledGroups, _ := cmd.Flags().GetStringSlice("disable-group")
+	prefix, _ := cmd.Flags().GetString("nodes-prefix")
+
+	// Create Instance
+	composer := loader.NewLxdCInstance(config)
+
+	// We need set this before loading phase
+	err := config.SetRenderEnvs(renderEnvs)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	err = composer.LoadEnvironments()
+	if err != nil {
+		fmt.Println("Error on load environments:" + err.Error() + "\n")
+		os.Exit(1)
+	}
+
+	composer.SetGroupsDisabled(disabledGroups)
+	composer.SetGroupsEnabled(enabledGroups)
+	composer.SetNodesPrefix(prefix)
+
+	return composer
+}
@@ -0,0 +1,61 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd_snapshot
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	"github.com/spf13/cobra"
+)
+
+func NewCreateCommand(config *specs.LxdComposeConfig) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "create [list-of-projects]",
+		Short: "Create a snapshot of the nodes of the projects.",
+		Long: `Create a snapshot of the nodes of the projects.
+
+All the nodes share the same snapshot name. Without --name the name
+is generated with the current date.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println("No project selected.")
+				os.Exit(1)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			name, _ := cmd.Flags().GetString("name")
+			stateful, _ := cmd.Flags().GetBool("stateful")
+
+			composer := newComposer(cmd, config)
+
+			if name == "" {
+				name = specs.GetSnapshotName(time.Now())
+			}
+
+			for _, proj := range args {
+				err := composer.CreateProjectSnapshot(proj, name, stateful)
+				if err != nil {
+					fmt.Println("Error on create snapshot of project " + proj + ": " + err.Error())
+					os.Exit(1)
+				}
+			}
+
+			composer.Logger.InfoC(
+				fmt.Sprintf(":chequered_flag:%s Snapshot %s :chequered_flag:",
+					composer.Logger.Aurora.Bold("All done!"), name))
+		},
+	}
+
+	addCommonFlags(cmd)
+	flags := cmd.Flags()
+	flags.String("name", "", "Name of the snapshot.")
+	flags.Bool("stateful", false, "Store the running state of the nodes.")
+
+	return cmd
+}
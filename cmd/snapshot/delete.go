@@ -0,0 +1,40 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd_snapshot
+
+import (
+	"fmt"
+	"os"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	"github.com/spf13/cobra"
+)
+
+func NewDeleteCommand(config *specs.LxdComposeConfig) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "delete [project] [snapshot]",
+		Aliases: []string{"rm"},
+		Short:   "Delete a snapshot from the nodes of the project.",
+		Args:    cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			composer := newComposer(cmd, config)
+
+			err := composer.DeleteProjectSnapshot(args[0], args[1])
+			if err != nil {
+				fmt.Println("Error on delete snapshot of project " + args[0] + ": " + err.Error())
+				os.Exit(1)
+			}
+
+			composer.Logger.InfoC(
+				fmt.Sprintf(":chequered_flag:%s :chequered_flag:",
+					composer.Logger.Aurora.Bold("All done!")))
+		},
+	}
+
+	addCommonFlags(cmd)
+
+	return cmd
+}
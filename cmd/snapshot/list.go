@@ -0,0 +1,84 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd_snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/spf13/cobra"
+)
+
+func NewListCommand(config *specs.LxdComposeConfig) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "list [list-of-projects]",
+		Aliases: []string{"l"},
+		Short:   "List the snapshots of the nodes of the projects.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println("No project selected.")
+				os.Exit(1)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+
+			composer := newComposer(cmd, config)
+
+			ans := []*specs.LxdCSnapshot{}
+			for _, proj := range args {
+				snapshots, err := composer.GetProjectSnapshots(proj)
+				if err != nil {
+					fmt.Println("Error on retrieve snapshots of project " + proj + ": " + err.Error())
+					os.Exit(1)
+				}
+				ans = append(ans, snapshots...)
+			}
+
+			if jsonOutput {
+				data, _ := json.Marshal(ans)
+				fmt.Println(string(data))
+				return
+			}
+
+			table := tablewriter.NewTable(os.Stdout,
+				tablewriter.WithRendition(tw.Rendition{
+					Borders: tw.Border{
+						Left:   tw.On,
+						Top:    tw.Off,
+						Right:  tw.On,
+						Bottom: tw.Off,
+					},
+					Symbols: tw.NewSymbols(tw.StyleASCII),
+				}),
+			)
+			table.Header([]string{
+				"Node Name", "Snapshot", "Created", "Stateful",
+			})
+
+			for _, s := range ans {
+				table.Append([]string{
+					s.Node,
+					s.Name,
+					s.CreatedAt.Local().Format(time.RFC3339),
+					fmt.Sprintf("%v", s.Stateful),
+				})
+			}
+
+			table.Render()
+		},
+	}
+
+	addCommonFlags(cmd)
+	cmd.Flags().Bool("json", false, "JSON output")
+
+	return cmd
+}
@@ -0,0 +1,42 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd_snapshot
+
+import (
+	"fmt"
+	"os"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	"github.com/spf13/cobra"
+)
+
+func NewRestoreCommand(config *specs.LxdComposeConfig) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "restore [project] [snapshot]",
+		Short: "Restore the nodes of the project to a snapshot.",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			stateful, _ := cmd.Flags().GetBool("stateful")
+
+			composer := newComposer(cmd, config)
+
+			_, err := composer.RestoreProjectSnapshot(args[0], args[1], stateful)
+			if err != nil {
+				fmt.Println("Error on restore snapshot of project " + args[0] + ": " + err.Error())
+				os.Exit(1)
+			}
+
+			composer.Logger.InfoC(
+				fmt.Sprintf(":chequered_flag:%s :chequered_flag:",
+					composer.Logger.Aurora.Bold("All done!")))
+		},
+	}
+
+	addCommonFlags(cmd)
+	cmd.Flags().Bool("stateful", false, "Restore the running state of the nodes.")
+
+	return cmd
+}
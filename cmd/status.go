@@ -0,0 +1,130 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	loader "github.com/MottainaiCI/lxd-compose/pkg/loader"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	tablewriter "github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/spf13/cobra"
+)
+
+func newStatusCommand(config *specs.LxdComposeConfig) *cobra.Command {
+	var enabledGroups []string
+	var disabledGroups []string
+
+	var cmd = &cobra.Command{
+		Use:   "status [list-of-projects]",
+		Short: "Compare the nodes of the projects with the running instances.",
+		Long: `Compare the nodes of the projects with the running instances.
+
+The command exits with 2 when a drift is found.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println("No project selected.")
+				os.Exit(1)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			// Create Instance
+			composer := loader.NewLxdCInstance(config)
+
+			err := composer.LoadEnvironments()
+			if err != nil {
+				fmt.Println("Error on load environments:" + err.Error() + "\n")
+				os.Exit(1)
+			}
+
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			skipImage, _ := cmd.Flags().GetBool("skip-image")
+			prefix, _ := cmd.Flags().GetString("nodes-prefix")
+
+			composer.SetGroupsDisabled(disabledGroups)
+			composer.SetGroupsEnabled(enabledGroups)
+			composer.SetNodesPrefix(prefix)
+
+			ans := []*specs.LxdCNodeStatus{}
+			drift := false
+
+			for _, proj := range args {
+				status, err := composer.GetProjectStatus(proj, !skipImage)
+				if err != nil {
+					fmt.Println("Error on retrieve status of project " + proj + ": " + err.Error())
+					os.Exit(1)
+				}
+
+				ans = append(ans, status...)
+			}
+
+			for _, s := range ans {
+				if s.HasDrift() {
+					drift = true
+					break
+				}
+			}
+
+			if jsonOutput {
+				data, _ := json.Marshal(ans)
+				fmt.Println(string(data))
+			} else {
+				table := tablewriter.NewTable(os.Stdout,
+					tablewriter.WithRendition(tw.Rendition{
+						Borders: tw.Border{
+							Left:   tw.On,
+							Top:    tw.Off,
+							Right:  tw.On,
+							Bottom: tw.Off,
+						},
+						Symbols: tw.NewSymbols(tw.StyleASCII),
+					}),
+				)
+				table.Header([]string{
+					"Node Name", "Group", "Running", "Addresses", "Drifts",
+				})
+
+				for _, s := range ans {
+					drifts := []string{}
+					for _, d := range s.Drifts {
+						drifts = append(drifts, fmt.Sprintf("%s: %s != %s",
+							d.Field, d.Current, d.Expected))
+					}
+
+					table.Append([]string{
+						s.Name,
+						s.Group,
+						fmt.Sprintf("%v", s.Running),
+						strings.Join(s.Addresses, "\n"),
+						strings.Join(drifts, "\n"),
+					})
+				}
+
+				table.Render()
+			}
+
+			if drift {
+				os.Exit(2)
+			}
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringSliceVar(&disabledGroups, "disable-group", []string{},
+		"Skip selected group.")
+	flags.StringSliceVar(&enabledGroups, "enable-group", []string{},
+		"Check only selected groups.")
+	flags.String("nodes-prefix", "", "Customize project nodes name with a prefix")
+	flags.Bool("skip-image", false, "Skip the check of the image of the nodes.")
+	flags.Bool("json", false, "JSON output")
+
+	return cmd
+}
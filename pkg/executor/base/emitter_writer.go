@@ -5,42 +5,84 @@ See AUTHORS and LICENSE for the license details and contributors.
 package base
 
 import (
+	"bytes"
+	"sync"
+
 	log "github.com/MottainaiCI/lxd-compose/pkg/logger"
 )
 
 type LxdCEmitterWriter struct {
 	Type string
+	// Prefix is added to every line written. When is set the
+	// output is buffered until a newline is received.
+	Prefix string
+
+	buffer bytes.Buffer
+	mutex  sync.Mutex
 }
 
 func NewLxdCEmitterWriter(t string) *LxdCEmitterWriter {
 	return &LxdCEmitterWriter{Type: t}
 }
 
+func NewLxdCEmitterWriterWithPrefix(t, prefix string) *LxdCEmitterWriter {
+	return &LxdCEmitterWriter{Type: t, Prefix: prefix}
+}
+
 func (e *LxdCEmitterWriter) Write(p []byte) (int, error) {
+	if e.Prefix == "" {
+		e.emit(string(p))
+		return len(p), nil
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.buffer.Write(p)
+	for {
+		idx := bytes.IndexByte(e.buffer.Bytes(), '\n')
+		if idx < 0 {
+			break
+		}
+		line := e.buffer.Next(idx + 1)
+		e.emit(e.Prefix + string(line))
+	}
+
+	return len(p), nil
+}
+
+func (e *LxdCEmitterWriter) emit(s string) {
 	logger := log.GetDefaultLogger()
 	switch e.Type {
 	case "lxd_stdout":
 		logger.Msg("info", false, false,
 			logger.Aurora.Bold(
-				logger.Aurora.BrightCyan(string(p)),
+				logger.Aurora.BrightCyan(s),
 			),
 		)
 	case "host_stdout":
 		logger.Msg("info", false, false,
 			logger.Aurora.Bold(
-				logger.Aurora.BrightYellow(string(p)),
+				logger.Aurora.BrightYellow(s),
 			),
 		)
 	case "host_stderr", "lxd_stderr":
 		logger.Msg("info", false, false,
 			logger.Aurora.Bold(
-				logger.Aurora.BrightRed(string(p)),
+				logger.Aurora.BrightRed(s),
 			),
 		)
 	}
-	return len(p), nil
 }
 
 func (e *LxdCEmitterWriter) Close() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	// Flush the last line without newline.
+	if e.buffer.Len() > 0 {
+		e.emit(e.Prefix + e.buffer.String() + "\n")
+		e.buffer.Reset()
+	}
 	return nil
 }
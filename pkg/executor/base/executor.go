@@ -5,9 +5,11 @@ See AUTHORS and LICENSE for the license details and contributors.
 package base
 
 import (
+	"errors"
 	"os"
 	"os/user"
 	"path"
+	"time"
 
 	helpers "github.com/MottainaiCI/lxd-compose/pkg/helpers"
 )
@@ -24,11 +26,16 @@ type BaseExecutor struct {
 	LocalDisable      bool
 	LegacyApi         bool
 
+	// Max execution time of the commands. 0 means no timeout.
+	CommandTimeout time.Duration
+
 	ExcludedRemotes []string
 
 	Emitter LxdCExecutorEmitter
 }
 
+var ErrCommandTimeout = errors.New("command timeout")
+
 type PurgeOpts struct {
 	All         bool
 	Fingerprint string
@@ -74,6 +81,8 @@ func (e *BaseExecutor) SetLocalDisable(v bool)                 { e.LocalDisable
 func (e *BaseExecutor) GetLocalDisable() bool                  { return e.LocalDisable }
 func (e *BaseExecutor) SetLegacyApi(a bool)                    { e.LegacyApi = a }
 func (e *BaseExecutor) GetLegacyApi() bool                     { return e.LegacyApi }
+func (e *BaseExecutor) SetCommandTimeout(t time.Duration)      { e.CommandTimeout = t }
+func (e *BaseExecutor) GetCommandTimeout() time.Duration       { return e.CommandTimeout }
 
 func (e *BaseExecutor) AddRemote2Exclude(remote string) {
 	isPresent := false
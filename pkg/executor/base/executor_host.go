@@ -6,6 +6,7 @@ package base
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -37,7 +38,14 @@ func (e *BaseExecutor) RunHostCommandWithOutput(command string, envs map[string]
 
 	cmds := append(entrypoint, command)
 
-	hostCommand := exec.Command(cmds[0], cmds[1:]...)
+	ctx := context.Background()
+	if e.CommandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.CommandTimeout)
+		defer cancel()
+	}
+
+	hostCommand := exec.CommandContext(ctx, cmds[0], cmds[1:]...)
 
 	logger := log.GetDefaultLogger()
 
@@ -71,6 +79,10 @@ func (e *BaseExecutor) RunHostCommandWithOutput(command string, envs map[string]
 	}
 
 	err = hostCommand.Wait()
+	if ctx.Err() == context.DeadlineExceeded {
+		logger.Error(fmt.Sprintf("Command killed after %s.", e.CommandTimeout))
+		return 1, fmt.Errorf("%w after %s", ErrCommandTimeout, e.CommandTimeout)
+	}
 	if err != nil {
 		logger.Error("Error on waiting command: " + err.Error())
 		return 1, err
@@ -5,8 +5,10 @@ See AUTHORS and LICENSE for the license details and contributors.
 package executor
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/MottainaiCI/lxd-compose/pkg/executor/base"
 	incus "github.com/MottainaiCI/lxd-compose/pkg/executor/incus"
@@ -21,16 +23,27 @@ type LxdCExecutor interface {
 	CreateContainer(name, fingerprint, imageServer string, profiles []string) error
 
 	CreateContainerWithConfig(name, fingerprint, imageServer string, profiles []string, configMap map[string]string) error
+	CreateInstanceWithConfig(name, fingerprint, imageServer, instanceType string, profiles []string,
+		configMap map[string]string, devicesMap map[string]map[string]string) error
 
 	StopContainer(name string) error
 	StartContainer(name string) error
 	GetContainerList() ([]string, error)
+	GetInstanceList() ([]string, error)
 	IsRunningContainer(name string) (bool, error)
 	IsEphemeralContainer(name string) (bool, error)
 	IsPresentContainer(name string) (bool, error)
 	CopyContainerOnInstance(srcName, dstName string) error
 	DeleteContainer(name string) error
 	WaitIpOfContainer(name string, timeout int64) error
+	GetContainerIpv4(name string) (string, error)
+	GetInstanceInfo(name string) (*specs.LxdCInstanceInfo, error)
+	SyncInstanceConfig(name string, profiles []string, configMap map[string]string,
+		devicesMap map[string]map[string]string) ([]string, error)
+
+	// Virtual machines
+	IsVirtualMachine(name string) (bool, error)
+	WaitAgentOfInstance(name string, timeout int64) error
 
 	GetAclList() ([]string, error)
 	IsPresentACL(name string) (bool, error)
@@ -55,13 +68,27 @@ type LxdCExecutor interface {
 
 	RecursiveMkdir(name string, dir string, mode *os.FileMode, uid int64, gid int64) error
 	RecursivePushFile(name, source, target string) error
+	RecursivePushFileWithOpts(name, source, target string, opts *specs.LxdCFileOpts) error
+	PushFileContent(name, target string, content []byte, opts *specs.LxdCFileOpts) error
 	RecursivePullFile(name string, destPath string, localPath string, localAsTarget bool) error
 	DeleteContainerDir(name, dir string) error
 
+	// Snapshots
+	CreateSnapshot(name, snapshot string, stateful bool) error
+	GetSnapshots(name string) ([]*specs.LxdCSnapshot, error)
+	RestoreSnapshot(name, snapshot string, stateful bool) error
+	DeleteSnapshot(name, snapshot string) error
+
+	// Backups
+	ExportInstance(name, file string, optimized bool, compression string) error
+	ImportInstance(name, file, pool string) error
+
 	// Images
 	PurgeImages(opts *base.PurgeOpts) error
 	DeleteImageByFingerprint(f string) error
 	PullImage(imageAlias, imageRemoteServer string) (string, error)
+	PullImageType(imageAlias, imageType, imageRemoteServer string) (string, error)
+	GetImageFingerprint(image, imageRemoteServer string) (string, error)
 
 	// Profiles
 	AddProfiles2Instance(name string, profiles []string) error
@@ -101,6 +128,8 @@ type LxdCExecutor interface {
 	GetLocalDisable() bool
 	SetLegacyApi(a bool)
 	GetLegacyApi() bool
+	SetCommandTimeout(t time.Duration)
+	GetCommandTimeout() time.Duration
 	AddRemote2Exclude(remote string)
 	SetExcludedRemotes(remotes []string)
 	GetExcludedRemotes() []string
@@ -129,3 +158,21 @@ func NewLxdCExecutorWithEmitter(connType, endpoint, configdir string,
 	}
 
 }
+
+// Copy the instance between the servers of the executors. The
+// executors must be of the same type.
+func CopyInstanceBetweenServers(source, target LxdCExecutor, name string, refresh bool) error {
+	switch s := source.(type) {
+	case *incus.IncusExecutor:
+		if t, ok := target.(*incus.IncusExecutor); ok {
+			return s.CopyInstanceToServer(name, t, refresh)
+		}
+	case *lxd.LxdExecutor:
+		if t, ok := target.(*lxd.LxdExecutor); ok {
+			return s.CopyInstanceToServer(name, t, refresh)
+		}
+	}
+
+	return fmt.Errorf("copy of instances from %s to %s not supported",
+		source.GetType(), target.GetType())
+}
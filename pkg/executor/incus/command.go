@@ -9,10 +9,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 
+	base "github.com/MottainaiCI/lxd-compose/pkg/executor/base"
 	helpers "github.com/MottainaiCI/lxd-compose/pkg/helpers"
 	log "github.com/MottainaiCI/lxd-compose/pkg/logger"
 
+	"github.com/gorilla/websocket"
 	"golang.org/x/sys/unix"
 
 	incus "github.com/lxc/incus/v7/client"
@@ -86,6 +89,14 @@ func (e *IncusExecutor) RunCommandWithOutput(containerName, command string,
 		DataDone: make(chan bool),
 	}
 
+	// The control websocket is used to kill the process on timeout.
+	controlConn := make(chan *websocket.Conn, 1)
+	if e.CommandTimeout > 0 {
+		execArgs.Control = func(conn *websocket.Conn) {
+			controlConn <- conn
+		}
+	}
+
 	// Run the command in the container
 	currOper, err = e.Client.ExecInstance(containerName, req, &execArgs)
 	if err != nil {
@@ -96,7 +107,11 @@ func (e *IncusExecutor) RunCommandWithOutput(containerName, command string,
 	dataChan = execArgs.DataDone
 
 	// Wait for the operation to complete
-	err = e.WaitOperation(currOper, nil)
+	if e.CommandTimeout > 0 {
+		err = e.waitExecWithTimeout(containerName, currOper, controlConn)
+	} else {
+		err = e.WaitOperation(currOper, nil)
+	}
 	if err != nil {
 		logger.Error("Error on waiting execution of commands: " + err.Error())
 		return 1, err
@@ -194,3 +209,43 @@ func (e *IncusExecutor) RunCommandWithOutput4Var(containerName, command, outVar,
 
 	return res, err
 }
+
+// Wait the exec operation until the timeout configured. On timeout
+// the process is killed through the control websocket and the
+// operation is cancelled.
+func (e *IncusExecutor) waitExecWithTimeout(containerName string,
+	op incus.Operation, controlConn chan *websocket.Conn) error {
+
+	done := make(chan error, 1)
+	go func() {
+		done <- e.WaitOperation(op, nil)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(e.CommandTimeout):
+	}
+
+	e.Emitter.WarnLog(false,
+		fmt.Sprintf("Timeout of %s reached on exec command in %s. Killing the process.",
+			e.CommandTimeout, containerName))
+
+	select {
+	case conn := <-controlConn:
+		_ = conn.WriteJSON(incus_api.InstanceExecControl{
+			Command: "signal",
+			Signal:  int(unix.SIGKILL),
+		})
+	default:
+	}
+	_ = op.Cancel()
+
+	// Give some time to the server to complete the operation.
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+	}
+
+	return fmt.Errorf("%w after %s", base.ErrCommandTimeout, e.CommandTimeout)
+}
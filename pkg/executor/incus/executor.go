@@ -44,6 +44,13 @@ func (e *IncusExecutor) CreateContainer(name, fingerprint, imageServer string, p
 }
 
 func (e *IncusExecutor) CreateContainerWithConfig(name, fingerprint, imageServer string, profiles []string, configMap map[string]string) error {
+	return e.CreateInstanceWithConfig(name, fingerprint, imageServer,
+		string(incus_api.InstanceTypeContainer), profiles, configMap, nil)
+}
+
+// Create an instance of the type defined: container or virtual-machine.
+func (e *IncusExecutor) CreateInstanceWithConfig(name, fingerprint, imageServer, instanceType string,
+	profiles []string, configMap map[string]string, devicesMap map[string]map[string]string) error {
 	if name == "" {
 		return errors.New("Invalid container name")
 	}
@@ -63,7 +70,7 @@ func (e *IncusExecutor) CreateContainerWithConfig(name, fingerprint, imageServer
 	}
 
 	// Pull image
-	imageFingerprint, err := e.PullImage(fingerprint, imageServer)
+	imageFingerprint, err := e.PullImageType(fingerprint, instanceType, imageServer)
 	if err != nil {
 		logger.Error("Error on pull image " + fingerprint + " from remote " + imageServer)
 		return err
@@ -71,7 +78,8 @@ func (e *IncusExecutor) CreateContainerWithConfig(name, fingerprint, imageServer
 
 	e.Emitter.InfoLog(true, logger.Aurora.Bold(logger.Aurora.BrightCyan(
 		">>> Creating container "+name+"... - :factory:")))
-	err = e.LaunchContainerWithConfig(name, imageFingerprint, profiles, configMap)
+	err = e.LaunchContainerType(name, imageFingerprint, instanceType,
+		profiles, configMap, devicesMap, e.Ephemeral)
 	if err != nil {
 		logger.Error("Creating container error: " + err.Error())
 		return err
@@ -92,6 +100,11 @@ func (e *IncusExecutor) GetContainerList() ([]string, error) {
 	return e.Client.GetInstanceNames(incus_api.InstanceTypeContainer)
 }
 
+// Return the names of the containers and of the virtual machines.
+func (e *IncusExecutor) GetInstanceList() ([]string, error) {
+	return e.Client.GetInstanceNames(incus_api.InstanceTypeAny)
+}
+
 func (e *IncusExecutor) IsRunningContainer(name string) (bool, error) {
 	ans := false
 	var status string
@@ -119,7 +132,7 @@ func (e *IncusExecutor) IsEphemeralContainer(containerName string) (bool, error)
 
 func (e *IncusExecutor) IsPresentContainer(containerName string) (bool, error) {
 	ans := false
-	list, err := e.GetContainerList()
+	list, err := e.GetInstanceList()
 
 	if err != nil {
 		return false, err
@@ -194,6 +207,62 @@ func (e *IncusExecutor) CopyContainerOnInstance(
 	return nil
 }
 
+// Copy the instance with the same name to the server of the target
+// executor maintaining profiles and config. With refresh the instance
+// already present on the target is aligned with the source.
+func (e *IncusExecutor) CopyInstanceToServer(containerName string,
+	target *IncusExecutor, refresh bool) error {
+
+	args := incus.InstanceCopyArgs{
+		Name: containerName,
+		Live: false,
+		// Ignore containers snapshot
+		InstanceOnly: true,
+		Mode:         "pull",
+		Refresh:      refresh,
+		// Ignore copy errors for volatile files of running containers.
+		AllowInconsistent: true,
+	}
+
+	entry, _, err := e.Client.GetInstance(containerName)
+	if err != nil {
+		return err
+	}
+
+	if entry.Config != nil {
+		// Strip the last_state.power key in all cases
+		delete(entry.Config, "volatile.last_state.power")
+	}
+
+	op, err := target.Client.CopyInstance(e.Client, *entry, &args)
+	if err != nil {
+		return err
+	}
+
+	// Watch the background operation
+	progress := incus_cli.ProgressRenderer{
+		Format: "Copy container: %s",
+		Quiet:  false,
+	}
+
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	err = incus_cli.CancelableWait(op, &progress)
+	progress.Done("")
+	if err != nil {
+		return err
+	}
+
+	e.Emitter.DebugLog(false,
+		fmt.Sprintf("Container %s copied to %s.", containerName, target.Endpoint))
+
+	return nil
+}
+
 func (e *IncusExecutor) DeleteContainer(containerName string) error {
 
 	ephemeral, err := e.IsEphemeralContainer(containerName)
@@ -235,7 +304,7 @@ func (e *IncusExecutor) WaitIpOfContainer(containerName string, timeout int64) e
 	withoutIp := true
 	for withoutIp && diff < timeout {
 		instances, err := e.Client.GetInstancesFullWithFilter(
-			incus_api.InstanceTypeContainer,
+			incus_api.InstanceTypeAny,
 			filters,
 		)
 		if err != nil {
@@ -250,6 +319,9 @@ func (e *IncusExecutor) WaitIpOfContainer(containerName string, timeout int64) e
 		}
 
 		c := instances[0]
+		// The commands and the files of the virtual machines are
+		// managed through the agent. I wait that the agent is ready.
+		agentReady := !isVirtualMachine(string(c.Type)) || isAgentReady(c.State)
 		for netIface, net := range c.State.Network {
 			if net.Type == "loopback" {
 				continue
@@ -260,7 +332,7 @@ func (e *IncusExecutor) WaitIpOfContainer(containerName string, timeout int64) e
 				}
 
 				if a.Family == "inet" {
-					if a.Address != "" && a.Netmask != "" {
+					if a.Address != "" && a.Netmask != "" && agentReady {
 						e.Emitter.Emits(base.LxdContainerIpAssigned, map[string]interface{}{
 							"name":    containerName,
 							"iface":   netIface,
@@ -279,3 +351,30 @@ func (e *IncusExecutor) WaitIpOfContainer(containerName string, timeout int64) e
 
 	return nil
 }
+
+// Return the first IPv4 address of the container not related to
+// the loopback interface. An empty string is returned if the
+// container is without address.
+func (e *IncusExecutor) GetContainerIpv4(containerName string) (string, error) {
+	state, _, err := e.Client.GetInstanceState(containerName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, net := range state.Network {
+		if net.Type == "loopback" {
+			continue
+		}
+		for _, a := range net.Addresses {
+			if a.Scope == "link" || a.Scope == "local" {
+				continue
+			}
+
+			if a.Family == "inet" && a.Address != "" {
+				return a.Address, nil
+			}
+		}
+	}
+
+	return "", nil
+}
@@ -17,6 +17,7 @@ import (
 
 	helpers "github.com/MottainaiCI/lxd-compose/pkg/helpers"
 	log "github.com/MottainaiCI/lxd-compose/pkg/logger"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
 
 	incus_units "github.com/canonical/lxd/shared/units"
 	incus "github.com/lxc/incus/v7/client"
@@ -88,6 +89,13 @@ func (e *IncusExecutor) RecursiveMkdir(nameContainer string, dir string, mode *o
 
 // Based on code of lxc client tool https://github.com/canonical/lxd/blob/master/lxc/file.go
 func (e *IncusExecutor) RecursivePushFile(nameContainer, source, target string) error {
+	return e.RecursivePushFileWithOpts(nameContainer, source, target, nil)
+}
+
+// Push the source to the node applying the ownership, the permissions
+// and the filters defined in the opts. The opts could be nil.
+func (e *IncusExecutor) RecursivePushFileWithOpts(nameContainer, source, target string,
+	opts *specs.LxdCFileOpts) error {
 	var targetIsFile bool = true
 	var sourceIsFile bool = true
 
@@ -112,6 +120,30 @@ func (e *IncusExecutor) RecursivePushFile(nameContainer, source, target string)
 	// Create directory as root. TODO: see if we can use a specific user.
 	var uid int64 = 0
 	var gid int64 = 0
+
+	var fileMode, dirMode *os.FileMode
+	if opts != nil {
+		var err error
+		fileMode, err = opts.GetMode()
+		if err != nil {
+			return err
+		}
+		dirMode, err = opts.GetDirMode()
+		if err != nil {
+			return err
+		}
+
+		if dirMode != nil {
+			mode = *dirMode
+		}
+		if opts.Uid != nil {
+			uid = *opts.Uid
+		}
+		if opts.Gid != nil {
+			gid = *opts.Gid
+		}
+	}
+
 	err := e.RecursiveMkdir(nameContainer, dir, &mode, uid, gid)
 	if err != nil {
 		return errors.New("Error on create dir " + filepath.Dir(target) + ": " + err.Error())
@@ -127,6 +159,14 @@ func (e *IncusExecutor) RecursivePushFile(nameContainer, source, target string)
 			return fmt.Errorf("'%s' isn't a supported file type", p)
 		}
 
+		if p != source && !opts.IsToPush(strings.TrimPrefix(
+			filepath.ToSlash(p[len(filepath.Clean(source)):]), "/"), fInfo.IsDir()) {
+			if fInfo.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		// Prepare for file transfer
 		targetPath := path.Join(target, filepath.ToSlash(p[sourceLen:]))
 
@@ -141,6 +181,17 @@ func (e *IncusExecutor) RecursivePushFile(nameContainer, source, target string)
 		}
 
 		mode, uid, gid := lxd_shared.GetOwnerMode(fInfo)
+		if fInfo.IsDir() && dirMode != nil {
+			mode = *dirMode
+		} else if !fInfo.IsDir() && fileMode != nil {
+			mode = *fileMode
+		}
+		if opts != nil && opts.Uid != nil {
+			uid = int(*opts.Uid)
+		}
+		if opts != nil && opts.Gid != nil {
+			gid = int(*opts.Gid)
+		}
 		args := incus.InstanceFileArgs{
 			UID:  int64(uid),
 			GID:  int64(gid),
@@ -238,6 +289,54 @@ func (e *IncusExecutor) RecursivePushFile(nameContainer, source, target string)
 	return filepath.Walk(source, sendFileInstance)
 }
 
+// Write the content to the target file of the node without using
+// the host filesystem. Without mode the file is created with 0644.
+func (e *IncusExecutor) PushFileContent(nameContainer, target string, content []byte,
+	opts *specs.LxdCFileOpts) error {
+	mode := os.FileMode(0644)
+	dirMode := os.FileMode(0755)
+	var uid int64 = 0
+	var gid int64 = 0
+
+	if opts != nil {
+		m, err := opts.GetMode()
+		if err != nil {
+			return err
+		}
+		if m != nil {
+			mode = *m
+		}
+		m, err = opts.GetDirMode()
+		if err != nil {
+			return err
+		}
+		if m != nil {
+			dirMode = *m
+		}
+		if opts.Uid != nil {
+			uid = *opts.Uid
+		}
+		if opts.Gid != nil {
+			gid = *opts.Gid
+		}
+	}
+
+	err := e.RecursiveMkdir(nameContainer, path.Dir(target), &dirMode, uid, gid)
+	if err != nil {
+		return errors.New("Error on create dir " + path.Dir(target) + ": " + err.Error())
+	}
+
+	args := incus.InstanceFileArgs{
+		UID:     uid,
+		GID:     gid,
+		Mode:    int(mode.Perm()),
+		Type:    "file",
+		Content: bytes.NewReader(content),
+	}
+
+	return e.Client.CreateInstanceFile(nameContainer, target, args)
+}
+
 // Based on code of lxc client tool https://github.com/canonical/lxd/blob/master/lxc/file.go
 func (l *IncusExecutor) RecursivePullFile(nameContainer string, destPath string, localPath string, localAsTarget bool) error {
 	var ftype string
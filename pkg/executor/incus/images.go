@@ -122,6 +122,12 @@ func (e *IncusExecutor) DeleteImageByFingerprint(f string) error {
 }
 
 func (e *IncusExecutor) PullImage(imageAlias, imageRemoteServer string) (string, error) {
+	return e.PullImageType(imageAlias, "", imageRemoteServer)
+}
+
+// Pull the image of the type defined (container or virtual-machine).
+// An empty type means any type.
+func (e *IncusExecutor) PullImageType(imageAlias, imageType, imageRemoteServer string) (string, error) {
 	var err error
 	var imageFingerprint, remote_name string
 	var remote incus.ImageServer
@@ -130,7 +136,7 @@ func (e *IncusExecutor) PullImage(imageAlias, imageRemoteServer string) (string,
 	e.Emitter.InfoLog(false, "Searching image: "+imageAlias)
 
 	// Find image hashing id
-	imageFingerprint, remote, remote_name, err = e.FindImage(imageAlias, imageRemoteServer)
+	imageFingerprint, remote, remote_name, err = e.FindImageType(imageAlias, imageType, imageRemoteServer)
 	if err != nil {
 		noRemoteImageFound = true
 		if strings.Contains(imageAlias, "/") {
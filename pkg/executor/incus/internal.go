@@ -16,14 +16,17 @@ import (
 )
 
 func (e *IncusExecutor) LaunchContainer(name, fingerprint string, profiles []string) error {
-	return e.LaunchContainerType(name, fingerprint, profiles, map[string]string{}, e.Ephemeral)
+	return e.LaunchContainerType(name, fingerprint, string(incus_api.InstanceTypeContainer),
+		profiles, map[string]string{}, nil, e.Ephemeral)
 }
 
 func (e *IncusExecutor) LaunchContainerWithConfig(name, fingerprint string, profiles []string, configMap map[string]string) error {
-	return e.LaunchContainerType(name, fingerprint, profiles, configMap, e.Ephemeral)
+	return e.LaunchContainerType(name, fingerprint, string(incus_api.InstanceTypeContainer),
+		profiles, configMap, nil, e.Ephemeral)
 }
 
-func (e *IncusExecutor) LaunchContainerType(name, fingerprint string, profiles []string, configMap map[string]string, ephemeral bool) error {
+func (e *IncusExecutor) LaunchContainerType(name, fingerprint, instanceType string, profiles []string,
+	configMap map[string]string, devicesMap map[string]map[string]string, ephemeral bool) error {
 
 	var err error
 	var image *incus_api.Image
@@ -36,7 +39,9 @@ func (e *IncusExecutor) LaunchContainerType(name, fingerprint string, profiles [
 
 	// Note: Avoid to create devece map for root /. We consider to handle this
 	//       as profile. Same for different storage.
-	devicesMap := map[string]map[string]string{}
+	if devicesMap == nil {
+		devicesMap = map[string]map[string]string{}
+	}
 
 	// Retrieve image info
 	image, _, err = e.Client.GetImage(fingerprint)
@@ -44,10 +49,19 @@ func (e *IncusExecutor) LaunchContainerType(name, fingerprint string, profiles [
 		return err
 	}
 
+	if instanceType == "" {
+		instanceType = string(incus_api.InstanceTypeContainer)
+	}
+
+	if image.Type != "" && image.Type != instanceType {
+		return fmt.Errorf("the image %s is of type %s and can't be used for a %s instance",
+			fingerprint, image.Type, instanceType)
+	}
+
 	// Setup container creation request
 	req := incus_api.InstancesPost{
 		Name: name,
-		Type: incus_api.InstanceTypeContainer,
+		Type: incus_api.InstanceType(instanceType),
 	}
 	req.Config = configMap
 	req.Devices = devicesMap
@@ -234,6 +248,12 @@ func (e *IncusExecutor) DoAction2Container(name, action string) error {
 
 // Retrieve Image from alias or fingerprint to a specific remote.
 func (e *IncusExecutor) GetImage(image string, remote incus.ImageServer) (*incus_api.Image, error) {
+	return e.GetImageType(image, "", remote)
+}
+
+// Retrieve the image with the input alias or fingerprint of the
+// type defined (container or virtual-machine). An empty type means any type.
+func (e *IncusExecutor) GetImageType(image, imageType string, remote incus.ImageServer) (*incus_api.Image, error) {
 	var err error
 	var img *incus_api.Image
 	var aliasEntry *incus_api.ImageAliasesEntry
@@ -250,7 +270,7 @@ func (e *IncusExecutor) GetImage(image string, remote incus.ImageServer) (*incus
 		}
 
 		// Check if exists an image with input alias
-		aliasEntry, _, err = remote.GetImageAlias(image)
+		aliasEntry, _, err = remote.GetImageAliasType(imageType, image)
 		if err != nil {
 			e.Emitter.DebugLog(false,
 				fmt.Sprintf("On search image with alias %s receive from remote '%s': %s",
@@ -263,6 +283,12 @@ func (e *IncusExecutor) GetImage(image string, remote incus.ImageServer) (*incus
 		}
 	}
 
+	if err == nil && imageType != "" && img.Type != imageType {
+		err = fmt.Errorf("the image %s is of type %s and not %s",
+			image, img.Type, imageType)
+		img = nil
+	}
+
 	return img, err
 }
 
@@ -372,6 +398,10 @@ func (e *IncusExecutor) AddAlias2Image(fingerprint string, alias incus_api.Image
 }
 
 func (e *IncusExecutor) FindImage(image, imageRemoteServer string) (string, incus.ImageServer, string, error) {
+	return e.FindImageType(image, "", imageRemoteServer)
+}
+
+func (e *IncusExecutor) FindImageType(image, imageType, imageRemoteServer string) (string, incus.ImageServer, string, error) {
 	var err error
 	var tmp_srv, srv incus.ImageServer
 	var img, tmp_img *incus_api.Image
@@ -415,7 +445,7 @@ func (e *IncusExecutor) FindImage(image, imageRemoteServer string) (string, incu
 			))
 			continue
 		}
-		tmp_img, err = e.GetImage(image, tmp_srv)
+		tmp_img, err = e.GetImageType(image, imageType, tmp_srv)
 		if err != nil {
 			// POST: No image found with input alias/fingerprint.
 			//       I go ahead to next remote
@@ -483,7 +513,7 @@ func (l *IncusExecutor) CreateImageFromContainer(containerName string, aliases [
 	// Create the image
 	req := incus_api.ImagesPost{
 		Source: &incus_api.ImagesPostSource{
-			Type: "container",
+			Type: "instance",
 			Name: containerName,
 		},
 		// CompressionAlgorithm contains name of the binary called by LXD for compression.
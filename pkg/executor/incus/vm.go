@@ -0,0 +1,63 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package incus
+
+import (
+	"fmt"
+	"time"
+
+	incus_api "github.com/lxc/incus/v7/shared/api"
+)
+
+func isVirtualMachine(instanceType string) bool {
+	return instanceType == string(incus_api.InstanceTypeVM)
+}
+
+// The state of a virtual machine reports the processes only
+// when the agent is running.
+func isAgentReady(state *incus_api.InstanceState) bool {
+	return state != nil && state.StatusCode == incus_api.Running && state.Processes > 0
+}
+
+func (e *IncusExecutor) IsVirtualMachine(name string) (bool, error) {
+	instance, _, err := e.Client.GetInstance(name)
+	if err != nil {
+		return false, err
+	}
+
+	return isVirtualMachine(string(instance.Type)), nil
+}
+
+// Wait until the agent of the virtual machine is ready to receive
+// commands and files. For the containers it returns immediately.
+func (e *IncusExecutor) WaitAgentOfInstance(name string, timeout int64) error {
+	isVm, err := e.IsVirtualMachine(name)
+	if err != nil {
+		return err
+	}
+
+	if !isVm {
+		return nil
+	}
+
+	e.Emitter.DebugLog(false, fmt.Sprintf(
+		"Waiting for the agent of the virtual machine %s...", name))
+
+	start := time.Now().Unix()
+	for time.Now().Unix()-start < timeout {
+		state, _, err := e.Client.GetInstanceState(name)
+		if err != nil {
+			return err
+		}
+
+		if isAgentReady(state) {
+			return nil
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return fmt.Errorf("timeout on waiting for the agent of the virtual machine %s", name)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package lxd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	lxd "github.com/canonical/lxd/client"
+	lxd_api "github.com/canonical/lxd/shared/api"
+	lxd_cli "github.com/canonical/lxd/shared/cmd"
+)
+
+// Export the instance to a tarball on the host. The backup created
+// on the server is removed after the download.
+func (e *LxdExecutor) ExportInstance(name, file string, optimized bool, compression string) error {
+	backupName := "lxdc-export-" + time.Now().Format("20060102150405")
+
+	oper, err := e.LxdClient.CreateInstanceBackup(name, lxd_api.InstanceBackupsPost{
+		Name:                 backupName,
+		ExpiresAt:            time.Now().Add(24 * time.Hour),
+		InstanceOnly:         true,
+		OptimizedStorage:     optimized,
+		CompressionAlgorithm: compression,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = e.WaitOperation(oper, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		oper, err := e.LxdClient.DeleteInstanceBackup(name, backupName)
+		if err == nil {
+			err = e.WaitOperation(oper, nil)
+		}
+		if err != nil {
+			e.Emitter.WarnLog(false, fmt.Sprintf(
+				"Error on remove backup %s of the instance %s: %s",
+				backupName, name, err.Error()))
+		}
+	}()
+
+	target, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	progress := lxd_cli.ProgressRenderer{
+		Format: fmt.Sprintf("Exporting %s: %%s", name),
+		Quiet:  false,
+	}
+
+	_, err = e.LxdClient.GetInstanceBackupFile(name, backupName, &lxd.BackupFileRequest{
+		BackupFile:      target,
+		ProgressHandler: progress.UpdateProgress,
+	})
+	progress.Done("")
+	if err != nil {
+		os.Remove(file)
+		return err
+	}
+
+	e.Emitter.DebugLog(false,
+		fmt.Sprintf("Instance %s exported to %s.", name, file))
+
+	return nil
+}
+
+// Create the instance from a tarball generated by ExportInstance.
+// With an empty pool is used the pool defined in the tarball.
+func (e *LxdExecutor) ImportInstance(name, file, pool string) error {
+	source, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	oper, err := e.LxdClient.CreateInstanceFromBackup(lxd.InstanceBackupArgs{
+		BackupFile: source,
+		PoolName:   pool,
+		Name:       name,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = e.WaitOperation(oper, nil)
+	if err != nil {
+		return err
+	}
+
+	e.Emitter.DebugLog(false,
+		fmt.Sprintf("Instance %s imported from %s.", name, file))
+
+	return nil
+}
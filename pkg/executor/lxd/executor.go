@@ -44,6 +44,13 @@ func (e *LxdExecutor) CreateContainer(name, fingerprint, imageServer string, pro
 }
 
 func (e *LxdExecutor) CreateContainerWithConfig(name, fingerprint, imageServer string, profiles []string, configMap map[string]string) error {
+	return e.CreateInstanceWithConfig(name, fingerprint, imageServer,
+		string(lxd_api.InstanceTypeContainer), profiles, configMap, nil)
+}
+
+// Create an instance of the type defined: container or virtual-machine.
+func (e *LxdExecutor) CreateInstanceWithConfig(name, fingerprint, imageServer, instanceType string,
+	profiles []string, configMap map[string]string, devicesMap map[string]map[string]string) error {
 	if name == "" {
 		return errors.New("Invalid container name")
 	}
@@ -63,7 +70,7 @@ func (e *LxdExecutor) CreateContainerWithConfig(name, fingerprint, imageServer s
 	}
 
 	// Pull image
-	imageFingerprint, err := e.PullImage(fingerprint, imageServer)
+	imageFingerprint, err := e.PullImageType(fingerprint, instanceType, imageServer)
 	if err != nil {
 		logger.Error("Error on pull image " + fingerprint + " from remote " + imageServer)
 		return err
@@ -71,7 +78,8 @@ func (e *LxdExecutor) CreateContainerWithConfig(name, fingerprint, imageServer s
 
 	e.Emitter.InfoLog(true, logger.Aurora.Bold(logger.Aurora.BrightCyan(
 		">>> Creating container "+name+"... - :factory:")))
-	err = e.LaunchContainerWithConfig(name, imageFingerprint, profiles, configMap)
+	err = e.LaunchContainerType(name, imageFingerprint, instanceType,
+		profiles, configMap, devicesMap, e.Ephemeral)
 	if err != nil {
 		logger.Error("Creating container error: " + err.Error())
 		return err
@@ -92,6 +100,11 @@ func (e *LxdExecutor) GetContainerList() ([]string, error) {
 	return e.LxdClient.GetInstanceNames(lxd_api.InstanceTypeContainer)
 }
 
+// Return the names of the containers and of the virtual machines.
+func (e *LxdExecutor) GetInstanceList() ([]string, error) {
+	return e.LxdClient.GetInstanceNames(lxd_api.InstanceTypeAny)
+}
+
 func (e *LxdExecutor) IsRunningContainer(name string) (bool, error) {
 	ans := false
 	var status string
@@ -119,7 +132,7 @@ func (e *LxdExecutor) IsEphemeralContainer(containerName string) (bool, error) {
 
 func (e *LxdExecutor) IsPresentContainer(containerName string) (bool, error) {
 	ans := false
-	list, err := e.GetContainerList()
+	list, err := e.GetInstanceList()
 
 	if err != nil {
 		return false, err
@@ -194,6 +207,62 @@ func (e *LxdExecutor) CopyContainerOnInstance(
 	return nil
 }
 
+// Copy the instance with the same name to the server of the target
+// executor maintaining profiles and config. With refresh the instance
+// already present on the target is aligned with the source.
+func (e *LxdExecutor) CopyInstanceToServer(containerName string,
+	target *LxdExecutor, refresh bool) error {
+
+	args := lxd.InstanceCopyArgs{
+		Name: containerName,
+		Live: false,
+		// Ignore containers snapshot
+		InstanceOnly: true,
+		Mode:         "pull",
+		Refresh:      refresh,
+		// Ignore copy errors for volatile files of running containers.
+		AllowInconsistent: true,
+	}
+
+	entry, _, err := e.LxdClient.GetInstance(containerName)
+	if err != nil {
+		return err
+	}
+
+	if entry.Config != nil {
+		// Strip the last_state.power key in all cases
+		delete(entry.Config, "volatile.last_state.power")
+	}
+
+	op, err := target.LxdClient.CopyInstance(e.LxdClient, *entry, &args)
+	if err != nil {
+		return err
+	}
+
+	// Watch the background operation
+	progress := lxd_cli.ProgressRenderer{
+		Format: "Copy container: %s",
+		Quiet:  false,
+	}
+
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	err = lxd_cli.CancelableWait(op, &progress)
+	progress.Done("")
+	if err != nil {
+		return err
+	}
+
+	e.Emitter.DebugLog(false,
+		fmt.Sprintf("Container %s copied to %s.", containerName, target.Endpoint))
+
+	return nil
+}
+
 func (e *LxdExecutor) DeleteContainer(containerName string) error {
 
 	ephemeral, err := e.IsEphemeralContainer(containerName)
@@ -236,7 +305,7 @@ func (e *LxdExecutor) WaitIpOfContainer(containerName string, timeout int64) err
 	for withoutIp && diff < timeout {
 		instances, err := e.LxdClient.GetInstancesFull(
 			lxd.GetInstancesFullArgs{
-				InstanceType: lxd_api.InstanceTypeContainer,
+				InstanceType: lxd_api.InstanceTypeAny,
 				Filters:      filters,
 			},
 		)
@@ -252,6 +321,9 @@ func (e *LxdExecutor) WaitIpOfContainer(containerName string, timeout int64) err
 		}
 
 		c := instances[0]
+		// The commands and the files of the virtual machines are
+		// managed through the agent. I wait that the agent is ready.
+		agentReady := !isVirtualMachine(string(c.Type)) || isAgentReady(c.State)
 		for netIface, net := range c.State.Network {
 			if net.Type == "loopback" {
 				continue
@@ -262,7 +334,7 @@ func (e *LxdExecutor) WaitIpOfContainer(containerName string, timeout int64) err
 				}
 
 				if a.Family == "inet" {
-					if a.Address != "" && a.Netmask != "" {
+					if a.Address != "" && a.Netmask != "" && agentReady {
 						e.Emitter.Emits(base.LxdContainerIpAssigned, map[string]interface{}{
 							"name":    containerName,
 							"iface":   netIface,
@@ -281,3 +353,30 @@ func (e *LxdExecutor) WaitIpOfContainer(containerName string, timeout int64) err
 
 	return nil
 }
+
+// Return the first IPv4 address of the container not related to
+// the loopback interface. An empty string is returned if the
+// container is without address.
+func (e *LxdExecutor) GetContainerIpv4(containerName string) (string, error) {
+	state, _, err := e.LxdClient.GetInstanceState(containerName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, net := range state.Network {
+		if net.Type == "loopback" {
+			continue
+		}
+		for _, a := range net.Addresses {
+			if a.Scope == "link" || a.Scope == "local" {
+				continue
+			}
+
+			if a.Family == "inet" && a.Address != "" {
+				return a.Address, nil
+			}
+		}
+	}
+
+	return "", nil
+}
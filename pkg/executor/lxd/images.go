@@ -121,6 +121,12 @@ func (e *LxdExecutor) DeleteImageByFingerprint(f string) error {
 }
 
 func (e *LxdExecutor) PullImage(imageAlias, imageRemoteServer string) (string, error) {
+	return e.PullImageType(imageAlias, "", imageRemoteServer)
+}
+
+// Pull the image of the type defined (container or virtual-machine).
+// An empty type means any type.
+func (e *LxdExecutor) PullImageType(imageAlias, imageType, imageRemoteServer string) (string, error) {
 	var err error
 	var imageFingerprint, remote_name string
 	var remote lxd.ImageServer
@@ -129,7 +135,7 @@ func (e *LxdExecutor) PullImage(imageAlias, imageRemoteServer string) (string, e
 	e.Emitter.InfoLog(false, "Searching image: "+imageAlias)
 
 	// Find image hashing id
-	imageFingerprint, remote, remote_name, err = e.FindImage(imageAlias, imageRemoteServer)
+	imageFingerprint, remote, remote_name, err = e.FindImageType(imageAlias, imageType, imageRemoteServer)
 	if err != nil {
 		noRemoteImageFound = true
 		if strings.Contains(imageAlias, "/") {
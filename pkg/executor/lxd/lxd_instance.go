@@ -5,7 +5,10 @@ See AUTHORS and LICENSE for the license details and contributors.
 package lxd
 
 import (
+	"strings"
+
 	base "github.com/MottainaiCI/lxd-compose/pkg/executor/base"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
 
 	lxd_api "github.com/canonical/lxd/shared/api"
 )
@@ -15,6 +18,47 @@ func (e *LxdExecutor) GetInstance(name string) (*lxd_api.Instance, string, error
 	return e.LxdClient.GetInstance(name)
 }
 
+// Return the current status of the instance.
+func (e *LxdExecutor) GetInstanceInfo(name string) (*specs.LxdCInstanceInfo, error) {
+	instance, _, err := e.LxdClient.GetInstanceFull(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &specs.LxdCInstanceInfo{
+		Name:             instance.Name,
+		Status:           instance.Status,
+		Profiles:         instance.Profiles,
+		Config:           instance.Config,
+		Devices:          instance.Devices,
+		Addresses:        []string{},
+		ImageFingerprint: instance.Config["volatile.base_image"],
+	}
+
+	if instance.State != nil {
+		for _, net := range instance.State.Network {
+			if net.Type == "loopback" {
+				continue
+			}
+			for _, a := range net.Addresses {
+				if a.Scope == "link" || a.Scope == "local" {
+					continue
+				}
+				ans.Addresses = append(ans.Addresses, a.Address)
+			}
+		}
+	}
+
+	return ans, nil
+}
+
+// Return the fingerprint of the image with the input alias or fingerprint
+// available in the remotes.
+func (e *LxdExecutor) GetImageFingerprint(image, imageRemoteServer string) (string, error) {
+	fingerprint, _, _, err := e.FindImage(image, imageRemoteServer)
+	return fingerprint, err
+}
+
 func (e *LxdExecutor) UpdateInstance(
 	name string, idata *lxd_api.InstancePut,
 	etag string) error {
@@ -40,6 +84,52 @@ func (e *LxdExecutor) UpdateInstance(
 	return nil
 }
 
+// Align the profiles, the config and the devices of the instance with
+// the input values. The config keys and the devices not available in the
+// input values are removed only if previously set by lxd-compose.
+// An empty list of profiles means that the profiles are not modified.
+// It returns the list of the config keys updated, "profiles" if the
+// profiles are been changed and devices.<name> for every device changed.
+func (e *LxdExecutor) SyncInstanceConfig(name string, profiles []string,
+	configMap map[string]string, devicesMap map[string]map[string]string) ([]string, error) {
+
+	changes := []string{}
+
+	idata, etag, err := e.GetInstance(name)
+	if err != nil {
+		return changes, err
+	}
+
+	iput := idata.Writable()
+	if iput.Config == nil {
+		iput.Config = make(map[string]string, 0)
+	}
+	if iput.Devices == nil {
+		iput.Devices = make(map[string]map[string]string, 0)
+	}
+
+	if len(profiles) > 0 &&
+		strings.Join(profiles, ",") != strings.Join(iput.Profiles, ",") {
+		iput.Profiles = profiles
+		changes = append(changes, "profiles")
+	}
+
+	changes = append(changes,
+		specs.ReconcileInstanceConfig(iput.Config, configMap)...)
+
+	changes = append(changes,
+		specs.ReconcileInstanceDevices(iput.Config, iput.Devices, devicesMap)...)
+
+	if len(changes) > 0 {
+		err = e.UpdateInstance(name, &iput, etag)
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	return changes, nil
+}
+
 func (e *LxdExecutor) RemoveProfilesFromInstance(name string, profiles []string) error {
 	// Retrieve the current status of the instance
 	idata, etag, err := e.GetInstance(name)
@@ -0,0 +1,85 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package lxd
+
+import (
+	"fmt"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	lxd_api "github.com/canonical/lxd/shared/api"
+)
+
+func (e *LxdExecutor) CreateSnapshot(name, snapshot string, stateful bool) error {
+	oper, err := e.LxdClient.CreateInstanceSnapshot(name, lxd_api.InstanceSnapshotsPost{
+		Name:     snapshot,
+		Stateful: stateful,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = e.WaitOperation(oper, nil)
+	if err != nil {
+		return err
+	}
+
+	e.Emitter.DebugLog(false,
+		fmt.Sprintf("Snapshot %s of the instance %s created.", snapshot, name))
+
+	return nil
+}
+
+func (e *LxdExecutor) GetSnapshots(name string) ([]*specs.LxdCSnapshot, error) {
+	ans := []*specs.LxdCSnapshot{}
+
+	snapshots, err := e.LxdClient.GetInstanceSnapshots(name)
+	if err != nil {
+		return ans, err
+	}
+
+	for _, s := range snapshots {
+		ans = append(ans, &specs.LxdCSnapshot{
+			Node:      name,
+			Name:      s.Name,
+			CreatedAt: s.CreatedAt,
+			Stateful:  s.Stateful,
+			Size:      s.Size,
+		})
+	}
+
+	return ans, nil
+}
+
+// Restore the instance to the snapshot. With stateful the running
+// state saved in the snapshot is restored too.
+func (e *LxdExecutor) RestoreSnapshot(name, snapshot string, stateful bool) error {
+	oper, err := e.LxdClient.UpdateInstance(name, lxd_api.InstancePut{
+		Restore:  snapshot,
+		Stateful: stateful,
+	}, "")
+	if err != nil {
+		return err
+	}
+
+	err = e.WaitOperation(oper, nil)
+	if err != nil {
+		return err
+	}
+
+	e.Emitter.DebugLog(false,
+		fmt.Sprintf("Instance %s restored to snapshot %s.", name, snapshot))
+
+	return nil
+}
+
+func (e *LxdExecutor) DeleteSnapshot(name, snapshot string) error {
+	oper, err := e.LxdClient.DeleteInstanceSnapshot(name, snapshot, "")
+	if err != nil {
+		return err
+	}
+
+	return e.WaitOperation(oper, nil)
+}
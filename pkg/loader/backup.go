@@ -0,0 +1,219 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+)
+
+type LxdCBackupRestoreOpts struct {
+	// Restore only the selected nodes.
+	Nodes []string
+	// Override the connection of the nodes.
+	Connection     string
+	ConnectionType string
+	// Storage pool of the restored instances.
+	Pool string
+}
+
+// Export the nodes of the project as tarballs under the directory
+// <exportDir>/<project>/<date> with the manifest of the nodes.
+// It returns the directory of the backup.
+func (i *LxdCInstance) ExportProjectBackup(projectName, exportDir string,
+	optimized bool, compression string) (string, error) {
+
+	if compression == "" {
+		compression = specs.BackupDefaultCompression
+	}
+
+	now := time.Now()
+	backupDir := filepath.Join(exportDir, projectName,
+		now.Format(specs.BackupExportDateFormat))
+
+	err := os.MkdirAll(backupDir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	manifest := &specs.LxdCBackupManifest{
+		Project:   projectName,
+		CreatedAt: now,
+		Optimized: optimized,
+		Nodes:     []specs.LxdCBackupNodeInfo{},
+	}
+
+	err = i.forEachPresentNode(projectName,
+		func(executor lxd_executor.LxdCExecutor, grp *specs.LxdCGroup, node *specs.LxdCNode) error {
+			file := specs.GetBackupFileName(node.GetName(), compression)
+
+			i.Logger.InfoC(
+				i.Logger.Aurora.Bold(
+					i.Logger.Aurora.BrightCyan(
+						fmt.Sprintf(">>> [%s] Exporting to %s... - :package:",
+							node.GetName(), file))))
+
+			err := executor.ExportInstance(node.GetName(),
+				filepath.Join(backupDir, file), optimized, compression)
+			if err != nil {
+				return fmt.Errorf("error on export node %s: %s",
+					node.GetName(), err.Error())
+			}
+
+			manifest.Nodes = append(manifest.Nodes, specs.LxdCBackupNodeInfo{
+				Name:           node.GetName(),
+				Group:          grp.Name,
+				Connection:     grp.Connection,
+				ConnectionType: grp.ConnectionType,
+				File:           file,
+				Spec:           *node,
+			})
+
+			return nil
+		})
+	if err != nil {
+		return backupDir, err
+	}
+
+	// The manifest is written only when all the nodes are exported.
+	return backupDir, manifest.Write(filepath.Join(backupDir, specs.BackupManifestFile))
+}
+
+// Remove the exported backups of the project not maintained by
+// the retention policy.
+func (i *LxdCInstance) PurgeProjectBackups(projectName, exportDir string,
+	retention *specs.LxdCBackupRetention) error {
+
+	projectDir := filepath.Join(exportDir, projectName)
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		return err
+	}
+
+	// Only the completed exports have the manifest.
+	dates := []string{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		_, err := os.Stat(filepath.Join(projectDir, e.Name(), specs.BackupManifestFile))
+		if err != nil {
+			continue
+		}
+		dates = append(dates, e.Name())
+	}
+
+	for _, d := range retention.GetBackupsToRemove(dates) {
+		i.Logger.InfoC(
+			i.Logger.Aurora.Bold(
+				fmt.Sprintf(":knife:[%s] Removing backup %s...", projectName, d)))
+
+		err = os.RemoveAll(filepath.Join(projectDir, d))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Import the nodes of an exported backup. The nodes are checked
+// before the import: if a node is already present no node is
+// restored.
+func (i *LxdCInstance) RestoreProjectBackup(backupDir string, opts *LxdCBackupRestoreOpts) error {
+	manifest, err := specs.BackupManifestFromFile(
+		filepath.Join(backupDir, specs.BackupManifestFile))
+	if err != nil {
+		return err
+	}
+
+	mnodes := make(map[string]bool, 0)
+	for _, n := range opts.Nodes {
+		if manifest.GetNode(n) == nil {
+			return fmt.Errorf("node %s not available in the backup", n)
+		}
+		mnodes[n] = true
+	}
+
+	executors := make(map[string]lxd_executor.LxdCExecutor, 0)
+	nodes := []*specs.LxdCBackupNodeInfo{}
+	nodesExecutor := []lxd_executor.LxdCExecutor{}
+	presents := []string{}
+
+	for idx := range manifest.Nodes {
+		node := &manifest.Nodes[idx]
+
+		if len(mnodes) > 0 && !mnodes[node.Name] {
+			continue
+		}
+
+		grp := &specs.LxdCGroup{
+			Name:           node.Group,
+			Connection:     node.Connection,
+			ConnectionType: node.ConnectionType,
+		}
+		if opts.Connection != "" {
+			grp.Connection = opts.Connection
+			grp.ConnectionType = opts.ConnectionType
+		}
+
+		key := grp.ConnectionType + "/" + grp.Connection
+		executor, ok := executors[key]
+		if !ok {
+			executor, err = i.newGroupExecutor(grp)
+			if err != nil {
+				return fmt.Errorf("Error on initialize executor for connection %s: %s",
+					grp.Connection, err.Error())
+			}
+			executors[key] = executor
+		}
+
+		present, err := executor.IsPresentContainer(node.Name)
+		if err != nil {
+			return err
+		}
+		if present {
+			presents = append(presents,
+				fmt.Sprintf("%s (connection %s)", node.Name, grp.Connection))
+			continue
+		}
+
+		nodes = append(nodes, node)
+		nodesExecutor = append(nodesExecutor, executor)
+	}
+
+	if len(presents) > 0 {
+		return fmt.Errorf("nodes already present: %s", strings.Join(presents, ", "))
+	}
+
+	restored := 0
+	for idx, node := range nodes {
+		i.Logger.InfoC(
+			i.Logger.Aurora.Bold(
+				i.Logger.Aurora.BrightCyan(
+					fmt.Sprintf(">>> [%s] Restoring from %s... - :package:",
+						node.Name, node.File))))
+
+		err = nodesExecutor[idx].ImportInstance(node.Name,
+			filepath.Join(backupDir, node.File), opts.Pool)
+		if err != nil {
+			return fmt.Errorf("error on restore node %s: %s", node.Name, err.Error())
+		}
+		restored++
+	}
+
+	if restored == 0 {
+		return errors.New("no nodes restored")
+	}
+
+	return nil
+}
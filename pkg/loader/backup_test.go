@@ -0,0 +1,95 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"os"
+	"path/filepath"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Project backups", func() {
+
+	var executor *fakeExecutor
+	var instance *LxdCInstance
+
+	BeforeEach(func() {
+		executor = newFakeExecutor()
+		executor.AddInstance("node1", true)
+		instance = newTestInstance(specs.LxdCProject{
+			Name: "proj1",
+			Groups: []specs.LxdCGroup{
+				{
+					Name:  "group1",
+					Nodes: []specs.LxdCNode{{Name: "node1"}},
+				},
+			},
+		}, executor)
+	})
+
+	It("Export with the default compression", func() {
+		exportDir := GinkgoT().TempDir()
+
+		backupDir, err := instance.ExportProjectBackup("proj1", exportDir, false, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(executor.Calls).To(ContainElement("ExportInstance node1 node1.tar.gz gzip"))
+
+		manifest, err := specs.BackupManifestFromFile(
+			filepath.Join(backupDir, specs.BackupManifestFile))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(manifest.Nodes[0].File).To(Equal("node1.tar.gz"))
+	})
+
+	It("Restore no nodes when a node is already present", func() {
+		executor.AddInstance("node2", true)
+		instance.Environments[0].Projects[0].Groups[0].Nodes = []specs.LxdCNode{
+			{Name: "node1"}, {Name: "node2"},
+		}
+
+		backupDir, err := instance.ExportProjectBackup("proj1", GinkgoT().TempDir(), false, "")
+		Expect(err).ToNot(HaveOccurred())
+
+		delete(executor.Instances, "node1")
+		executor.Calls = []string{}
+
+		err = instance.RestoreProjectBackup(backupDir, &LxdCBackupRestoreOpts{})
+		Expect(err).To(MatchError(ContainSubstring("node2")))
+		Expect(executor.Calls).To(BeEmpty())
+		Expect(executor.Instances).ToNot(HaveKey("node1"))
+
+		err = instance.RestoreProjectBackup(backupDir, &LxdCBackupRestoreOpts{
+			Nodes: []string{"node1"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(executor.Calls).To(Equal([]string{"ImportInstance node1 node1.tar.gz"}))
+		Expect(executor.Instances).To(HaveKey("node1"))
+	})
+
+	It("Purge only the completed backups", func() {
+		exportDir := GinkgoT().TempDir()
+		projectDir := filepath.Join(exportDir, "proj1")
+
+		for _, d := range []string{"20260101-100000", "20260102-100000", "20260103-100000"} {
+			Expect(os.MkdirAll(filepath.Join(projectDir, d), 0755)).To(Succeed())
+		}
+		for _, d := range []string{"20260101-100000", "20260102-100000"} {
+			Expect(os.WriteFile(filepath.Join(projectDir, d, specs.BackupManifestFile),
+				[]byte("project: proj1\n"), 0644)).To(Succeed())
+		}
+
+		err := instance.PurgeProjectBackups("proj1", exportDir,
+			&specs.LxdCBackupRetention{KeepLast: 1})
+		Expect(err).ToNot(HaveOccurred())
+
+		// The incomplete export isn't counted by the retention.
+		Expect(filepath.Join(projectDir, "20260101-100000")).ToNot(BeADirectory())
+		Expect(filepath.Join(projectDir, "20260102-100000")).To(BeADirectory())
+		Expect(filepath.Join(projectDir, "20260103-100000")).To(BeADirectory())
+	})
+})
@@ -5,10 +5,14 @@ See AUTHORS and LICENSE for the license details and contributors.
 package loader
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
 	base "github.com/MottainaiCI/lxd-compose/pkg/executor/base"
@@ -34,7 +38,7 @@ func (i *LxdCInstance) GetNodeHooks4Event(event string, proj *specs.LxdCProject,
 	return ans
 }
 
-func (i *LxdCInstance) ApplyProject(projectName string) error {
+func (i *LxdCInstance) ApplyProject(projectName string) (err error) {
 
 	env := i.GetEnvByProjectName(projectName)
 	if env == nil {
@@ -50,6 +54,21 @@ func (i *LxdCInstance) ApplyProject(projectName string) error {
 		proj.SetNodesPrefix(i.NodesPrefix)
 	}
 
+	// Initialize the journal used to resume a failed apply.
+	err = i.initJournal(projectName)
+	if err != nil {
+		return err
+	}
+	defer func() { i.journal = nil }()
+	i.loadJournalVars(proj)
+
+	defer func() {
+		if err != nil {
+			err = i.processFailureHooks(specs.HookOnProjectFailure,
+				proj, nil, nil, err)
+		}
+	}()
+
 	// Get only host hooks. All other hooks are handled by group and node.
 	preProjHooks := proj.GetHooks4Nodes(specs.HookPreProject, []string{"host"})
 	postProjHooks := proj.GetHooks4Nodes(specs.HookPostProject, []string{"*", "host"})
@@ -58,7 +77,7 @@ func (i *LxdCInstance) ApplyProject(projectName string) error {
 	i.Logger.Debug(fmt.Sprintf(
 		"[%s] Running %d %s hooks... ", projectName,
 		len(preProjHooks), specs.HookPreProject))
-	err := i.ProcessHooks(&preProjHooks, proj, nil, nil)
+	err = i.ProcessHooks(&preProjHooks, proj, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -68,26 +87,37 @@ func (i *LxdCInstance) ApplyProject(projectName string) error {
 		return err
 	}
 
-	// Compiler project files
-	err = template.CompileProjectFiles(proj, compiler, template.CompilerOpts{
-		Concurrency: i.Config.GetGeneral().Concurrency,
-	})
-	if err != nil {
-		return err
-	}
-
-	for _, grp := range proj.Groups {
-
-		if !grp.ToProcess(i.GroupsEnabled, i.GroupsDisabled) {
-			i.Logger.Debug("Skipped group ", grp.Name)
-			continue
+	// Compiler project files. On plan the templates are only reported.
+	if i.isDryRun() {
+		i.planTemplates(proj, nil)
+	} else {
+		err = template.CompileProjectFiles(proj, compiler, template.CompilerOpts{
+			Concurrency: i.Config.GetGeneral().Concurrency,
+		})
+		if err != nil {
+			return err
 		}
+	}
 
-		err := i.ApplyGroup(&grp, proj, env, compiler)
+	if proj.HasGroupsDependencies() {
+		err = i.applyGroupsLevels(proj, env, compiler)
 		if err != nil {
 			return err
 		}
+	} else {
+		for _, grp := range proj.Groups {
+
+			if !grp.ToProcess(i.GroupsEnabled, i.GroupsDisabled) {
+				i.Logger.Debug("Skipped group ", grp.Name)
+				continue
+			}
 
+			err = i.ApplyGroup(&grp, proj, env, compiler)
+			if err != nil {
+				return err
+			}
+
+		}
 	}
 
 	// Execute post-project hooks
@@ -99,126 +129,269 @@ func (i *LxdCInstance) ApplyProject(projectName string) error {
 		return err
 	}
 
+	// The apply is completed. The journal is not needed anymore.
+	return i.removeJournal()
+}
+
+// Apply the groups of the project following the dependencies graph.
+// The groups without dependencies between them are applied in parallel.
+func (i *LxdCInstance) applyGroupsLevels(proj *specs.LxdCProject,
+	env *specs.LxdCEnvironment, compiler template.LxdCTemplateCompiler) error {
+
+	levels, err := proj.GetGroupsLevels(i.GroupsEnabled, i.GroupsDisabled)
+	if err != nil {
+		return err
+	}
+
+	for idx, level := range levels {
+		names := []string{}
+		for _, grp := range level {
+			names = append(names, fmt.Sprintf("%s - %s", proj.Name, grp.Name))
+		}
+
+		i.Logger.Debug(fmt.Sprintf("[%s] Applying groups level %d: %v",
+			proj.Name, idx+1, names))
+
+		// On plan the groups are processed in order.
+		maxParallel := i.Config.GetGeneral().Concurrency
+		if i.Ask || i.isDryRun() || maxParallel < 1 {
+			maxParallel = 1
+		}
+
+		err = i.runParallel(names, maxParallel,
+			func(ctx context.Context, idx int) error {
+				return i.ApplyGroup(level[idx], proj, env, compiler)
+			})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (i *LxdCInstance) ProcessHooks(hooks *[]specs.LxdCHook, proj *specs.LxdCProject, group *specs.LxdCGroup, targetNode *specs.LxdCNode) error {
-	var res int
+// A single command or action of an hook to execute on a specific node.
+type hookCommand struct {
+	Hook    *specs.LxdCHook
+	Node    string
+	Command string
+	Action  *specs.LxdCHookAction
+}
+
+// Return the list of the commands to execute for the input hooks
+// filtered by the enabled/disabled flags. The hooks without node
+// are expanded for the target node or for all nodes of the group/project.
+func (i *LxdCInstance) getHooksCommands(hooks *[]specs.LxdCHook,
+	proj *specs.LxdCProject, group *specs.LxdCGroup,
+	targetNode *specs.LxdCNode) []hookCommand {
+
+	ans := []hookCommand{}
 	nodes := []specs.LxdCNode{}
-	storeVar := false
+
+	// Retrieve list of nodes
+	if group != nil {
+		nodes = group.Nodes
+	} else {
+		for _, g := range proj.Groups {
+			nodes = append(nodes, g.Nodes...)
+		}
+	}
+
+	for idx := range *hooks {
+		h := &(*hooks)[idx]
+
+		// Check if hooks must be processed
+		if !h.ToProcess(i.FlagsEnabled, i.FlagsDisabled) {
+			i.Logger.Debug("Skipped hooks ", *h)
+			continue
+		}
+
+		addEntry := func(cmds string, action *specs.LxdCHookAction) {
+			switch h.Node {
+			case "", "*":
+				if targetNode != nil {
+					ans = append(ans, hookCommand{h, targetNode.GetName(), cmds, action})
+				} else {
+					for _, node := range nodes {
+						ans = append(ans, hookCommand{h, node.GetName(), cmds, action})
+					}
+				}
+			default:
+				ans = append(ans, hookCommand{h, h.Node, cmds, action})
+			}
+		}
+
+		// The actions are executed before the commands.
+		for aidx := range h.Actions {
+			addEntry(h.Actions[aidx].String(), &h.Actions[aidx])
+		}
+
+		for _, cmds := range h.Commands {
+			addEntry(cmds, nil)
+		}
+	}
+
+	return ans
+}
+
+func (i *LxdCInstance) ProcessHooks(hooks *[]specs.LxdCHook, proj *specs.LxdCProject, group *specs.LxdCGroup, targetNode *specs.LxdCNode) error {
+	return i.processHooks(hooks, proj, group, targetNode, nil)
+}
+
+// Process the hooks with additional environment variables
+// available to the commands.
+func (i *LxdCInstance) processHooks(hooks *[]specs.LxdCHook,
+	proj *specs.LxdCProject, group *specs.LxdCGroup,
+	targetNode *specs.LxdCNode, extraEnvs map[string]string) error {
 
 	executorMap := make(map[string]lxd_executor.LxdCExecutor, 0)
+	// Used on parallel hooks to share the executors between the workers.
+	executorMutex := sync.Mutex{}
+
+	// When the nodes of the group are processed in parallel
+	// the output of the commands is prefixed with the node name.
+	prefixOutput := group != nil &&
+		(group.GetMaxParallel(i.Config.GetGeneral().Concurrency) > 1 ||
+			(i.Upgrade && group.RollingUpgrade != nil &&
+				group.RollingUpgrade.GetMaxUnavailable() > 1))
 
 	if len(*hooks) > 0 {
 
+		// On resume the hooks of the event already executed are skipped.
+		groupName, nodeName := "", ""
+		if group != nil {
+			groupName = group.Name
+		}
+		if targetNode != nil {
+			nodeName = targetNode.GetName()
+		}
+		event := (*hooks)[0].Event
+		// The failure hooks are not tracked by the journal.
+		journaled := !specs.IsFailureHookEvent(event)
+		if journaled && i.isStepDone(groupName, nodeName, event) {
+			i.Logger.Debug(fmt.Sprintf("[%s] Hooks %s of %s/%s already executed. Skipped.",
+				proj.Name, event, groupName, nodeName))
+			return nil
+		}
+
 		runSingleCmd := func(h *specs.LxdCHook, node, cmds string) error {
 			var executor lxd_executor.LxdCExecutor
+			var res int
+			storeVar := false
 
+			i.varsMutex.Lock()
 			envs, err := proj.GetEnvsMap()
+			i.varsMutex.Unlock()
 			if err != nil {
 				return err
 			}
 			if _, ok := envs["HOME"]; !ok {
 				envs["HOME"] = "/"
 			}
+			for k, v := range extraEnvs {
+				envs[k] = v
+			}
 
 			if node != "host" {
-
-				var grp *specs.LxdCGroup = nil
-				var nodeEntity *specs.LxdCNode = nil
-
-				_, _, grp, nodeEntity = i.GetEntitiesByNodeName(node)
-				if nodeEntity == nil && i.NodesPrefix != "" {
-					// Trying to search node with prefix
-					_, _, grp, nodeEntity = i.GetEntitiesByNodeName(
-						fmt.Sprintf("%s-%s", i.NodesPrefix, node))
-
-					if nodeEntity != nil {
-						node = fmt.Sprintf("%s-%s", i.NodesPrefix, node)
-					}
-				}
-
-				if nodeEntity != nil {
-					json, err := nodeEntity.ToJson()
-					if err != nil {
-						return err
-					}
-					envs["node"] = json
-
-					if nodeEntity.Labels != nil && len(nodeEntity.Labels) > 0 {
-						for k, v := range nodeEntity.Labels {
-							envs[k] = v
+				// The executors are shared between the workers
+				// of the parallel hooks.
+				err = func() error {
+					executorMutex.Lock()
+					defer executorMutex.Unlock()
+
+					var grp *specs.LxdCGroup = nil
+					var nodeEntity *specs.LxdCNode = nil
+
+					_, _, grp, nodeEntity = i.GetEntitiesByNodeName(node)
+					if nodeEntity == nil && i.NodesPrefix != "" {
+						// Trying to search node with prefix
+						_, _, grp, nodeEntity = i.GetEntitiesByNodeName(
+							fmt.Sprintf("%s-%s", i.NodesPrefix, node))
+
+						if nodeEntity != nil {
+							node = fmt.Sprintf("%s-%s", i.NodesPrefix, node)
 						}
 					}
 
-					if _, ok := executorMap[node]; !ok {
-						// Initialize executor
-						executor = lxd_executor.NewLxdCExecutor(grp.ConnectionType,
-							grp.Connection,
-							i.Config.GetGeneral().LxdConfDir, []string{}, grp.Ephemeral,
-							i.Config.GetLogging().CmdsOutput,
-							i.Config.GetLogging().RuntimeCmdsOutput)
-						err := executor.Setup()
+					if nodeEntity != nil {
+						json, err := nodeEntity.ToJson()
 						if err != nil {
 							return err
 						}
+						envs["node"] = json
 
-						executor.SetP2PMode(i.Config.GetGeneral().P2PMode)
-						executorMap[node] = executor
-					} else {
-
-						if group == nil && grp == nil {
-							return errors.New(fmt.Sprintf(
-								"Error on retrieve node information for %s and hook %v",
-								node, h))
+						if nodeEntity.Labels != nil && len(nodeEntity.Labels) > 0 {
+							for k, v := range nodeEntity.Labels {
+								envs[k] = v
+							}
 						}
 
-						if group == nil {
-							group = grp
+						if _, ok := executorMap[node]; !ok {
+							// Initialize executor
+							executor = i.newExecutor(grp.ConnectionType,
+								grp.Connection, grp.Ephemeral)
+							err := executor.Setup()
+							if err != nil {
+								return err
+							}
+
+							executorMap[node] = executor
+						} else {
+
+							if group == nil && grp == nil {
+								return errors.New(fmt.Sprintf(
+									"Error on retrieve node information for %s and hook %v",
+									node, h))
+							}
+
+							if group == nil {
+								group = grp
+							}
+
+							executor = i.newExecutor(grp.ConnectionType,
+								group.Connection, group.Ephemeral)
+							err := executor.Setup()
+							if err != nil {
+								return err
+							}
 						}
 
-						executor = lxd_executor.NewLxdCExecutor(grp.ConnectionType,
-							group.Connection,
-							i.Config.GetGeneral().LxdConfDir, []string{}, group.Ephemeral,
-							i.Config.GetLogging().CmdsOutput,
-							i.Config.GetLogging().RuntimeCmdsOutput)
-						err := executor.Setup()
-						if err != nil {
-							return err
+						// Initialize entrypoint to ensure to set always the
+						if nodeEntity.Entrypoint != nil && len(nodeEntity.Entrypoint) > 0 {
+							executor.SetEntrypoint(nodeEntity.Entrypoint)
+						} else {
+							executor.SetEntrypoint([]string{})
 						}
-						executor.SetP2PMode(i.Config.GetGeneral().P2PMode)
-					}
 
-					// Initialize entrypoint to ensure to set always the
-					if nodeEntity.Entrypoint != nil && len(nodeEntity.Entrypoint) > 0 {
-						executor.SetEntrypoint(nodeEntity.Entrypoint)
 					} else {
-						executor.SetEntrypoint([]string{})
+						executor = executorMap[node]
 					}
 
-				} else {
-					executor = executorMap[node]
+					return nil
+				}()
+				if err != nil {
+					return err
 				}
-
 			} else {
+				connType := ""
 				connection := "local"
 				ephemeral := true
 
+				// The hooks of the project are executed without group.
 				if group != nil {
+					connType = group.ConnectionType
 					connection = group.Connection
 					ephemeral = group.Ephemeral
 				}
 				// Initialize executor with local LXD connection
-				executor = lxd_executor.NewLxdCExecutor(group.ConnectionType,
-					connection,
-					i.Config.GetGeneral().LxdConfDir, []string{}, ephemeral,
-					i.Config.GetLogging().CmdsOutput,
-					i.Config.GetLogging().RuntimeCmdsOutput)
-				executor.SetP2PMode(i.Config.GetGeneral().P2PMode)
+				executor = i.newExecutor(connType,
+					connection, ephemeral)
 
 				// NOTE: I don't need to run executor.Setup() for host node.
 			}
 
+			executor.SetCommandTimeout(h.GetTimeout())
+
 			if h.Out2Var != "" || h.Err2Var != "" {
 				storeVar = true
 			} else {
@@ -253,12 +426,23 @@ func (i *LxdCInstance) ProcessHooks(hooks *[]specs.LxdCHook, proj *specs.LxdCPro
 				} else {
 					if i.Config.GetLogging().RuntimeCmdsOutput {
 						emitter := executor.GetEmitter()
+						outWriter := (emitter.(*base.LxdCEmitter)).GetLxdWriterStdout()
+						errWriter := (emitter.(*base.LxdCEmitter)).GetLxdWriterStderr()
+						if prefixOutput || h.Parallel {
+							outWriter = base.NewLxdCEmitterWriterWithPrefix(
+								"lxd_stdout", fmt.Sprintf("[%s] ", node))
+							errWriter = base.NewLxdCEmitterWriterWithPrefix(
+								"lxd_stderr", fmt.Sprintf("[%s] ", node))
+						}
 						res, err = executor.RunCommandWithOutput(
-							node, cmds, envs,
-							(emitter.(*base.LxdCEmitter)).GetLxdWriterStdout(),
-							(emitter.(*base.LxdCEmitter)).GetLxdWriterStderr(),
+							node, cmds, envs, outWriter, errWriter,
 							h.Entrypoint, h.Uid, h.Gid, h.Cwd,
 						)
+						if prefixOutput || h.Parallel {
+							// Flush the last line if it's without newline.
+							outWriter.Close()
+							errWriter.Close()
+						}
 					} else {
 						res, err = executor.RunCommand(
 							node, cmds, envs, h.Entrypoint,
@@ -280,81 +464,207 @@ func (i *LxdCInstance) ProcessHooks(hooks *[]specs.LxdCHook, proj *specs.LxdCPro
 			}
 
 			if storeVar {
-				if len(proj.Environments) == 0 {
-					proj.AddEnvironment(&specs.LxdCEnvVars{EnvVars: make(map[string]interface{}, 0)})
+				varsNode := ""
+				if h.GetVarsScope() == specs.HookVarsScopeNode {
+					varsNode = node
 				}
+
+				var out interface{}
 				if h.Out2Var != "" {
-					proj.Environments[len(proj.Environments)-1].EnvVars[h.Out2Var] = envs[h.Out2Var]
+					out, err = h.ParseOut2Var(envs[h.Out2Var])
+					if err != nil {
+						return err
+					}
+				}
+
+				i.varsMutex.Lock()
+				defer i.varsMutex.Unlock()
+
+				if h.Out2Var != "" {
+					proj.SetHookVar(h.Out2Var, out, varsNode)
+					err = i.setJournalVar(h.Out2Var, varsNode, out)
+					if err != nil {
+						return err
+					}
 				}
 				if h.Err2Var != "" {
-					proj.Environments[len(proj.Environments)-1].EnvVars[h.Err2Var] = envs[h.Err2Var]
+					proj.SetHookVar(h.Err2Var, envs[h.Err2Var], varsNode)
+					err = i.setJournalVar(h.Err2Var, varsNode, envs[h.Err2Var])
+					if err != nil {
+						return err
+					}
 				}
 			}
 
 			return nil
 		}
 
-		// Retrieve list of nodes
-		if group != nil {
-			nodes = group.Nodes
-		} else {
-			for _, g := range proj.Groups {
-				nodes = append(nodes, g.Nodes...)
+		runEntry := func(hc hookCommand) error {
+			toRun, err := i.evalHookWhen(hc.Hook, proj, hc.Node)
+			if err != nil {
+				return newApplyError(hc.Node, hc.Hook.Event, err)
+			}
+			if !toRun {
+				i.Logger.Debug(fmt.Sprintf("[%s] Skipped command of the hook %s: when %s is false.",
+					hc.Node, hc.Hook.Event, hc.Hook.When))
+				return nil
+			}
+
+			if i.isDryRun() {
+				i.planHook(hc, group, targetNode)
+				return nil
+			}
+
+			err = i.runHookCommand(hc, func(hc hookCommand) error {
+				if hc.Action != nil {
+					return i.runHookAction(hc, proj)
+				}
+				return runSingleCmd(hc.Hook, hc.Node, hc.Command)
+			})
+			if err != nil {
+				return newApplyError(hc.Node, hc.Hook.Event, err)
 			}
+			return nil
 		}
 
-		for _, h := range *hooks {
+		entries := i.getHooksCommands(hooks, proj, group, targetNode)
+		for idx := 0; idx < len(entries); {
+			if entries[idx].Hook.Parallel && !i.isDryRun() {
+				// The commands of the same hook are executed
+				// in parallel on the nodes.
+				end := idx + 1
+				for end < len(entries) && entries[end].Hook == entries[idx].Hook {
+					end++
+				}
 
-			// Check if hooks must be processed
-			if !h.ToProcess(i.FlagsEnabled, i.FlagsDisabled) {
-				i.Logger.Debug("Skipped hooks ", h)
+				err := i.runHookParallel(entries[idx:end], runEntry)
+				if err != nil {
+					return err
+				}
+				idx = end
 				continue
 			}
 
-			if h.Commands != nil && len(h.Commands) > 0 {
+			err := runEntry(entries[idx])
+			if err != nil {
+				return err
+			}
+			idx++
+		}
 
-				for _, cmds := range h.Commands {
-					switch h.Node {
-					case "", "*":
-						if targetNode != nil {
-							err := runSingleCmd(&h, targetNode.GetName(), cmds)
-							if err != nil {
-								return err
-							}
-						} else {
-							for _, node := range nodes {
-								err := runSingleCmd(&h, node.GetName(), cmds)
-								if err != nil {
-									return err
-								}
-							}
-						}
+		if journaled {
+			err := i.setStepDone(groupName, nodeName, event)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-					default:
+	return nil
+}
 
-						err := runSingleCmd(&h, h.Node, cmds)
-						if err != nil {
-							return err
-						}
-					}
+// Run the commands of a parallel hook. The commands of every node
+// are executed in order by a dedicated worker and the errors of
+// all nodes are returned together.
+func (i *LxdCInstance) runHookParallel(entries []hookCommand,
+	runEntry func(hc hookCommand) error) error {
+
+	nodes := []string{}
+	mentries := make(map[string][]hookCommand, 0)
+	for _, hc := range entries {
+		if _, ok := mentries[hc.Node]; !ok {
+			nodes = append(nodes, hc.Node)
+		}
+		mentries[hc.Node] = append(mentries[hc.Node], hc)
+	}
 
-				}
+	h := entries[0].Hook
+	maxParallel := h.GetMaxParallel(i.Config.GetGeneral().Concurrency)
+	if maxParallel > len(nodes) {
+		maxParallel = len(nodes)
+	}
 
+	i.Logger.Debug(fmt.Sprintf("Running hook %s on %d nodes with %d workers.",
+		h.Event, len(nodes), maxParallel))
+
+	failed := []string{}
+	errs := []error{}
+	for idx, err := range i.runWorkers(nodes, maxParallel, false,
+		func(ctx context.Context, idx int) error {
+			for _, hc := range mentries[nodes[idx]] {
+				err := runEntry(hc)
+				if err != nil {
+					return err
+				}
 			}
+			return nil
+		}) {
+		if err != nil {
+			i.Logger.Error(fmt.Sprintf("[%s] Failed: %s", nodes[idx], err.Error()))
+			failed = append(failed, nodes[idx])
+			errs = append(errs, err)
 		}
 	}
 
+	if len(errs) > 0 {
+		return fmt.Errorf("hook %s failed on nodes %s: %w",
+			h.Event, strings.Join(failed, ", "), errors.Join(errs...))
+	}
+
 	return nil
 }
 
-func (i *LxdCInstance) ApplyGroup(group *specs.LxdCGroup, proj *specs.LxdCProject, env *specs.LxdCEnvironment, compiler template.LxdCTemplateCompiler) error {
+// Run the command of the hook with the retries configured and
+// apply the on_failure policy when all the attempts fail.
+func (i *LxdCInstance) runHookCommand(hc hookCommand,
+	runCmd func(hc hookCommand) error) error {
+	var err error
+
+	retries := hc.Hook.GetRetries()
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			i.Logger.Warning(fmt.Sprintf(
+				"[%s] Command failed (%s). Retry %d of %d in %s...",
+				hc.Node, err.Error(), attempt, retries, hc.Hook.GetRetryDelay()))
+			time.Sleep(hc.Hook.GetRetryDelay())
+		}
+
+		err = runCmd(hc)
+		if err == nil {
+			return nil
+		}
+	}
+
+	switch hc.Hook.GetOnFailure() {
+	case specs.HookOnFailureWarn:
+		i.Logger.Warning(fmt.Sprintf(
+			"[%s] Hook %s failed: %s. Continue.", hc.Node, hc.Hook.Event, err.Error()))
+		return nil
+	case specs.HookOnFailureContinue:
+		i.Logger.Debug(fmt.Sprintf(
+			"[%s] Hook %s failed: %s. Continue.", hc.Node, hc.Hook.Event, err.Error()))
+		return nil
+	default:
+		return err
+	}
+}
+
+func (i *LxdCInstance) ApplyGroup(group *specs.LxdCGroup, proj *specs.LxdCProject, env *specs.LxdCEnvironment, compiler template.LxdCTemplateCompiler) (err error) {
+
+	defer func() {
+		if err != nil {
+			err = i.processFailureHooks(specs.HookOnGroupFailure,
+				proj, group, nil, err)
+		}
+	}()
 
-	var syncSourceDir string
 	envBaseAbs, err := filepath.Abs(filepath.Dir(env.File))
 	if err != nil {
 		return err
 	}
 
+	i.planGroup(group)
+
 	// Retrieve pre-group hooks from project
 	preGroupHooks := proj.GetHooks4Nodes(specs.HookPreGroup, []string{"*", "host"})
 	// Retrieve pre-group hooks from group
@@ -369,28 +679,29 @@ func (i *LxdCInstance) ApplyGroup(group *specs.LxdCGroup, proj *specs.LxdCProjec
 		return err
 	}
 
+	// The compiler is shared between the groups of the project.
+	i.varsMutex.Lock()
 	// We need reload variables updated from out2var/err2var hooks.
 	compiler.InitVars()
 
 	// Compile group templates
-	err = template.CompileGroupFiles(group, compiler, template.CompilerOpts{
-		Concurrency: i.Config.GetGeneral().Concurrency,
-	})
+	if i.isDryRun() {
+		i.planTemplates(proj, group)
+	} else {
+		err = template.CompileGroupFiles(group, compiler, template.CompilerOpts{
+			Concurrency: i.Config.GetGeneral().Concurrency,
+		})
+	}
+	i.varsMutex.Unlock()
 	if err != nil {
 		return err
 	}
 
 	// Initialize executor
-	executor := lxd_executor.NewLxdCExecutor(group.ConnectionType,
-		group.Connection,
-		i.Config.GetGeneral().LxdConfDir, []string{}, group.Ephemeral,
-		i.Config.GetLogging().CmdsOutput,
-		i.Config.GetLogging().RuntimeCmdsOutput)
-	err = executor.Setup()
+	executor, err := i.newGroupExecutor(group)
 	if err != nil {
 		return err
 	}
-	executor.SetP2PMode(i.Config.GetGeneral().P2PMode)
 
 	// Retrieve the list of configured profiles
 	instanceProfiles, err := executor.GetProfilesList()
@@ -400,91 +711,206 @@ func (i *LxdCInstance) ApplyGroup(group *specs.LxdCGroup, proj *specs.LxdCProjec
 				group.Name, err.Error()))
 	}
 
-	// TODO: implement parallel creation
-	for _, node := range group.Nodes {
+	maxParallel := group.GetMaxParallel(i.Config.GetGeneral().Concurrency)
+	if maxParallel > 1 && i.isDryRun() {
+		maxParallel = 1
+	}
+	if maxParallel > 1 && i.Ask {
+		i.Logger.Debug(fmt.Sprintf(
+			"[%s - %s] Ask mode enabled. Disable parallel provisioning of the nodes.",
+			proj.Name, group.Name))
+		maxParallel = 1
+	}
 
-		syncSourceDir = ""
+	// Without dependencies all nodes are in the same level.
+	levels, err := group.GetNodesLevels()
+	if err != nil {
+		return err
+	}
 
-		// Initialize entrypoint to ensure to set always the
-		if node.Entrypoint != nil && len(node.Entrypoint) > 0 {
-			executor.SetEntrypoint(node.Entrypoint)
-		} else {
-			executor.SetEntrypoint([]string{})
+	if i.Upgrade && group.RollingUpgrade != nil {
+		err = i.applyNodesRolling(levels, group, proj, compiler,
+			instanceProfiles, envBaseAbs)
+	} else {
+		for _, level := range levels {
+			if maxParallel > 1 && len(level) > 1 {
+				err = i.applyNodesParallel(level, group, proj, compiler,
+					instanceProfiles, envBaseAbs, maxParallel)
+			} else {
+				for _, node := range level {
+					err = i.applyNode(context.Background(), node,
+						group, proj, compiler, executor, instanceProfiles, envBaseAbs)
+					if err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				break
+			}
 		}
+	}
+	if err != nil {
+		return err
+	}
 
-		isPresent, err := executor.IsPresentContainer(node.GetName())
-		if err != nil {
-			i.Logger.Error("Error on check if container " +
-				node.GetName() + " is present: " + err.Error())
-			return err
-		}
+	// Retrieve post-group hooks from project
+	postGroupHooks := proj.GetHooks4Nodes(specs.HookPostGroup, []string{"*", "host"})
+	postGroupHooks = append(postGroupHooks, group.GetHooks4Nodes(specs.HookPostGroup, []string{"*", "host"})...)
 
-		i.Logger.Debug(fmt.Sprintf(
-			"[%s - %s] Node %s is present: %v.",
-			proj.Name, group.Name, node.GetName(), isPresent))
-
-		if !isPresent {
-
-			// Execute the pre-node-creation hooks,
-			// create the container and run the post-node-creation
-			// hooks.
-			err := i.createInstance(
-				proj, group, &node,
-				executor,
-				instanceProfiles,
-			)
-			if err != nil {
-				return err
-			}
+	// Execute post-group hooks
+	i.Logger.Debug(fmt.Sprintf(
+		"[%s - %s] Running %d %s hooks... ", proj.Name, group.Name,
+		len(postGroupHooks), specs.HookPostGroup))
+	err = i.ProcessHooks(&postGroupHooks, proj, group, nil)
 
-		} else {
+	return err
+}
+
+func (i *LxdCInstance) newGroupExecutor(group *specs.LxdCGroup) (lxd_executor.LxdCExecutor, error) {
+	executor := i.newExecutor(group.ConnectionType,
+		group.Connection, group.Ephemeral)
+	err := executor.Setup()
+	if err != nil {
+		return nil, err
+	}
+
+	return executor, nil
+}
+
+func (i *LxdCInstance) applyNodesParallel(nodes []*specs.LxdCNode,
+	group *specs.LxdCGroup, proj *specs.LxdCProject,
+	compiler template.LxdCTemplateCompiler,
+	instanceProfiles []string, envBaseAbs string, maxParallel int) error {
 
-			isRunning, err := executor.IsRunningContainer(node.GetName())
+	i.Logger.Debug(fmt.Sprintf(
+		"[%s - %s] Processing %d nodes with %d workers.",
+		proj.Name, group.Name, len(nodes), maxParallel))
+
+	names := []string{}
+	for _, node := range nodes {
+		names = append(names,
+			fmt.Sprintf("%s - %s - %s", proj.Name, group.Name, node.GetName()))
+	}
+
+	return i.runParallel(names, maxParallel,
+		func(ctx context.Context, idx int) error {
+			// Every worker uses a dedicated executor because the
+			// entrypoint is configured for node.
+			executor, err := i.newGroupExecutor(group)
 			if err != nil {
-				i.Logger.Error(
-					fmt.Sprintf("Error on check if container %s is running: %s",
-						node.GetName(), err.Error()))
 				return err
 			}
+			return i.applyNode(ctx, nodes[idx], group, proj, compiler,
+				executor, instanceProfiles, envBaseAbs)
+		})
+}
 
-			if i.Upgrade {
+func (i *LxdCInstance) applyNode(ctx context.Context, node *specs.LxdCNode,
+	group *specs.LxdCGroup, proj *specs.LxdCProject,
+	compiler template.LxdCTemplateCompiler,
+	executor lxd_executor.LxdCExecutor,
+	instanceProfiles []string, envBaseAbs string) error {
 
-				// POST: The instance is already present
-				//       but the upgrade flag is enable.
+	err := i.applyNodeSteps(ctx, node, group, proj, compiler, executor,
+		instanceProfiles, envBaseAbs)
+	if err != nil {
+		err = i.processFailureHooks(specs.HookOnNodeFailure,
+			proj, group, node, newApplyError(node.GetName(), failureEventApply, err))
+	}
 
-				if isRunning {
+	return err
+}
 
-					if i.Ask {
-						wantUpgrade := helpers.Ask(
-							fmt.Sprintf(
-								"[%s - %s] Found running node %s, are you sure to proceed with the upgrade? [y/N]: ",
-								proj.Name, group.Name, node.GetName(),
-							))
-						if !wantUpgrade {
-							return fmt.Errorf(
-								"Upgrade process stopped by user.",
-							)
-						}
-					}
+func (i *LxdCInstance) applyNodeSteps(ctx context.Context, node *specs.LxdCNode,
+	group *specs.LxdCGroup, proj *specs.LxdCProject,
+	compiler template.LxdCTemplateCompiler,
+	executor lxd_executor.LxdCExecutor,
+	instanceProfiles []string, envBaseAbs string) (err error) {
 
-					preNodeUpgradeHooks := i.GetNodeHooks4Event(
-						specs.HookPreNodeUpgrade,
-						proj, group, &node)
+	var syncSourceDir string
 
-					// Run post-node-creation hooks
-					i.Logger.Debug(fmt.Sprintf(
-						"[%s - %s] Running %d %s hooks for node %s... ",
-						proj.Name, group.Name, len(preNodeUpgradeHooks),
-						specs.HookPreNodeUpgrade, node.GetName()))
-					err = i.ProcessHooks(&preNodeUpgradeHooks, proj, group, &node)
-					if err != nil {
-						return err
-					}
+	// With the snapshot strategy a copy of the instance is used to
+	// restore the node when the upgrade or the next steps of the
+	// node fail. The copy is removed when all steps are completed.
+	backupName := ""
+	wasRunning := false
+	defer func() {
+		if backupName == "" {
+			return
+		}
+
+		if err != nil {
+			err = i.rollbackInstance(proj, group, node, executor,
+				backupName, wasRunning, err)
+			return
+		}
+
+		rerr := executor.DeleteContainer(backupName)
+		if rerr != nil {
+			i.Logger.Warning(fmt.Sprintf(
+				"[%s - %s] Error on remove the instance %s: %s",
+				proj.Name, group.Name, backupName, rerr.Error()))
+		}
+	}()
+
+	// Initialize entrypoint to ensure to set always the
+	if node.Entrypoint != nil && len(node.Entrypoint) > 0 {
+		executor.SetEntrypoint(node.Entrypoint)
+	} else {
+		executor.SetEntrypoint([]string{})
+	}
+
+	isPresent, err := executor.IsPresentContainer(node.GetName())
+	if err != nil {
+		i.Logger.Error("Error on check if container " +
+			node.GetName() + " is present: " + err.Error())
+		return err
+	}
+
+	i.Logger.Debug(fmt.Sprintf(
+		"[%s - %s] Node %s is present: %v.",
+		proj.Name, group.Name, node.GetName(), isPresent))
+
+	if !isPresent {
+		i.planNodeAction(node, specs.PlanActionCreate)
+
+		// Execute the pre-node-creation hooks,
+		// create the container and run the post-node-creation
+		// hooks.
+		err := i.createInstance(
+			proj, group, node,
+			executor,
+			instanceProfiles,
+		)
+		if err != nil {
+			return err
+		}
+
+	} else {
+
+		isRunning, err := executor.IsRunningContainer(node.GetName())
+		if err != nil {
+			i.Logger.Error(
+				fmt.Sprintf("Error on check if container %s is running: %s",
+					node.GetName(), err.Error()))
+			return err
+		}
+		i.planNodeState(node, true, isRunning)
+
+		// On resume the nodes already upgraded are skipped.
+		if i.Upgrade && !i.isStepDone(group.Name, node.GetName(), specs.JournalStepUpgrade) {
+			i.planNodeAction(node, specs.PlanActionUpgrade)
 
-				} else if i.Ask {
+			// POST: The instance is already present
+			//       but the upgrade flag is enable.
+
+			if isRunning {
+
+				if i.Ask {
 					wantUpgrade := helpers.Ask(
 						fmt.Sprintf(
-							"[%s - %s] Found stopped node %s, are you sure to proceed with the upgrade of the node? [y/N]: ",
+							"[%s - %s] Found running node %s, are you sure to proceed with the upgrade? [y/N]: ",
 							proj.Name, group.Name, node.GetName(),
 						))
 					if !wantUpgrade {
@@ -494,161 +920,273 @@ func (i *LxdCInstance) ApplyGroup(group *specs.LxdCGroup, proj *specs.LxdCProjec
 					}
 				}
 
-				// POST: The running container is stopped
-				//       and destroyed.
-				err = executor.DeleteContainer(node.GetName())
+				preNodeUpgradeHooks := i.GetNodeHooks4Event(
+					specs.HookPreNodeUpgrade,
+					proj, group, node)
+
+				// Run post-node-creation hooks
+				i.Logger.Debug(fmt.Sprintf(
+					"[%s - %s] Running %d %s hooks for node %s... ",
+					proj.Name, group.Name, len(preNodeUpgradeHooks),
+					specs.HookPreNodeUpgrade, node.GetName()))
+				err = i.ProcessHooks(&preNodeUpgradeHooks, proj, group, node)
 				if err != nil {
-					i.Logger.Error("Error on destroy container " + node.GetName() +
-						": " + err.Error())
 					return err
 				}
 
-				// Execute the pre-node-creation hooks,
-				// create the container and run the post-node-creation
-				// hooks.
-				err := i.createInstance(
-					proj, group, &node,
-					executor,
-					instanceProfiles,
-				)
+			} else if i.Ask {
+				wantUpgrade := helpers.Ask(
+					fmt.Sprintf(
+						"[%s - %s] Found stopped node %s, are you sure to proceed with the upgrade of the node? [y/N]: ",
+						proj.Name, group.Name, node.GetName(),
+					))
+				if !wantUpgrade {
+					return fmt.Errorf(
+						"Upgrade process stopped by user.",
+					)
+				}
+			}
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if node.GetUpgradeStrategy(group.GetUpgradeStrategy()) == specs.UpgradeStrategySnapshot {
+				backupName, err = i.backupInstance(proj, group, node, executor)
 				if err != nil {
 					return err
 				}
+				wasRunning = isRunning
+			}
 
-				postNodeUpgradeHooks := i.GetNodeHooks4Event(
-					specs.HookPostNodeUpgrade,
-					proj, group, &node)
+			err = i.upgradeInstance(proj, group, node, executor, instanceProfiles)
+			if err != nil {
+				return err
+			}
 
-				// Run post-node-creation hooks
-				i.Logger.Debug(fmt.Sprintf(
-					"[%s - %s] Running %d %s hooks for node %s... ",
-					proj.Name, group.Name, len(postNodeUpgradeHooks),
-					specs.HookPostNodeUpgrade, node.GetName()))
-				err = i.ProcessHooks(&postNodeUpgradeHooks, proj, group, &node)
+			err = i.setStepDone(group.Name, node.GetName(), specs.JournalStepUpgrade)
+			if err != nil {
+				return err
+			}
+
+		} else {
+
+			if !i.SkipReconcile {
+				err = i.reconcileInstance(proj, group, node, executor,
+					instanceProfiles, isRunning)
 				if err != nil {
 					return err
 				}
+			}
 
-			} else {
+			if !isRunning {
+				i.planNodeAction(node, specs.PlanActionStart)
 
-				if !isRunning {
-					// Run post-node-creation hooks
-					i.Logger.Debug(fmt.Sprintf(
-						"[%s - %s] Node %s is already present but not running. I'm starting it.",
-						proj.Name, group.Name, node.GetName()))
+				// Run post-node-creation hooks
+				i.Logger.Debug(fmt.Sprintf(
+					"[%s - %s] Node %s is already present but not running. I'm starting it.",
+					proj.Name, group.Name, node.GetName()))
 
-					err = executor.StartContainer(node.GetName())
-					if err != nil {
-						i.Logger.Error(
-							fmt.Sprintf("Error on start container %s: %s",
-								node.GetName(), err.Error()))
-						return err
-					}
+				err = executor.StartContainer(node.GetName())
+				if err != nil {
+					i.Logger.Error(
+						fmt.Sprintf("Error on start container %s: %s",
+							node.GetName(), err.Error()))
+					return err
+				}
+
+				err = i.waitNodeAgent(node, executor)
+				if err != nil {
+					return err
 				}
 			}
 
+			// The node created by the apply resumed could have
+			// post-node-creation hooks not yet executed. The hooks
+			// already done are skipped.
+			if i.isStepDone(group.Name, node.GetName(), specs.JournalStepCreate) {
+				err = i.runPostCreationHooks(proj, group, node)
+				if err != nil {
+					return err
+				}
+			}
 		}
 
-		// Retrieve pre-node-sync hooks of the node from project
-		preSyncHooks := i.GetNodeHooks4Event(specs.HookPreNodeSync, proj, group, &node)
+	}
 
-		// Run pre-node-sync hooks
-		err = i.ProcessHooks(&preSyncHooks, proj, group, &node)
-		if err != nil {
-			return err
-		}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// Retrieve pre-node-sync hooks of the node from project
+	preSyncHooks := i.GetNodeHooks4Event(specs.HookPreNodeSync, proj, group, node)
 
-		// We need reload variables updated from out2var/err2var hooks.
-		compiler.InitVars()
+	// Run pre-node-sync hooks
+	err = i.ProcessHooks(&preSyncHooks, proj, group, node)
+	if err != nil {
+		return err
+	}
 
-		// Compile node templates
-		err = template.CompileNodeFiles(node, compiler, template.CompilerOpts{
+	// The compiler is shared between the nodes of the group.
+	i.varsMutex.Lock()
+	// We need reload variables updated from out2var/err2var hooks.
+	compiler.InitVars()
+
+	// Compile node templates. On plan the pushed templates are
+	// rendered to check them but only the executor records the push.
+	if i.isDryRun() {
+		i.planNode(node, func(nplan *specs.LxdCNodePlan) {
+			nplan.ConfigTemplates = node.ConfigTemplates
+		})
+	} else {
+		err = template.CompileNodeFiles(*node, compiler, template.CompilerOpts{
 			Concurrency: i.Config.GetGeneral().Concurrency,
 		})
+	}
+	var pushTemplates []template.LxdCRenderedTemplate
+	if err == nil && !i.SkipSync {
+		pushTemplates, err = template.RenderNodePushFiles(*node, compiler)
+	}
+	i.varsMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if len(pushTemplates) > 0 {
+		err = i.pushNodeTemplates(node, executor, pushTemplates)
 		if err != nil {
-			return err
+			return newApplyError(node.GetName(), specs.JournalStepSync, err)
 		}
+	}
 
-		if len(node.SyncResources) > 0 && !i.SkipSync {
-			if node.SourceDir != "" {
-				if node.IsSourcePathRelative() {
-					syncSourceDir = filepath.Join(envBaseAbs, node.SourceDir)
-				} else {
-					syncSourceDir = node.SourceDir
-				}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	syncNeeded := len(node.SyncResources) > 0 && !i.SkipSync &&
+		!i.isStepDone(group.Name, node.GetName(), specs.JournalStepSync)
+
+	if syncNeeded && i.isDryRun() {
+		i.planNode(node, func(nplan *specs.LxdCNodePlan) {
+			nplan.SyncResources = node.SyncResources
+		})
+	} else if syncNeeded {
+		if node.SourceDir != "" {
+			if node.IsSourcePathRelative() {
+				syncSourceDir = filepath.Join(envBaseAbs, node.SourceDir)
 			} else {
-				// Use env file directory
-				syncSourceDir = envBaseAbs
+				syncSourceDir = node.SourceDir
 			}
+		} else {
+			// Use env file directory
+			syncSourceDir = envBaseAbs
+		}
 
-			i.Logger.Debug(i.Logger.Aurora.Bold(
-				i.Logger.Aurora.BrightCyan(
-					">>> [" + node.GetName() + "] Using sync source basedir " +
-						syncSourceDir)))
-
-			nResources := len(node.SyncResources)
-			i.Logger.InfoC(
-				i.Logger.Aurora.Bold(
-					i.Logger.Aurora.BrightCyan(
-						fmt.Sprintf(">>> [%s] Syncing %d resources... - :bus:",
-							node.GetName(), nResources))))
+		i.Logger.Debug(i.Logger.Aurora.Bold(
+			i.Logger.Aurora.BrightCyan(
+				">>> [" + node.GetName() + "] Using sync source basedir " +
+					syncSourceDir)))
 
-			for idx, resource := range node.SyncResources {
+		nResources := len(node.SyncResources)
+		i.Logger.InfoC(
+			i.Logger.Aurora.Bold(
+				i.Logger.Aurora.BrightCyan(
+					fmt.Sprintf(">>> [%s] Syncing %d resources... - :bus:",
+						node.GetName(), nResources))))
 
-				var sourcePath string
+		for idx, resource := range node.SyncResources {
 
-				if filepath.IsAbs(resource.Source) {
-					sourcePath = resource.Source
-				} else {
-					sourcePath = filepath.Join(syncSourceDir, resource.Source)
-				}
+			var sourcePath string
 
-				i.Logger.DebugC(
-					i.Logger.Aurora.Italic(
-						i.Logger.Aurora.BrightCyan(
-							fmt.Sprintf(">>> [%s] %s => %s",
-								node.GetName(), resource.Source,
-								resource.Destination))))
-
-				err = executor.RecursivePushFile(node.GetName(),
-					sourcePath, resource.Destination)
-				if err != nil {
-					i.Logger.Debug("Error on sync from sourcePath " + sourcePath +
-						" to dest " + resource.Destination)
-					i.Logger.Error("Error on sync " + resource.Source + ": " + err.Error())
-					return err
-				}
+			if filepath.IsAbs(resource.Source) {
+				sourcePath = resource.Source
+			} else {
+				sourcePath = filepath.Join(syncSourceDir, resource.Source)
+			}
 
-				i.Logger.InfoC(
+			i.Logger.DebugC(
+				i.Logger.Aurora.Italic(
 					i.Logger.Aurora.BrightCyan(
-						fmt.Sprintf(">>> [%s] - [%2d/%2d] %s - :check_mark:",
-							node.GetName(), idx+1, nResources, resource.Destination)))
+						fmt.Sprintf(">>> [%s] %s => %s",
+							node.GetName(), resource.Source,
+							resource.Destination))))
+
+			summary := ""
+			if resource.IsIncremental() {
+				var changes *specs.LxdCSyncSummary
+				changes, err = i.syncResourceIncremental(node, executor,
+					sourcePath, &resource)
+				if changes != nil {
+					summary = " (" + changes.String() + ")"
+				}
+			} else {
+				err = executor.RecursivePushFileWithOpts(node.GetName(),
+					sourcePath, resource.Destination, &resource.LxdCFileOpts)
+			}
+			if err != nil {
+				i.Logger.Debug("Error on sync from sourcePath " + sourcePath +
+					" to dest " + resource.Destination)
+				i.Logger.Error("Error on sync " + resource.Source + ": " + err.Error())
+				return newApplyError(node.GetName(), specs.JournalStepSync, err)
 			}
 
+			i.Logger.InfoC(
+				i.Logger.Aurora.BrightCyan(
+					fmt.Sprintf(">>> [%s] - [%2d/%2d] %s%s - :check_mark:",
+						node.GetName(), idx+1, nResources, resource.Destination, summary)))
 		}
 
-		// Retrieve post-node-sync hooks of the node from project
-		postSyncHooks := i.GetNodeHooks4Event(specs.HookPostNodeSync, proj, group, &node)
+		err = i.setStepDone(group.Name, node.GetName(), specs.JournalStepSync)
+		if err != nil {
+			return err
+		}
+	}
 
-		// Run post-node-sync hooks
-		err = i.ProcessHooks(&postSyncHooks, proj, group, &node)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// Wait for the services of the node before the post-node-sync hooks.
+	if node.Readiness != nil && len(node.Readiness.Probes) > 0 && !i.isDryRun() {
+		err = i.waitNodeReadiness(proj, group, node, executor)
 		if err != nil {
 			return err
 		}
+	}
 
+	if ctx.Err() != nil {
+		return ctx.Err()
 	}
 
-	// Retrieve post-group hooks from project
-	postGroupHooks := proj.GetHooks4Nodes(specs.HookPostGroup, []string{"*", "host"})
-	postGroupHooks = append(postGroupHooks, group.GetHooks4Nodes(specs.HookPostGroup, []string{"*", "host"})...)
+	// Retrieve post-node-sync hooks of the node from project
+	postSyncHooks := i.GetNodeHooks4Event(specs.HookPostNodeSync, proj, group, node)
 
-	// Execute post-group hooks
-	i.Logger.Debug(fmt.Sprintf(
-		"[%s - %s] Running %d %s hooks... ", proj.Name, group.Name,
-		len(postGroupHooks), specs.HookPostGroup))
-	err = i.ProcessHooks(&postGroupHooks, proj, group, nil)
+	// Run post-node-sync hooks
+	err = i.ProcessHooks(&postSyncHooks, proj, group, node)
+	if err != nil {
+		return err
+	}
 
-	return err
+	fetchNeeded := len(node.FetchResources) > 0 &&
+		!i.isStepDone(group.Name, node.GetName(), specs.JournalStepFetch)
+
+	if fetchNeeded && i.isDryRun() {
+		i.planNode(node, func(nplan *specs.LxdCNodePlan) {
+			nplan.FetchResources = node.FetchResources
+		})
+	} else if fetchNeeded {
+		err = i.fetchResources(proj, node, compiler, executor, envBaseAbs)
+		if err != nil {
+			return newApplyError(node.GetName(), specs.JournalStepFetch, err)
+		}
+
+		err = i.setStepDone(group.Name, node.GetName(), specs.JournalStepFetch)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (i *LxdCInstance) createInstance(
@@ -676,6 +1214,10 @@ func (i *LxdCInstance) createInstance(
 	profiles = append(profiles, node.Profiles...)
 
 	configMap := node.GetLxdConfig(group.GetLxdConfig())
+	devicesMap := node.GetLxdDevices(group.GetLxdDevices())
+	// Track the config keys and the devices set to remove them on reconcile.
+	specs.SetManagedConfigKeys(configMap)
+	specs.SetManagedDevices(configMap, devicesMap)
 
 	i.Logger.Debug(fmt.Sprintf("[%s] Using profiles %s",
 		node.GetName(), profiles))
@@ -683,13 +1225,16 @@ func (i *LxdCInstance) createInstance(
 	i.Logger.Debug(fmt.Sprintf("[%s] Using config map %s",
 		node.GetName(), configMap))
 
+	i.Logger.Debug(fmt.Sprintf("[%s] Using devices %s",
+		node.GetName(), devicesMap))
+
 	err = i.validateProfiles(instanceProfiles, profiles)
 	if err != nil {
 		return err
 	}
 
-	err = executor.CreateContainerWithConfig(node.GetName(), node.ImageSource,
-		node.ImageRemoteServer, profiles, configMap)
+	err = executor.CreateInstanceWithConfig(node.GetName(), node.ImageSource,
+		node.ImageRemoteServer, node.GetType(), profiles, configMap, devicesMap)
 	if err != nil {
 		i.Logger.Error("Error on create container " +
 			node.GetName() + ":" + err.Error())
@@ -706,6 +1251,26 @@ func (i *LxdCInstance) createInstance(
 		}
 	}
 
+	err = i.waitNodeAgent(node, executor)
+	if err != nil {
+		return err
+	}
+
+	// On resume the node is present and only the pending
+	// post-node-creation hooks are executed.
+	err = i.setStepDone(group.Name, node.GetName(), specs.JournalStepCreate)
+	if err != nil {
+		return err
+	}
+
+	return i.runPostCreationHooks(proj, group, node)
+}
+
+func (i *LxdCInstance) runPostCreationHooks(
+	proj *specs.LxdCProject,
+	group *specs.LxdCGroup,
+	node *specs.LxdCNode) error {
+
 	postCreationHooks := i.GetNodeHooks4Event(specs.HookPostNodeCreation, proj, group, node)
 
 	// Run post-node-creation hooks
@@ -713,12 +1278,30 @@ func (i *LxdCInstance) createInstance(
 		"[%s - %s] Running %d %s hooks for node %s... ",
 		proj.Name, group.Name, len(postCreationHooks),
 		specs.HookPostNodeCreation, node.GetName()))
-	err = i.ProcessHooks(&postCreationHooks, proj, group, node)
-	if err != nil {
-		return err
+	return i.ProcessHooks(&postCreationHooks, proj, group, node)
+}
+
+// Wait the agent of the virtual machines before running the hooks
+// and syncing the files. For the containers it does nothing.
+func (i *LxdCInstance) waitNodeAgent(node *specs.LxdCNode,
+	executor lxd_executor.LxdCExecutor) error {
+
+	if !node.IsVirtualMachine() {
+		return nil
 	}
 
-	return nil
+	i.Logger.InfoC(
+		i.Logger.Aurora.Bold(
+			i.Logger.Aurora.BrightCyan(
+				fmt.Sprintf(">>> [%s] Waiting for the agent of the virtual machine... - :hourglass:",
+					node.GetName()))))
+
+	err := executor.WaitAgentOfInstance(node.GetName(), node.Wait4Agent())
+	if err != nil {
+		i.Logger.Error("Something goes wrong on waiting for the agent: " +
+			err.Error())
+	}
+	return err
 }
 
 func (i *LxdCInstance) ApplyCommand(c *specs.LxdCCommand, proj *specs.LxdCProject, envs []string, varfiles []string) error {
@@ -733,7 +1316,7 @@ func (i *LxdCInstance) ApplyCommand(c *specs.LxdCCommand, proj *specs.LxdCProjec
 
 	env := i.GetEnvByProjectName(proj.GetName())
 	if env == nil {
-		return errors.New(fmt.Sprintf("No environment found for project " + proj.GetName()))
+		return errors.New("No environment found for project " + proj.GetName())
 	}
 
 	envBaseDir, err := filepath.Abs(filepath.Dir(env.File))
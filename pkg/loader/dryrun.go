@@ -0,0 +1,220 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+)
+
+// Executor used by the plan. The read-only calls are done by the
+// wrapped executor, the changes of the instances are recorded in
+// the plan without contacting the server.
+type dryRunExecutor struct {
+	lxd_executor.LxdCExecutor
+
+	planner *planRecorder
+}
+
+func newDryRunExecutor(executor lxd_executor.LxdCExecutor, planner *planRecorder) *dryRunExecutor {
+	return &dryRunExecutor{
+		LxdCExecutor: executor,
+		planner:      planner,
+	}
+}
+
+func (e *dryRunExecutor) IsPresentContainer(name string) (bool, error) {
+	if i, ok := e.planner.getInstance(name); ok {
+		return i.Present, nil
+	}
+	return e.LxdCExecutor.IsPresentContainer(name)
+}
+
+func (e *dryRunExecutor) IsRunningContainer(name string) (bool, error) {
+	if i, ok := e.planner.getInstance(name); ok {
+		return i.Running, nil
+	}
+	return e.LxdCExecutor.IsRunningContainer(name)
+}
+
+func (e *dryRunExecutor) CreateContainer(name, fingerprint, imageServer string, profiles []string) error {
+	return e.CreateInstanceWithConfig(name, fingerprint, imageServer, specs.NodeTypeContainer,
+		profiles, nil, nil)
+}
+
+func (e *dryRunExecutor) CreateContainerWithConfig(name, fingerprint, imageServer string,
+	profiles []string, configMap map[string]string) error {
+	return e.CreateInstanceWithConfig(name, fingerprint, imageServer, specs.NodeTypeContainer,
+		profiles, configMap, nil)
+}
+
+func (e *dryRunExecutor) CreateInstanceWithConfig(name, fingerprint, imageServer, instanceType string,
+	profiles []string, configMap map[string]string, devicesMap map[string]map[string]string) error {
+	e.planner.setInstance(name, true, true)
+	e.planner.addOperation(name, fmt.Sprintf("create %s from image %s (profiles: %s)",
+		instanceType, fingerprint, strings.Join(profiles, ",")))
+	return nil
+}
+
+func (e *dryRunExecutor) StartContainer(name string) error {
+	e.planner.setInstance(name, true, true)
+	e.planner.addOperation(name, "start")
+	return nil
+}
+
+func (e *dryRunExecutor) StopContainer(name string) error {
+	e.planner.setInstance(name, true, false)
+	e.planner.addOperation(name, "stop")
+	return nil
+}
+
+func (e *dryRunExecutor) DeleteContainer(name string) error {
+	e.planner.setInstance(name, false, false)
+	e.planner.addOperation(name, "delete "+name)
+	return nil
+}
+
+func (e *dryRunExecutor) CopyContainerOnInstance(srcName, dstName string) error {
+	e.planner.setInstance(dstName, true, false)
+	e.planner.addOperation(srcName, "copy to "+dstName)
+	return nil
+}
+
+func (e *dryRunExecutor) WaitIpOfContainer(name string, timeout int64) error   { return nil }
+func (e *dryRunExecutor) WaitAgentOfInstance(name string, timeout int64) error { return nil }
+
+// Return the changes of the reconcile of the instance without
+// update it.
+func (e *dryRunExecutor) SyncInstanceConfig(name string, profiles []string,
+	configMap map[string]string, devicesMap map[string]map[string]string) ([]string, error) {
+
+	changes := []string{}
+
+	info, err := e.LxdCExecutor.GetInstanceInfo(name)
+	if err != nil {
+		return changes, err
+	}
+
+	if len(profiles) > 0 &&
+		strings.Join(profiles, ",") != strings.Join(info.Profiles, ",") {
+		changes = append(changes, "profiles")
+	}
+
+	// The reconcile is done on a copy of the config and of the devices
+	// to return the same changes of the apply.
+	config := maps.Clone(info.Config)
+	if config == nil {
+		config = make(map[string]string, 0)
+	}
+	changes = append(changes, specs.ReconcileInstanceConfig(config, configMap)...)
+
+	devices := maps.Clone(info.Devices)
+	if devices == nil {
+		devices = make(map[string]map[string]string, 0)
+	}
+	changes = append(changes,
+		specs.ReconcileInstanceDevices(config, devices, devicesMap)...)
+
+	if len(changes) > 0 {
+		sort.Strings(changes)
+		e.planner.addOperation(name, "update "+strings.Join(changes, ", "))
+	}
+
+	return changes, nil
+}
+
+func (e *dryRunExecutor) RunCommandWithOutput(name, command string, envs map[string]string,
+	outBuffer, errBuffer io.WriteCloser, entrypoint []string,
+	uid, gid *uint32, cwd string) (int, error) {
+	return e.RunCommand(name, command, envs, entrypoint, uid, gid, cwd)
+}
+
+func (e *dryRunExecutor) RunCommand(name, command string, envs map[string]string,
+	entrypoint []string, uid, gid *uint32, cwd string) (int, error) {
+	e.planner.addOperation(name, "run "+command)
+	return 0, nil
+}
+
+func (e *dryRunExecutor) RunCommandWithOutput4Var(name, command, outVar, errVar string,
+	envs *map[string]string, entrypoint []string,
+	uid, gid *uint32, cwd string) (int, error) {
+	return e.RunCommand(name, command, *envs, entrypoint, uid, gid, cwd)
+}
+
+func (e *dryRunExecutor) RunHostCommandWithOutput(command string, envs map[string]string,
+	outBuffer, errBuffer io.WriteCloser, entryPoint []string) (int, error) {
+	return 0, nil
+}
+
+func (e *dryRunExecutor) RunHostCommand(command string, envs map[string]string,
+	entryPoint []string) (int, error) {
+	return 0, nil
+}
+
+func (e *dryRunExecutor) RunHostCommandWithOutput4Var(command, outVar, errVar string,
+	envs *map[string]string, entryPoint []string) (int, error) {
+	return 0, nil
+}
+
+func (e *dryRunExecutor) RecursiveMkdir(name string, dir string, mode *os.FileMode, uid int64, gid int64) error {
+	e.planner.addOperation(name, "mkdir "+dir)
+	return nil
+}
+
+func (e *dryRunExecutor) RecursivePushFile(name, source, target string) error {
+	e.planner.addOperation(name, fmt.Sprintf("push %s => %s", source, target))
+	return nil
+}
+
+func (e *dryRunExecutor) RecursivePushFileWithOpts(name, source, target string, opts *specs.LxdCFileOpts) error {
+	return e.RecursivePushFile(name, source, target)
+}
+
+func (e *dryRunExecutor) PushFileContent(name, target string, content []byte, opts *specs.LxdCFileOpts) error {
+	e.planner.addOperation(name, "push template => "+target)
+	return nil
+}
+
+func (e *dryRunExecutor) RecursivePullFile(name string, destPath string, localPath string, localAsTarget bool) error {
+	e.planner.addOperation(name, fmt.Sprintf("pull %s => %s", destPath, localPath))
+	return nil
+}
+
+func (e *dryRunExecutor) DeleteContainerDir(name, dir string) error {
+	e.planner.addOperation(name, "remove "+dir)
+	return nil
+}
+
+func (e *dryRunExecutor) CreateSnapshot(name, snapshot string, stateful bool) error {
+	e.planner.addOperation(name, "create snapshot "+snapshot)
+	return nil
+}
+
+func (e *dryRunExecutor) RestoreSnapshot(name, snapshot string, stateful bool) error {
+	e.planner.addOperation(name, "restore snapshot "+snapshot)
+	return nil
+}
+
+func (e *dryRunExecutor) DeleteSnapshot(name, snapshot string) error {
+	e.planner.addOperation(name, "delete snapshot "+snapshot)
+	return nil
+}
+
+func (e *dryRunExecutor) AddProfiles2Instance(name string, profiles []string) error {
+	e.planner.addOperation(name, "add profiles "+strings.Join(profiles, ","))
+	return nil
+}
+
+func (e *dryRunExecutor) RemoveProfilesFromInstance(name string, profiles []string) error {
+	e.planner.addOperation(name, "remove profiles "+strings.Join(profiles, ","))
+	return nil
+}
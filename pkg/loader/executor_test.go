@@ -0,0 +1,385 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+)
+
+type fakeInstance struct {
+	Running   bool
+	Config    map[string]string
+	Devices   map[string]map[string]string
+	Snapshots []string
+}
+
+// In memory executor used by the tests of the apply flow. The
+// methods not overridden panic through the nil embedded interface.
+type fakeExecutor struct {
+	lxd_executor.LxdCExecutor
+
+	mutex     sync.Mutex
+	Instances map[string]*fakeInstance
+	Calls     []string
+	Commands  []string
+	Fails     map[string]error
+	FailCmds  map[string]bool
+	// Commands that hang until the command timeout.
+	HangCmds map[string]bool
+	// Command timeout used by every executed command.
+	Timeouts   []time.Duration
+	Address    string
+	entrypoint []string
+	timeout    time.Duration
+}
+
+func newFakeExecutor() *fakeExecutor {
+	return &fakeExecutor{
+		Instances: make(map[string]*fakeInstance, 0),
+		Calls:     []string{},
+		Commands:  []string{},
+		Fails:     make(map[string]error, 0),
+		FailCmds:  make(map[string]bool, 0),
+		HangCmds:  make(map[string]bool, 0),
+		Timeouts:  []time.Duration{},
+		Address:   "127.0.0.1",
+	}
+}
+
+func (f *fakeExecutor) AddInstance(name string, running bool) {
+	f.Instances[name] = &fakeInstance{
+		Running: running,
+		Config:  make(map[string]string, 0),
+		Devices: make(map[string]map[string]string, 0),
+	}
+}
+
+// Record the call of the method. The call fails when the method is
+// available in Fails alone or with the arguments. The caller must
+// hold the mutex.
+func (f *fakeExecutor) call(method, name string) error {
+	c := fmt.Sprintf("%s %s", method, name)
+	f.Calls = append(f.Calls, c)
+	if err, ok := f.Fails[method]; ok {
+		return err
+	}
+	if err, ok := f.Fails[c]; ok {
+		return err
+	}
+	return nil
+}
+
+func (f *fakeExecutor) Setup() error                       { return nil }
+func (f *fakeExecutor) SetP2PMode(m bool)                  {}
+func (f *fakeExecutor) SetEntrypoint(ep []string)          { f.entrypoint = ep }
+func (f *fakeExecutor) GetEntrypoint() []string            { return f.entrypoint }
+func (f *fakeExecutor) GetProfilesList() ([]string, error) { return []string{"default"}, nil }
+
+func (f *fakeExecutor) SetCommandTimeout(t time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.timeout = t
+}
+
+func (f *fakeExecutor) GetCommandTimeout() time.Duration {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.timeout
+}
+
+func (f *fakeExecutor) GetInstanceList() ([]string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	ans := []string{}
+	for name := range f.Instances {
+		ans = append(ans, name)
+	}
+	return ans, nil
+}
+
+func (f *fakeExecutor) IsPresentContainer(name string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	_, ok := f.Instances[name]
+	return ok, nil
+}
+
+func (f *fakeExecutor) IsRunningContainer(name string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	i, ok := f.Instances[name]
+	return ok && i.Running, nil
+}
+
+func (f *fakeExecutor) IsVirtualMachine(name string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeExecutor) StartContainer(name string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	err := f.call("StartContainer", name)
+	if err != nil {
+		return err
+	}
+	if i, ok := f.Instances[name]; ok {
+		i.Running = true
+		return nil
+	}
+	return fmt.Errorf("instance %s not found", name)
+}
+
+func (f *fakeExecutor) StopContainer(name string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	err := f.call("StopContainer", name)
+	if err != nil {
+		return err
+	}
+	if i, ok := f.Instances[name]; ok {
+		i.Running = false
+		return nil
+	}
+	return fmt.Errorf("instance %s not found", name)
+}
+
+func (f *fakeExecutor) DeleteContainer(name string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	err := f.call("DeleteContainer", name)
+	if err != nil {
+		return err
+	}
+	if _, ok := f.Instances[name]; !ok {
+		return fmt.Errorf("instance %s not found", name)
+	}
+	delete(f.Instances, name)
+	return nil
+}
+
+func (f *fakeExecutor) CopyContainerOnInstance(srcName, dstName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	err := f.call("CopyContainerOnInstance", srcName+" "+dstName)
+	if err != nil {
+		return err
+	}
+	src, ok := f.Instances[srcName]
+	if !ok {
+		return fmt.Errorf("instance %s not found", srcName)
+	}
+	if _, ok := f.Instances[dstName]; ok {
+		return fmt.Errorf("instance %s already present", dstName)
+	}
+	f.AddInstance(dstName, false)
+	for k, v := range src.Config {
+		f.Instances[dstName].Config[k] = v
+	}
+	return nil
+}
+
+func (f *fakeExecutor) CreateInstanceWithConfig(name, fingerprint, imageServer, instanceType string,
+	profiles []string, configMap map[string]string, devicesMap map[string]map[string]string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	err := f.call("CreateInstanceWithConfig", name)
+	if err != nil {
+		return err
+	}
+	if _, ok := f.Instances[name]; ok {
+		return fmt.Errorf("instance %s already present", name)
+	}
+	f.AddInstance(name, true)
+	for k, v := range configMap {
+		f.Instances[name].Config[k] = v
+	}
+	for k, v := range devicesMap {
+		f.Instances[name].Devices[k] = v
+	}
+	return nil
+}
+
+func (f *fakeExecutor) RecursivePullFile(name, destPath, localPath string, localAsTarget bool) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.call("RecursivePullFile",
+		fmt.Sprintf("%s %s %s %v", name, destPath, localPath, localAsTarget))
+}
+
+func (f *fakeExecutor) GetContainerIpv4(name string) (string, error) {
+	return f.Address, nil
+}
+
+func (f *fakeExecutor) WaitIpOfContainer(name string, timeout int64) error {
+	return nil
+}
+
+func (f *fakeExecutor) WaitAgentOfInstance(name string, timeout int64) error {
+	return nil
+}
+
+func (f *fakeExecutor) SyncInstanceConfig(name string, profiles []string,
+	configMap map[string]string, devicesMap map[string]map[string]string) ([]string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	err := f.call("SyncInstanceConfig", name)
+	if err != nil {
+		return []string{}, err
+	}
+	i, ok := f.Instances[name]
+	if !ok {
+		return []string{}, fmt.Errorf("instance %s not found", name)
+	}
+	changes := specs.ReconcileInstanceConfig(i.Config, configMap)
+	changes = append(changes,
+		specs.ReconcileInstanceDevices(i.Config, i.Devices, devicesMap)...)
+	return changes, nil
+}
+
+func (f *fakeExecutor) GetInstanceInfo(name string) (*specs.LxdCInstanceInfo, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	i, ok := f.Instances[name]
+	if !ok {
+		return nil, fmt.Errorf("instance %s not found", name)
+	}
+	status := "Stopped"
+	if i.Running {
+		status = "Running"
+	}
+	return &specs.LxdCInstanceInfo{
+		Name:    name,
+		Status:  status,
+		Config:  i.Config,
+		Devices: i.Devices,
+	}, nil
+}
+
+func (f *fakeExecutor) GetSnapshots(name string) ([]*specs.LxdCSnapshot, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	i, ok := f.Instances[name]
+	if !ok {
+		return nil, fmt.Errorf("instance %s not found", name)
+	}
+	ans := []*specs.LxdCSnapshot{}
+	for _, s := range i.Snapshots {
+		ans = append(ans, &specs.LxdCSnapshot{Node: name, Name: s})
+	}
+	return ans, nil
+}
+
+func (f *fakeExecutor) RestoreSnapshot(name, snapshot string, stateful bool) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.call("RestoreSnapshot", name+" "+snapshot)
+}
+
+func (f *fakeExecutor) ExportInstance(name, file string, optimized bool, compression string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.call("ExportInstance", fmt.Sprintf("%s %s %s", name, filepath.Base(file), compression))
+}
+
+func (f *fakeExecutor) ImportInstance(name, file, pool string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	err := f.call("ImportInstance", fmt.Sprintf("%s %s", name, filepath.Base(file)))
+	if err != nil {
+		return err
+	}
+	f.Instances[name] = &fakeInstance{
+		Config:  make(map[string]string, 0),
+		Devices: make(map[string]map[string]string, 0),
+	}
+	return nil
+}
+
+// Record the command executed in the node. The command fails when
+// it's available in FailCmds alone or with the node name. A command
+// available in HangCmds is stopped by the timeout: without timeout
+// it fails to not block the tests.
+func (f *fakeExecutor) runCommand(name, command string) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.Commands = append(f.Commands, fmt.Sprintf("%s %s", name, command))
+	f.Timeouts = append(f.Timeouts, f.timeout)
+	if f.HangCmds[command] || f.HangCmds[fmt.Sprintf("%s %s", name, command)] {
+		if f.timeout == 0 {
+			return 1, fmt.Errorf("command %s hangs without timeout", command)
+		}
+		return 1, fmt.Errorf("command %s stopped after %s", command, f.timeout)
+	}
+	if f.FailCmds[command] || f.FailCmds[fmt.Sprintf("%s %s", name, command)] {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (f *fakeExecutor) RunCommand(name, command string, envs map[string]string,
+	entrypoint []string, uid, gid *uint32, cwd string) (int, error) {
+	return f.runCommand(name, command)
+}
+
+func (f *fakeExecutor) RunCommandWithOutput4Var(name, command, outVar, errVar string,
+	envs *map[string]string, entrypoint []string,
+	uid, gid *uint32, cwd string) (int, error) {
+	if outVar != "" {
+		(*envs)[outVar] = command
+	}
+	return f.runCommand(name, command)
+}
+
+func (f *fakeExecutor) RunHostCommand(command string, envs map[string]string,
+	entryPoint []string) (int, error) {
+	return f.runCommand("host", command)
+}
+
+// Prepare an instance with a single environment and the executor
+// used for every connection.
+func newTestInstance(proj specs.LxdCProject, executor *fakeExecutor) *LxdCInstance {
+	config := specs.NewLxdComposeConfig(nil)
+	config.GetLogging().RuntimeCmdsOutput = false
+	config.GetLogging().Level = "error"
+	config.GetGeneral().StateDir = "."
+
+	dir := GinkgoT().TempDir()
+
+	i := NewLxdCInstance(config)
+	i.executorFactory = func(connType, connection string, ephemeral bool) lxd_executor.LxdCExecutor {
+		return executor
+	}
+	i.AddEnvironment(specs.LxdCEnvironment{
+		File:     filepath.Join(dir, ".lxd-compose.yml"),
+		Projects: []specs.LxdCProject{proj},
+		TemplateEngine: specs.LxdCTemplateEngine{
+			Engine: "mottainai",
+		},
+	})
+
+	return i
+}
@@ -0,0 +1,40 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+)
+
+// Create the executor of the connection. The executor is not
+// initialized: the caller must call Setup() when needed.
+func (i *LxdCInstance) newExecutor(connType, connection string, ephemeral bool) lxd_executor.LxdCExecutor {
+	var executor lxd_executor.LxdCExecutor
+
+	if i.executorFactory != nil {
+		executor = i.executorFactory(connType, connection, ephemeral)
+	} else {
+		executor = lxd_executor.NewLxdCExecutor(connType, connection,
+			i.Config.GetGeneral().LxdConfDir, []string{}, ephemeral,
+			i.Config.GetLogging().CmdsOutput,
+			i.Config.GetLogging().RuntimeCmdsOutput)
+	}
+	executor.SetP2PMode(i.Config.GetGeneral().P2PMode)
+
+	if i.planner != nil {
+		return newDryRunExecutor(executor, i.planner)
+	}
+
+	return executor
+}
+
+// Copy the instance between the servers of the executors.
+func (i *LxdCInstance) copyInstance(source, target lxd_executor.LxdCExecutor,
+	name string, refresh bool) error {
+	if i.instanceCopier != nil {
+		return i.instanceCopier(source, target, name, refresh)
+	}
+	return lxd_executor.CopyInstanceBetweenServers(source, target, name, refresh)
+}
@@ -0,0 +1,229 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+)
+
+const (
+	// Environment variables available to the failure hooks.
+	FailureEnvProject = "LXD_COMPOSE_FAILED_PROJECT"
+	FailureEnvGroup   = "LXD_COMPOSE_FAILED_GROUP"
+	FailureEnvNode    = "LXD_COMPOSE_FAILED_NODE"
+	FailureEnvEvent   = "LXD_COMPOSE_FAILED_EVENT"
+	FailureEnvError   = "LXD_COMPOSE_ERROR"
+
+	// Event used when the failure is not related to an hook.
+	failureEventApply = "apply"
+)
+
+// Error of the apply with the node and the event where the failure
+// happens. It's used to populate the env of the failure hooks.
+type applyError struct {
+	Node  string
+	Event string
+	Err   error
+}
+
+func (e *applyError) Error() string { return e.Err.Error() }
+func (e *applyError) Unwrap() error { return e.Err }
+
+func newApplyError(node, event string, err error) error {
+	var ae *applyError
+	if errors.As(err, &ae) {
+		// Maintain the first failure.
+		return err
+	}
+	return &applyError{Node: node, Event: event, Err: err}
+}
+
+func getFailureEnvs(proj *specs.LxdCProject, group *specs.LxdCGroup,
+	node *specs.LxdCNode, err error) map[string]string {
+
+	ans := map[string]string{
+		FailureEnvProject: proj.Name,
+		FailureEnvGroup:   "",
+		FailureEnvNode:    "",
+		FailureEnvEvent:   failureEventApply,
+		FailureEnvError:   err.Error(),
+	}
+
+	if group != nil {
+		ans[FailureEnvGroup] = group.Name
+	}
+	if node != nil {
+		ans[FailureEnvNode] = node.GetName()
+	}
+
+	var ae *applyError
+	if errors.As(err, &ae) {
+		if ae.Node != "" {
+			ans[FailureEnvNode] = ae.Node
+		}
+		if ae.Event != "" {
+			ans[FailureEnvEvent] = ae.Event
+		}
+	}
+
+	return ans
+}
+
+// Run the hooks of the failure event. The errors of the failure hooks
+// are only logged and the original error is returned to the caller.
+func (i *LxdCInstance) processFailureHooks(event string,
+	proj *specs.LxdCProject, group *specs.LxdCGroup,
+	node *specs.LxdCNode, err error) error {
+
+	// The nodes cancelled for the failure of another node
+	// are not failed.
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	var hooks []specs.LxdCHook
+	switch event {
+	case specs.HookOnNodeFailure:
+		hooks = i.GetNodeHooks4Event(event, proj, group, node)
+		hooks = i.getFailureHooks4Node(hooks, proj, group, node)
+	case specs.HookOnGroupFailure:
+		hooks = proj.GetHooks4Nodes(event, []string{"*", "host"})
+		hooks = append(hooks, group.GetHooks4Nodes(event, []string{"*", "host"})...)
+		hooks = i.getFailureHooks4PresentNodes(hooks, proj, []*specs.LxdCGroup{group})
+	default:
+		groups := []*specs.LxdCGroup{}
+		for idx := range proj.Groups {
+			if proj.Groups[idx].ToProcess(i.GroupsEnabled, i.GroupsDisabled) {
+				groups = append(groups, &proj.Groups[idx])
+			}
+		}
+		hooks = proj.GetHooks4Nodes(event, []string{"*", "host"})
+		hooks = i.getFailureHooks4PresentNodes(hooks, proj, groups)
+	}
+
+	if len(hooks) == 0 {
+		return err
+	}
+
+	envs := getFailureEnvs(proj, group, node, err)
+
+	i.Logger.Debug(fmt.Sprintf(
+		"[%s] Running %d %s hooks for the failure of %s/%s... ",
+		proj.Name, len(hooks), event, envs[FailureEnvGroup], envs[FailureEnvNode]))
+
+	herr := i.processHooks(&hooks, proj, group, node, envs)
+	if herr != nil {
+		i.Logger.Warning(fmt.Sprintf("[%s] Error on execute %s hooks: %s",
+			proj.Name, event, herr.Error()))
+	}
+
+	return err
+}
+
+// Return the failure hooks of the node. When the node isn't present,
+// for example for a failure of the creation, only the hooks of the
+// host are executed.
+func (i *LxdCInstance) getFailureHooks4Node(hooks []specs.LxdCHook,
+	proj *specs.LxdCProject, group *specs.LxdCGroup,
+	node *specs.LxdCNode) []specs.LxdCHook {
+
+	if len(hooks) == 0 {
+		return hooks
+	}
+
+	isPresent := false
+	executor, err := i.newGroupExecutor(group)
+	if err == nil {
+		isPresent, err = executor.IsPresentContainer(node.GetName())
+	}
+	if err != nil {
+		i.Logger.Warning(fmt.Sprintf(
+			"[%s - %s] Error on check if the node %s is present: %s",
+			proj.Name, group.Name, node.GetName(), err.Error()))
+	}
+	if isPresent {
+		return hooks
+	}
+
+	ans := []specs.LxdCHook{}
+	for _, h := range hooks {
+		if h.Node == "host" {
+			ans = append(ans, h)
+		} else {
+			i.Logger.Debug(fmt.Sprintf(
+				"[%s] Node %s not present. Skipped %s hook.",
+				proj.Name, node.GetName(), h.Event))
+		}
+	}
+
+	return ans
+}
+
+// Return the failure hooks of the group or of the project with the
+// hooks for all nodes expanded only for the nodes present. The nodes
+// not yet created when the failure happens are skipped.
+func (i *LxdCInstance) getFailureHooks4PresentNodes(hooks []specs.LxdCHook,
+	proj *specs.LxdCProject, groups []*specs.LxdCGroup) []specs.LxdCHook {
+
+	ans := []specs.LxdCHook{}
+	if len(hooks) == 0 {
+		return ans
+	}
+
+	present := []string{}
+	mpresent := make(map[string]bool, 0)
+	for _, grp := range groups {
+		executor, err := i.newGroupExecutor(grp)
+		if err != nil {
+			i.Logger.Warning(fmt.Sprintf(
+				"[%s - %s] Error on setup executor for the failure hooks: %s",
+				proj.Name, grp.Name, err.Error()))
+			continue
+		}
+
+		for idx := range grp.Nodes {
+			name := grp.Nodes[idx].GetName()
+			isPresent, err := executor.IsPresentContainer(name)
+			if err != nil {
+				i.Logger.Warning(fmt.Sprintf(
+					"[%s - %s] Error on check if the node %s is present: %s",
+					proj.Name, grp.Name, name, err.Error()))
+				continue
+			}
+			if isPresent {
+				present = append(present, name)
+				mpresent[name] = true
+			}
+		}
+	}
+
+	for _, h := range hooks {
+		switch h.Node {
+		case "host":
+			ans = append(ans, h)
+		case "", "*":
+			for _, name := range present {
+				nh := h
+				nh.Node = name
+				ans = append(ans, nh)
+			}
+		default:
+			if mpresent[h.Node] ||
+				(i.NodesPrefix != "" && mpresent[fmt.Sprintf("%s-%s", i.NodesPrefix, h.Node)]) {
+				ans = append(ans, h)
+			} else {
+				i.Logger.Debug(fmt.Sprintf(
+					"[%s] Node %s not present. Skipped %s hook.",
+					proj.Name, h.Node, h.Event))
+			}
+		}
+	}
+
+	return ans
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"context"
+	"errors"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+	template "github.com/MottainaiCI/lxd-compose/pkg/template"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Failure hooks", func() {
+
+	var executor *fakeExecutor
+	var instance *LxdCInstance
+	var proj *specs.LxdCProject
+
+	setup := func(projHooks, groupHooks []specs.LxdCHook) {
+		executor = newFakeExecutor()
+		executor.AddInstance("node1", true)
+		executor.AddInstance("node3", true)
+		instance = newTestInstance(specs.LxdCProject{
+			Name:  "proj1",
+			Hooks: projHooks,
+			Groups: []specs.LxdCGroup{
+				{
+					Name:  "group1",
+					Hooks: groupHooks,
+					Nodes: []specs.LxdCNode{
+						{Name: "node1"},
+						{Name: "node2"},
+					},
+				},
+				{
+					Name: "group2",
+					Nodes: []specs.LxdCNode{
+						{Name: "node3"},
+						{Name: "node4"},
+					},
+				},
+			},
+		}, executor)
+		proj = &instance.Environments[0].Projects[0]
+	}
+
+	It("Run the group hooks only on the nodes present", func() {
+		setup(nil, []specs.LxdCHook{
+			{Event: specs.HookOnGroupFailure, Node: "*", Commands: []string{"cleanup"}},
+			{Event: specs.HookOnGroupFailure, Node: "node2", Commands: []string{"dump"}},
+		})
+
+		applyErr := errors.New("apply error")
+		err := instance.processFailureHooks(specs.HookOnGroupFailure,
+			proj, &proj.Groups[0], nil, applyErr)
+		Expect(err).To(Equal(applyErr))
+		Expect(executor.Commands).To(Equal([]string{"node1 cleanup"}))
+	})
+
+	It("Run only the host hooks when the node creation fails", func() {
+		setup(nil, []specs.LxdCHook{
+			{Event: specs.HookOnNodeFailure, Node: "*", Commands: []string{"dump"}},
+			{Event: specs.HookOnNodeFailure, Node: "host", Commands: []string{"notify"}},
+		})
+		executor.Fails["CreateInstanceWithConfig"] = errors.New("create error")
+
+		env := &instance.Environments[0]
+		compiler, err := template.NewProjectTemplateCompiler(env, proj)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = instance.applyNode(context.Background(), &proj.Groups[0].Nodes[1],
+			&proj.Groups[0], proj, compiler, executor, []string{"default"},
+			GinkgoT().TempDir())
+		Expect(err).To(MatchError(ContainSubstring("create error")))
+		Expect(executor.Commands).To(Equal([]string{"host notify"}))
+	})
+
+	It("Run the project hooks on the nodes present of all groups", func() {
+		setup([]specs.LxdCHook{
+			{Event: specs.HookOnProjectFailure, Commands: []string{"cleanup"}},
+		}, nil)
+
+		applyErr := errors.New("apply error")
+		err := instance.processFailureHooks(specs.HookOnProjectFailure,
+			proj, nil, nil, applyErr)
+		Expect(err).To(Equal(applyErr))
+		Expect(executor.Commands).To(Equal([]string{"node1 cleanup", "node3 cleanup"}))
+	})
+
+	It("Run the project host hooks without group", func() {
+		setup([]specs.LxdCHook{
+			{Event: specs.HookOnProjectFailure, Node: "host", Commands: []string{"notify"}},
+		}, nil)
+
+		applyErr := errors.New("apply error")
+		err := instance.processFailureHooks(specs.HookOnProjectFailure,
+			proj, nil, nil, applyErr)
+		Expect(err).To(Equal(applyErr))
+		Expect(executor.Commands).To(Equal([]string{"host notify"}))
+	})
+})
@@ -0,0 +1,108 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+	"github.com/MottainaiCI/lxd-compose/pkg/template"
+)
+
+// Copy the fetch resources of the node on the host. The relative
+// destinations are based on the directory of the environment file.
+func (i *LxdCInstance) fetchResources(proj *specs.LxdCProject,
+	node *specs.LxdCNode, compiler template.LxdCTemplateCompiler,
+	executor lxd_executor.LxdCExecutor, envBaseAbs string) error {
+
+	targets, err := i.getFetchTargets(node, compiler)
+	if err != nil {
+		return err
+	}
+
+	nResources := len(node.FetchResources)
+	i.Logger.InfoC(
+		i.Logger.Aurora.Bold(
+			i.Logger.Aurora.BrightCyan(
+				fmt.Sprintf(">>> [%s] Fetching %d resources... - :truck:",
+					node.GetName(), nResources))))
+
+	for idx, resource := range node.FetchResources {
+		target := targets[idx]
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(envBaseAbs, target)
+		}
+
+		// With a destination that ends with / the resource
+		// is stored inside the directory.
+		localAsTarget := !strings.HasSuffix(targets[idx], "/")
+		dir := target
+		if localAsTarget {
+			dir = filepath.Dir(target)
+		}
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return fmt.Errorf("error on create directory %s: %s", dir, err.Error())
+		}
+
+		i.Logger.DebugC(
+			i.Logger.Aurora.Italic(
+				i.Logger.Aurora.BrightCyan(
+					fmt.Sprintf(">>> [%s] %s => %s",
+						node.GetName(), resource.Source, target))))
+
+		err = executor.RecursivePullFile(node.GetName(),
+			resource.Source, target, localAsTarget)
+		if err != nil {
+			i.Logger.Error("Error on fetch " + resource.Source + ": " + err.Error())
+			return err
+		}
+
+		i.Logger.InfoC(
+			i.Logger.Aurora.BrightCyan(
+				fmt.Sprintf(">>> [%s] - [%2d/%2d] %s - :check_mark:",
+					node.GetName(), idx+1, nResources, target)))
+	}
+
+	return nil
+}
+
+// Render the destinations of the fetch resources with the vars
+// of the project and of the node.
+func (i *LxdCInstance) getFetchTargets(node *specs.LxdCNode,
+	compiler template.LxdCTemplateCompiler) ([]string, error) {
+
+	ans := []string{}
+
+	// The compiler is shared between the nodes of the group.
+	i.varsMutex.Lock()
+	defer i.varsMutex.Unlock()
+
+	compiler.InitVars()
+	(*compiler.GetVars())["node"] = *node
+	for k, v := range node.Labels {
+		(*compiler.GetVars())[k] = v
+	}
+
+	for _, resource := range node.FetchResources {
+		if !strings.Contains(resource.Destination, "{{") {
+			ans = append(ans, resource.Destination)
+			continue
+		}
+
+		dst, err := compiler.CompileRaw(resource.Destination)
+		if err != nil {
+			return ans, fmt.Errorf("error on render destination %s: %s",
+				resource.Destination, err.Error())
+		}
+		ans = append(ans, strings.TrimSpace(dst))
+	}
+
+	return ans, nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"path/filepath"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+	template "github.com/MottainaiCI/lxd-compose/pkg/template"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fetch", func() {
+
+	var executor *fakeExecutor
+	var instance *LxdCInstance
+	var proj *specs.LxdCProject
+	var node *specs.LxdCNode
+	var compiler template.LxdCTemplateCompiler
+
+	BeforeEach(func() {
+		executor = newFakeExecutor()
+		executor.AddInstance("node1", true)
+		instance = newTestInstance(specs.LxdCProject{
+			Name: "proj1",
+			Environments: []specs.LxdCEnvVars{
+				{
+					EnvVars: map[string]interface{}{"version": "1.2"},
+				},
+			},
+			Groups: []specs.LxdCGroup{
+				{
+					Name: "group1",
+					Nodes: []specs.LxdCNode{
+						{
+							Name:   "node1",
+							Labels: map[string]string{"role": "db"},
+							FetchResources: []specs.LxdCFetchResource{
+								{Source: "/var/log/app", Destination: "logs/{{ .node.Name }}/"},
+								{Source: "/tmp/dump.sql", Destination: "dumps/{{ .role }}-{{ .version }}.sql"},
+								{Source: "/etc/app.conf", Destination: "/var/tmp/lxdc/app.conf"},
+							},
+						},
+					},
+				},
+			},
+		}, executor)
+
+		env := &instance.Environments[0]
+		proj = &env.Projects[0]
+		node = &proj.Groups[0].Nodes[0]
+
+		var err error
+		compiler, err = template.NewProjectTemplateCompiler(env, proj)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Render the destinations", func() {
+		targets, err := instance.getFetchTargets(node, compiler)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(targets).To(Equal([]string{
+			"logs/node1/",
+			"dumps/db-1.2.sql",
+			"/var/tmp/lxdc/app.conf",
+		}))
+	})
+
+	It("Fails with an invalid destination", func() {
+		node.FetchResources = []specs.LxdCFetchResource{
+			{Source: "/tmp/dump.sql", Destination: "dumps/{{ .role "},
+		}
+
+		_, err := instance.getFetchTargets(node, compiler)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Pull the resources relative to the environment directory", func() {
+		envBaseAbs := GinkgoT().TempDir()
+		node.FetchResources = node.FetchResources[:2]
+
+		err := instance.fetchResources(proj, node, compiler, executor, envBaseAbs)
+		Expect(err).ToNot(HaveOccurred())
+
+		// The destination with the final / is the directory where
+		// the resource is stored.
+		Expect(executor.Calls).To(Equal([]string{
+			"RecursivePullFile node1 /var/log/app " +
+				filepath.Join(envBaseAbs, "logs", "node1") + " false",
+			"RecursivePullFile node1 /tmp/dump.sql " +
+				filepath.Join(envBaseAbs, "dumps", "db-1.2.sql") + " true",
+		}))
+		Expect(filepath.Join(envBaseAbs, "logs", "node1")).To(BeADirectory())
+		Expect(filepath.Join(envBaseAbs, "dumps")).To(BeADirectory())
+		Expect(filepath.Join(envBaseAbs, "dumps", "db-1.2.sql")).ToNot(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,135 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+	"github.com/MottainaiCI/lxd-compose/pkg/template"
+)
+
+// Execute a typed action of an hook on the target node.
+func (i *LxdCInstance) runHookAction(hc hookCommand, proj *specs.LxdCProject) error {
+	node := hc.Node
+	action := hc.Action
+
+	env, _, grp, nodeEntity := i.GetEntitiesByNodeName(node)
+	if nodeEntity == nil && i.NodesPrefix != "" {
+		node = fmt.Sprintf("%s-%s", i.NodesPrefix, node)
+		env, _, grp, nodeEntity = i.GetEntitiesByNodeName(node)
+	}
+	if nodeEntity == nil {
+		return fmt.Errorf("node %s not found for the action %s", hc.Node, action.Type)
+	}
+
+	executor, err := i.newGroupExecutor(grp)
+	if err != nil {
+		return err
+	}
+	if len(nodeEntity.Entrypoint) > 0 {
+		executor.SetEntrypoint(nodeEntity.Entrypoint)
+	}
+
+	envBaseAbs, err := filepath.Abs(filepath.Dir(env.File))
+	if err != nil {
+		return err
+	}
+
+	i.Logger.InfoC(
+		i.Logger.Aurora.Bold(
+			i.Logger.Aurora.BrightCyan(
+				fmt.Sprintf(">>> [%s] - %s - :gear:", node, action.String()))))
+
+	switch action.Type {
+	case specs.HookActionPush:
+		return executor.RecursivePushFile(node,
+			getActionLocalPath(envBaseAbs, action.Source), action.Destination)
+
+	case specs.HookActionPull:
+		return executor.RecursivePullFile(node, action.Source,
+			getActionLocalPath(envBaseAbs, action.Destination), true)
+
+	case specs.HookActionTemplate:
+		return i.runTemplateAction(env, proj, nodeEntity, executor,
+			getActionLocalPath(envBaseAbs, action.Source), action.Destination)
+
+	case specs.HookActionWait:
+		n := *nodeEntity
+		if action.Readiness != nil {
+			n.Readiness = action.Readiness
+		}
+		if n.Readiness == nil || len(n.Readiness.Probes) == 0 {
+			return fmt.Errorf("no readiness probes available for node %s", node)
+		}
+		return i.waitNodeReadiness(proj, grp, &n, executor)
+
+	case specs.HookActionRestart:
+		if grp.Ephemeral {
+			return fmt.Errorf("restart of the ephemeral node %s not admitted", node)
+		}
+		err = executor.StopContainer(node)
+		if err != nil {
+			return err
+		}
+		err = executor.StartContainer(node)
+		if err != nil {
+			return err
+		}
+		return i.waitNodeAgent(nodeEntity, executor)
+	}
+
+	return fmt.Errorf("invalid action type %s", action.Type)
+}
+
+func getActionLocalPath(envBaseAbs, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(envBaseAbs, p)
+}
+
+// Compile the template with the vars of the project and the node
+// and push the result to the node.
+func (i *LxdCInstance) runTemplateAction(env *specs.LxdCEnvironment,
+	proj *specs.LxdCProject, node *specs.LxdCNode,
+	executor lxd_executor.LxdCExecutor, source, target string) error {
+
+	compiler, err := template.NewProjectTemplateCompiler(env, proj)
+	if err != nil {
+		return err
+	}
+
+	i.varsMutex.Lock()
+	// Reload the vars updated by out2var/err2var hooks.
+	compiler.InitVars()
+	i.varsMutex.Unlock()
+
+	(*compiler.GetVars())["node"] = *node
+	for k, v := range node.Labels {
+		(*compiler.GetVars())[k] = v
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+
+	content, err := compiler.CompileRaw(string(data))
+	if err != nil {
+		return fmt.Errorf("error on compile template %s: %s", source, err.Error())
+	}
+
+	if strings.HasSuffix(target, "/") {
+		target = path.Join(target, filepath.Base(source))
+	}
+
+	return executor.PushFileContent(node.GetName(), target, []byte(content), nil)
+}
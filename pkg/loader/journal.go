@@ -0,0 +1,138 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"fmt"
+	"path/filepath"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+)
+
+// Return the path of the journal of the project. A relative state
+// directory is resolved from the directory of the environment file
+// to use the same journal from every working directory.
+func (i *LxdCInstance) getJournalFile(projectName string) (string, error) {
+	stateDir := i.Config.GetGeneral().StateDir
+
+	if !filepath.IsAbs(stateDir) {
+		env := i.GetEnvByProjectName(projectName)
+		if env == nil {
+			return "", fmt.Errorf("No environment found for project %s", projectName)
+		}
+
+		envBaseAbs, err := filepath.Abs(filepath.Dir(env.File))
+		if err != nil {
+			return "", err
+		}
+		stateDir = filepath.Join(envBaseAbs, stateDir)
+	}
+
+	return filepath.Join(stateDir,
+		fmt.Sprintf("%s.journal.json", projectName)), nil
+}
+
+// Initialize the journal of the apply of the project. On resume
+// the steps of the previous execution are loaded. On plan the
+// journal is only read.
+func (i *LxdCInstance) initJournal(projectName string) error {
+	var err error
+
+	file, err := i.getJournalFile(projectName)
+	if err != nil {
+		return err
+	}
+
+	if i.Resume || i.RestartFrom != "" {
+		i.journal, err = specs.LoadLxdCJournal(projectName, file)
+		if err != nil {
+			return err
+		}
+
+		if i.RestartFrom != "" {
+			node, step, err := specs.ParseJournalRestartFrom(i.RestartFrom)
+			if err != nil {
+				return err
+			}
+
+			if !i.journal.RestartFrom(node, step) {
+				i.Logger.Warning(fmt.Sprintf(
+					"[%s] Step %s not found in the journal.",
+					projectName, i.RestartFrom))
+			}
+		}
+
+		i.Logger.Debug(fmt.Sprintf("[%s] Resuming apply with %d steps completed.",
+			projectName, len(i.journal.Steps)))
+	} else if i.isDryRun() {
+		return nil
+	} else {
+		i.journal = specs.NewLxdCJournal(projectName, file)
+	}
+
+	if i.isDryRun() {
+		return nil
+	}
+
+	return i.journal.Write()
+}
+
+// Remove the journal of the apply completed.
+func (i *LxdCInstance) removeJournal() error {
+	if i.journal == nil || i.isDryRun() {
+		return nil
+	}
+	return i.journal.Remove()
+}
+
+func (i *LxdCInstance) isStepDone(group, node, step string) bool {
+	if i.journal == nil {
+		return false
+	}
+	return i.journal.IsDone(group, node, step)
+}
+
+func (i *LxdCInstance) setStepDone(group, node, step string) error {
+	if i.journal == nil || i.isDryRun() {
+		return nil
+	}
+	return i.journal.AddStep(group, node, step)
+}
+
+// Drop all the steps of the node from the journal.
+func (i *LxdCInstance) resetNodeSteps(node string) {
+	if i.journal == nil || i.isDryRun() {
+		return
+	}
+	if i.journal.RestartFrom(node, "") {
+		err := i.journal.Write()
+		if err != nil {
+			i.Logger.Warning("Error on write the journal: " + err.Error())
+		}
+	}
+}
+
+func (i *LxdCInstance) setJournalVar(name, node string, value interface{}) error {
+	if i.journal == nil || i.isDryRun() {
+		return nil
+	}
+	return i.journal.AddVar(name, node, value)
+}
+
+// Restore the vars of the hooks skipped on resume.
+func (i *LxdCInstance) loadJournalVars(proj *specs.LxdCProject) {
+	if i.journal == nil {
+		return
+	}
+
+	for _, v := range i.journal.Vars {
+		proj.SetHookVar(v.Name, v.Value, v.Node)
+	}
+
+	if len(i.journal.Vars) > 0 {
+		i.Logger.Debug(fmt.Sprintf("[%s] Restored %d vars of the hooks from the journal.",
+			proj.Name, len(i.journal.Vars)))
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Journal", func() {
+
+	var executor *fakeExecutor
+	var instance *LxdCInstance
+
+	BeforeEach(func() {
+		executor = newFakeExecutor()
+		instance = newTestInstance(specs.LxdCProject{
+			Name: "proj1",
+			Groups: []specs.LxdCGroup{
+				{
+					Name: "group1",
+					Nodes: []specs.LxdCNode{
+						{
+							Name:        "node1",
+							ImageSource: "alpine/3.20",
+							Hooks: []specs.LxdCHook{
+								{
+									Event:    specs.HookPreNodeCreation,
+									Node:     "host",
+									Commands: []string{"prepare"},
+								},
+								{
+									Event:    specs.HookPostNodeCreation,
+									Commands: []string{"setup"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}, executor)
+	})
+
+	It("Run the pending post-node-creation hooks on resume", func() {
+		executor.FailCmds["setup"] = true
+
+		err := instance.ApplyProject("proj1")
+		Expect(err).To(HaveOccurred())
+		Expect(executor.Instances).To(HaveKey("node1"))
+		Expect(executor.Commands).To(Equal([]string{"host prepare", "node1 setup"}))
+
+		delete(executor.FailCmds, "setup")
+		instance.SetResumeMode(true)
+		err = instance.ApplyProject("proj1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(executor.Commands).To(Equal([]string{
+			"host prepare", "node1 setup", "node1 setup",
+		}))
+		Expect(executor.Calls).To(ConsistOf(
+			"CreateInstanceWithConfig node1",
+			"SyncInstanceConfig node1",
+		))
+	})
+})
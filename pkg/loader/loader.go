@@ -27,7 +27,9 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sync"
 
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
 	helpers "github.com/MottainaiCI/lxd-compose/pkg/helpers"
 	helpers_render "github.com/MottainaiCI/lxd-compose/pkg/helpers/render"
 	helpers_sec "github.com/MottainaiCI/lxd-compose/pkg/helpers/security"
@@ -40,6 +42,7 @@ type LxdCInstance struct {
 	Logger         *log.LxdCLogger
 	Environments   []specs.LxdCEnvironment
 	SkipSync       bool
+	SkipReconcile  bool
 	FlagsDisabled  []string
 	FlagsEnabled   []string
 	GroupsEnabled  []string
@@ -48,6 +51,24 @@ type LxdCInstance struct {
 
 	Upgrade bool
 	Ask     bool
+
+	// Resume the last apply failed using the journal.
+	Resume      bool
+	RestartFrom string
+	journal     *specs.LxdCJournal
+
+	// Used to create the executors instead of the LXD/Incus
+	// executors when defined.
+	executorFactory func(connType, connection string, ephemeral bool) lxd_executor.LxdCExecutor
+	// Used by the plan to run the apply in dry-run mode.
+	planner *planRecorder
+	// Used to copy the instances between the executors instead of
+	// the LXD/Incus copy when defined.
+	instanceCopier func(source, target lxd_executor.LxdCExecutor, name string, refresh bool) error
+
+	// Used to serialize the access to the project variables and
+	// to the template compiler on parallel processing.
+	varsMutex sync.Mutex
 }
 
 func NewLxdCInstance(config *specs.LxdComposeConfig) *LxdCInstance {
@@ -82,10 +103,16 @@ func (i *LxdCInstance) SetNodesPrefix(s string)     { i.NodesPrefix = s }
 func (i *LxdCInstance) GetNodesPrefix() string      { return i.NodesPrefix }
 func (i *LxdCInstance) SetSkipSync(v bool)          { i.SkipSync = v }
 func (i *LxdCInstance) GetSkipSync() bool           { return i.SkipSync }
+func (i *LxdCInstance) SetSkipReconcile(v bool)     { i.SkipReconcile = v }
+func (i *LxdCInstance) GetSkipReconcile() bool      { return i.SkipReconcile }
 func (i *LxdCInstance) SetUpgradeMode(v bool)       { i.Upgrade = v }
 func (i *LxdCInstance) GetUpgradeMode() bool        { return i.Upgrade }
 func (i *LxdCInstance) SetAskMode(v bool)           { i.Ask = v }
 func (i *LxdCInstance) GetAskMode() bool            { return i.Ask }
+func (i *LxdCInstance) SetResumeMode(v bool)        { i.Resume = v }
+func (i *LxdCInstance) GetResumeMode() bool         { return i.Resume }
+func (i *LxdCInstance) SetRestartFrom(s string)     { i.RestartFrom = s }
+func (i *LxdCInstance) GetRestartFrom() string      { return i.RestartFrom }
 func (i *LxdCInstance) GetGroupsEnabled() []string  { return i.GroupsEnabled }
 func (i *LxdCInstance) GetGroupsDisabled() []string { return i.GroupsDisabled }
 func (i *LxdCInstance) SetGroupsEnabled(groups []string) {
@@ -205,6 +232,46 @@ func (i *LxdCInstance) Validate(ignoreError bool) error {
 	dupCommands := 0
 	wrongHooks := 0
 
+	checkHookOpts := func(h *specs.LxdCHook, owner string) error {
+		if err := h.Validate(); err != nil {
+			wrongHooks++
+			if !ignoreError {
+				return fmt.Errorf("%s: %s", owner, err.Error())
+			}
+			i.Logger.Warning(fmt.Sprintf("Found invalid hook on %s: %s", owner, err.Error()))
+		}
+		return nil
+	}
+
+	checkTemplate := func(ct *specs.LxdCConfigTemplate, owner string, isNode bool,
+		engine string) error {
+		err := ct.Validate(isNode)
+		if err == nil && ct.Push && engine == "jinja2" {
+			// The jinja2 engine compiles the templates through files
+			// of the host.
+			err = errors.New("push is not supported with the jinja2 engine")
+		}
+		if err != nil {
+			if !ignoreError {
+				return fmt.Errorf("%s: template %s: %s", owner, ct.Source, err.Error())
+			}
+			i.Logger.Warning(fmt.Sprintf("Found invalid template %s on %s: %s",
+				ct.Source, owner, err.Error()))
+		}
+		return nil
+	}
+
+	checkFileOpts := func(opts *specs.LxdCFileOpts, resource, owner string) error {
+		if err := opts.Validate(); err != nil {
+			if !ignoreError {
+				return fmt.Errorf("%s: resource %s: %s", owner, resource, err.Error())
+			}
+			i.Logger.Warning(fmt.Sprintf("Found invalid options on %s resource %s: %s",
+				owner, resource, err.Error()))
+		}
+		return nil
+	}
+
 	// Check for duplicated project name
 	for _, env := range i.Environments {
 
@@ -259,6 +326,10 @@ func (i *LxdCInstance) Validate(ignoreError bool) error {
 
 			// Check project's hooks events
 			for _, h := range proj.Hooks {
+				if err := checkHookOpts(&h, "project "+proj.Name); err != nil {
+					return err
+				}
+
 				if (h.Event == specs.HookPreProject || h.Event == specs.HookPreGroup) && h.Node != "host" {
 					i.Logger.Warning("On project " + proj.Name + " is present an hook " +
 						h.Event + " for node " + h.Node + ". Only node host is admitted.")
@@ -274,6 +345,23 @@ func (i *LxdCInstance) Validate(ignoreError bool) error {
 
 			}
 
+			for _, ct := range proj.ConfigTemplates {
+				if err := checkTemplate(&ct, "project "+proj.Name, false,
+					env.TemplateEngine.Engine); err != nil {
+					return err
+				}
+			}
+
+			// Check groups dependencies
+			err := proj.ValidateDependencies()
+			if err != nil {
+				if !ignoreError {
+					return err
+				}
+
+				i.Logger.Warning("Invalid groups dependencies: " + err.Error())
+			}
+
 			// Check groups
 			for _, grp := range proj.Groups {
 
@@ -293,12 +381,18 @@ func (i *LxdCInstance) Validate(ignoreError bool) error {
 				// Check group's hooks events
 				if len(grp.Hooks) > 0 {
 					for _, h := range grp.Hooks {
+						if err := checkHookOpts(&h, "group "+grp.Name); err != nil {
+							return err
+						}
+
 						if h.Event != specs.HookPreNodeCreation &&
 							h.Event != specs.HookPostNodeCreation &&
 							h.Event != specs.HookPreNodeSync &&
 							h.Event != specs.HookPostNodeSync &&
 							h.Event != specs.HookPreGroup &&
-							h.Event != specs.HookPostGroup {
+							h.Event != specs.HookPostGroup &&
+							h.Event != specs.HookOnNodeFailure &&
+							h.Event != specs.HookOnGroupFailure {
 
 							wrongHooks++
 
@@ -313,8 +407,90 @@ func (i *LxdCInstance) Validate(ignoreError bool) error {
 					}
 				}
 
+				for _, ct := range grp.ConfigTemplates {
+					if err := checkTemplate(&ct, "group "+grp.Name, false,
+						env.TemplateEngine.Engine); err != nil {
+						return err
+					}
+				}
+
+				// Check nodes dependencies
+				_, err = grp.GetNodesLevels()
+				if err != nil {
+					if !ignoreError {
+						return err
+					}
+
+					i.Logger.Warning("Invalid nodes dependencies: " + err.Error())
+				}
+
+				err = specs.ValidateDevices(grp.Devices)
+				if err != nil {
+					if !ignoreError {
+						return errors.New("Invalid devices on group " +
+							grp.Name + ": " + err.Error())
+					}
+
+					i.Logger.Warning("Invalid devices on group " +
+						grp.Name + ": " + err.Error())
+				}
+
+				if grp.RollingUpgrade != nil {
+					err = grp.RollingUpgrade.Validate()
+					if err != nil {
+						if !ignoreError {
+							return errors.New("Invalid rolling upgrade on group " +
+								grp.Name + ": " + err.Error())
+						}
+
+						i.Logger.Warning("Invalid rolling upgrade on group " +
+							grp.Name + ": " + err.Error())
+					}
+				}
+
+				if !specs.IsValidUpgradeStrategy(grp.UpgradeStrategy) {
+					if !ignoreError {
+						return errors.New("Invalid upgrade strategy " +
+							grp.UpgradeStrategy + " on group " + grp.Name)
+					}
+
+					i.Logger.Warning("Invalid upgrade strategy " +
+						grp.UpgradeStrategy + " on group " + grp.Name)
+				}
+
 				for _, node := range grp.Nodes {
 
+					if !specs.IsValidNodeType(node.Type) {
+						if !ignoreError {
+							return errors.New("Invalid type " +
+								node.Type + " on node " + node.GetName())
+						}
+
+						i.Logger.Warning("Invalid type " +
+							node.Type + " on node " + node.GetName())
+					}
+
+					err = specs.ValidateDevices(node.Devices)
+					if err != nil {
+						if !ignoreError {
+							return errors.New("Invalid devices on node " +
+								node.GetName() + ": " + err.Error())
+						}
+
+						i.Logger.Warning("Invalid devices on node " +
+							node.GetName() + ": " + err.Error())
+					}
+
+					if !specs.IsValidUpgradeStrategy(node.UpgradeStrategy) {
+						if !ignoreError {
+							return errors.New("Invalid upgrade strategy " +
+								node.UpgradeStrategy + " on node " + node.GetName())
+						}
+
+						i.Logger.Warning("Invalid upgrade strategy " +
+							node.UpgradeStrategy + " on node " + node.GetName())
+					}
+
 					if _, isPresent := mnodes[node.GetName()]; isPresent {
 						if !ignoreError {
 							return errors.New("Duplicated node " + node.GetName())
@@ -328,8 +504,36 @@ func (i *LxdCInstance) Validate(ignoreError bool) error {
 						mnodes[node.GetName()] = 1
 					}
 
+					for _, ct := range node.ConfigTemplates {
+						if err := checkTemplate(&ct, "node "+node.GetName(), true,
+							env.TemplateEngine.Engine); err != nil {
+							return err
+						}
+					}
+
+					for _, r := range node.SyncResources {
+						if err := checkFileOpts(&r.LxdCFileOpts, r.Source, "node "+node.GetName()); err != nil {
+							return err
+						}
+					}
+
+					for _, r := range node.FetchResources {
+						if err := r.Validate(); err != nil {
+							if !ignoreError {
+								return fmt.Errorf("node %s: fetch resource %s: %s",
+									node.GetName(), r.Source, err.Error())
+							}
+							i.Logger.Warning(fmt.Sprintf("Found invalid fetch resource %s on node %s: %s",
+								r.Source, node.GetName(), err.Error()))
+						}
+					}
+
 					if len(node.Hooks) > 0 {
 						for _, h := range node.Hooks {
+							if err := checkHookOpts(&h, "node "+node.GetName()); err != nil {
+								return err
+							}
+
 							if h.Node != "" && h.Node != "host" {
 								i.Logger.Warning("Invalid hook on node " + node.GetName() + " with node field valorized.")
 								wrongHooks++
@@ -341,7 +545,8 @@ func (i *LxdCInstance) Validate(ignoreError bool) error {
 							if h.Event != specs.HookPreNodeCreation &&
 								h.Event != specs.HookPostNodeCreation &&
 								h.Event != specs.HookPreNodeSync &&
-								h.Event != specs.HookPostNodeSync {
+								h.Event != specs.HookPostNodeSync &&
+								h.Event != specs.HookOnNodeFailure {
 
 								wrongHooks++
 
@@ -792,6 +997,17 @@ func (i *LxdCInstance) loadExtraFiles(env *specs.LxdCEnvironment, secrets *map[s
 	}
 
 	err = i.loadIncludeHooks(env, secrets)
+	if err != nil {
+		return err
+	}
+
+	// Expand the nodes with replicas or matrix.
+	for idx := range env.Projects {
+		err = env.Projects[idx].ExpandNodes()
+		if err != nil {
+			return err
+		}
+	}
 
 	// Set paths of the certificates
 	if len(env.Certificates) > 0 {
@@ -0,0 +1,68 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate", func() {
+
+	var instance *LxdCInstance
+	var node *specs.LxdCNode
+
+	BeforeEach(func() {
+		instance = newTestInstance(specs.LxdCProject{
+			Name: "proj1",
+			Groups: []specs.LxdCGroup{
+				{
+					Name: "group1",
+					Nodes: []specs.LxdCNode{
+						{
+							Name: "node1",
+							ConfigTemplates: []specs.LxdCConfigTemplate{
+								{Source: "app.tmpl", Destination: "/etc/app.conf", Push: true},
+							},
+						},
+					},
+				},
+			},
+		}, newFakeExecutor())
+		node = &instance.Environments[0].Projects[0].Groups[0].Nodes[0]
+	})
+
+	It("Accept the pushed templates", func() {
+		Expect(instance.Validate(false)).To(Succeed())
+	})
+
+	It("Reject the pushed templates with the jinja2 engine", func() {
+		instance.Environments[0].TemplateEngine.Engine = "jinja2"
+		Expect(instance.Validate(false)).ToNot(Succeed())
+
+		node.ConfigTemplates[0].Push = false
+		node.ConfigTemplates[0].Destination = "app.conf"
+		Expect(instance.Validate(false)).To(Succeed())
+	})
+
+	It("Reject the fetch resources without source or destination", func() {
+		node.FetchResources = []specs.LxdCFetchResource{
+			{Source: "/var/log/app", Destination: "logs/"},
+		}
+		Expect(instance.Validate(false)).To(Succeed())
+
+		node.FetchResources[0].Destination = ""
+		Expect(instance.Validate(false)).ToNot(Succeed())
+
+		node.FetchResources[0].Destination = "logs/"
+		node.FetchResources[0].Source = "var/log/app"
+		Expect(instance.Validate(false)).ToNot(Succeed())
+
+		node.FetchResources[0].Source = ""
+		Expect(instance.Validate(false)).ToNot(Succeed())
+	})
+})
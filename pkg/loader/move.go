@@ -0,0 +1,216 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"errors"
+	"fmt"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+)
+
+type LxdCNodeMoveOpts struct {
+	ToGroup  string
+	ToRemote string
+	// Copy the running instance and stop it only for the final
+	// refresh of the copy.
+	Cutover bool
+	// Maintain the instance on the source server.
+	KeepSource bool
+}
+
+// Copy the instance of the node on the connection of another group
+// or on another remote. It returns the placement of the node to
+// record in the environment.
+func (i *LxdCInstance) MoveNode(nodeName string, opts *LxdCNodeMoveOpts) (*specs.LxdCNodePlacement, error) {
+	if (opts.ToGroup == "") == (opts.ToRemote == "") {
+		return nil, errors.New("one of the target group or the target remote is needed")
+	}
+
+	_, proj, grp, node := i.GetEntitiesByNodeName(nodeName)
+	if node == nil && i.NodesPrefix != "" {
+		_, proj, grp, node = i.GetEntitiesByNodeName(
+			fmt.Sprintf("%s-%s", i.NodesPrefix, nodeName))
+	}
+	if node == nil {
+		return nil, fmt.Errorf("node %s not found", nodeName)
+	}
+
+	// The placement reports the node as declared. The prefix of the
+	// command line is used only for the name of the instance.
+	declared := *node
+	instance := *node
+	if instance.NamePrefix == "" {
+		instance.NamePrefix = i.NodesPrefix
+	}
+
+	var target specs.LxdCGroup
+	if opts.ToGroup != "" {
+		tgrp := proj.GetGroupByName(opts.ToGroup)
+		if tgrp == nil {
+			return nil, fmt.Errorf("group %s not found", opts.ToGroup)
+		}
+		if tgrp.Name == grp.Name {
+			return nil, fmt.Errorf("node %s is already in the group %s",
+				node.GetName(), grp.Name)
+		}
+		target = *tgrp
+	} else {
+		target = *grp
+		target.Connection = opts.ToRemote
+	}
+
+	placement := &specs.LxdCNodePlacement{
+		FromGroup:      grp.Name,
+		Group:          target.Name,
+		Connection:     target.Connection,
+		ConnectionType: target.ConnectionType,
+		Nodes:          []specs.LxdCNode{declared},
+	}
+
+	if target.Connection == grp.Connection &&
+		target.ConnectionType == grp.ConnectionType {
+		i.Logger.Info(fmt.Sprintf(
+			"[%s] The target uses the same connection. No copy needed.",
+			instance.GetName()))
+		return placement, nil
+	}
+
+	source, err := i.newGroupExecutor(grp)
+	if err != nil {
+		return nil, fmt.Errorf("Error on initialize executor for group %s: %s",
+			grp.Name, err.Error())
+	}
+
+	dest, err := i.newGroupExecutor(&target)
+	if err != nil {
+		return nil, fmt.Errorf("Error on initialize executor for connection %s: %s",
+			target.Connection, err.Error())
+	}
+
+	err = i.moveInstance(instance.GetName(), source, dest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return placement, nil
+}
+
+func (i *LxdCInstance) moveInstance(name string,
+	source, dest lxd_executor.LxdCExecutor, opts *LxdCNodeMoveOpts) error {
+
+	present, err := source.IsPresentContainer(name)
+	if err != nil {
+		return err
+	}
+	if !present {
+		return fmt.Errorf("node %s is not present on the source", name)
+	}
+
+	present, err = dest.IsPresentContainer(name)
+	if err != nil {
+		return err
+	}
+	if present {
+		return fmt.Errorf("node %s is already present on the target", name)
+	}
+
+	info, err := source.GetInstanceInfo(name)
+	if err != nil {
+		return err
+	}
+
+	// The profiles of the instance must be available on the target.
+	targetProfiles, err := dest.GetProfilesList()
+	if err != nil {
+		return err
+	}
+	err = i.validateProfiles(targetProfiles, info.Profiles)
+	if err != nil {
+		return err
+	}
+
+	running := info.Status == "Running"
+
+	if running && opts.Cutover {
+		i.Logger.InfoC(
+			i.Logger.Aurora.Bold(
+				i.Logger.Aurora.BrightCyan(
+					fmt.Sprintf(">>> [%s] Copying the running instance... - :truck:", name))))
+
+		err = i.copyInstance(source, dest, name, false)
+		if err != nil {
+			return i.rollbackMove(name, source, dest, false, err)
+		}
+	}
+
+	if running {
+		err = source.StopContainer(name)
+		if err != nil {
+			return i.rollbackMove(name, source, dest, false, err)
+		}
+	}
+
+	i.Logger.InfoC(
+		i.Logger.Aurora.Bold(
+			i.Logger.Aurora.BrightCyan(
+				fmt.Sprintf(">>> [%s] Copying the stopped instance... - :truck:", name))))
+
+	err = i.copyInstance(source, dest, name, running && opts.Cutover)
+	if err != nil {
+		return i.rollbackMove(name, source, dest, running, err)
+	}
+
+	if running {
+		err = dest.StartContainer(name)
+		if err != nil {
+			return i.rollbackMove(name, source, dest, running, err)
+		}
+	}
+
+	if !opts.KeepSource {
+		err = source.DeleteContainer(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	i.Logger.InfoC(
+		i.Logger.Aurora.Bold(
+			i.Logger.Aurora.BrightCyan(
+				fmt.Sprintf(">>> [%s] Instance moved - :check_mark:", name))))
+
+	return nil
+}
+
+// Remove the partial copy of the instance from the target and
+// restart the source instance when it was stopped by the move.
+func (i *LxdCInstance) rollbackMove(name string,
+	source, dest lxd_executor.LxdCExecutor, restart bool, moveErr error) error {
+
+	present, err := dest.IsPresentContainer(name)
+	if err != nil {
+		i.Logger.Warning(fmt.Sprintf("[%s] Error on check the copy on the target: %s",
+			name, err.Error()))
+	} else if present {
+		err = dest.DeleteContainer(name)
+		if err != nil {
+			i.Logger.Warning(fmt.Sprintf("[%s] Error on remove the copy from the target: %s",
+				name, err.Error()))
+		}
+	}
+
+	if restart {
+		err = source.StartContainer(name)
+		if err != nil {
+			return fmt.Errorf("move of the node %s failed (%s) and restart of the source failed: %s",
+				name, moveErr.Error(), err.Error())
+		}
+	}
+
+	return fmt.Errorf("move of the node %s failed and rollback done: %s",
+		name, moveErr.Error())
+}
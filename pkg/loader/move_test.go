@@ -0,0 +1,84 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"errors"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Move node", func() {
+
+	var source, dest *fakeExecutor
+	var instance *LxdCInstance
+	var copyErr error
+
+	BeforeEach(func() {
+		copyErr = nil
+		source = newFakeExecutor()
+		dest = newFakeExecutor()
+		source.AddInstance("prod-node1", true)
+
+		instance = newTestInstance(specs.LxdCProject{
+			Name: "proj1",
+			Groups: []specs.LxdCGroup{
+				{
+					Name:       "group1",
+					Connection: "local",
+					Nodes:      []specs.LxdCNode{{Name: "node1"}},
+				},
+				{
+					Name:       "group2",
+					Connection: "remote",
+				},
+			},
+		}, source)
+		instance.SetNodesPrefix("prod")
+		instance.executorFactory = func(connType, connection string, ephemeral bool) lxd_executor.LxdCExecutor {
+			if connection == "remote" {
+				return dest
+			}
+			return source
+		}
+		// The copy creates the instance on the target before the error.
+		instance.instanceCopier = func(s, t lxd_executor.LxdCExecutor, name string, refresh bool) error {
+			if !refresh {
+				dest.AddInstance(name, false)
+			}
+			return copyErr
+		}
+	})
+
+	It("Move the instance and report the node as declared", func() {
+		placement, err := instance.MoveNode("node1", &LxdCNodeMoveOpts{ToGroup: "group2"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(placement.Nodes[0].NamePrefix).To(Equal(""))
+		Expect(source.Instances).ToNot(HaveKey("prod-node1"))
+		Expect(dest.Instances["prod-node1"].Running).To(BeTrue())
+	})
+
+	It("Restart the source and remove the copy when the copy fails", func() {
+		copyErr = errors.New("copy error")
+
+		_, err := instance.MoveNode("node1", &LxdCNodeMoveOpts{ToGroup: "group2"})
+		Expect(err).To(MatchError(ContainSubstring("rollback done")))
+		Expect(source.Instances["prod-node1"].Running).To(BeTrue())
+		Expect(dest.Instances).ToNot(HaveKey("prod-node1"))
+	})
+
+	It("Restart the source and remove the copy when the start fails", func() {
+		dest.Fails["StartContainer"] = errors.New("start error")
+
+		_, err := instance.MoveNode("node1", &LxdCNodeMoveOpts{ToGroup: "group2"})
+		Expect(err).To(MatchError(ContainSubstring("rollback done")))
+		Expect(source.Instances["prod-node1"].Running).To(BeTrue())
+		Expect(dest.Instances).ToNot(HaveKey("prod-node1"))
+	})
+})
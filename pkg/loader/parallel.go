@@ -0,0 +1,98 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	helpers "github.com/MottainaiCI/lxd-compose/pkg/helpers"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// Run the function f for every entry of names with at most maxParallel
+// workers. The first failure cancels the context used by the other
+// workers and the entries not yet started are skipped.
+// The first error received is returned.
+func (i *LxdCInstance) runParallel(names []string, maxParallel int,
+	f func(ctx context.Context, idx int) error) error {
+
+	var ans error = nil
+
+	for idx, err := range i.runWorkers(names, maxParallel, true, f) {
+		if err == nil {
+			continue
+		}
+
+		if errors.Is(err, context.Canceled) {
+			i.Logger.Debug(fmt.Sprintf("[%s] Processing cancelled.", names[idx]))
+			continue
+		}
+
+		i.Logger.Error(fmt.Sprintf("[%s] Failed: %s", names[idx], err.Error()))
+		if ans == nil {
+			ans = err
+		}
+	}
+
+	return ans
+}
+
+// Run the function f for every entry of names with at most maxParallel
+// workers and return the error of every entry. With failFast the first
+// failure cancels the processing of the other entries.
+func (i *LxdCInstance) runWorkers(names []string, maxParallel int,
+	failFast bool, f func(ctx context.Context, idx int) error) []error {
+
+	waitGroup := &sync.WaitGroup{}
+	sem := semaphore.NewWeighted(int64(maxParallel))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ch chan helpers.ChannelError = make(
+		chan helpers.ChannelError,
+		len(names),
+	)
+
+	for idx := range names {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+
+			err := sem.Acquire(ctx, 1)
+			if err != nil {
+				ch <- helpers.ChannelError{Error: err, Closure: idx}
+				return
+			}
+			defer sem.Release(1)
+
+			if ctx.Err() != nil {
+				ch <- helpers.ChannelError{Error: ctx.Err(), Closure: idx}
+				return
+			}
+
+			err = f(ctx, idx)
+			if err != nil && failFast && ctx.Err() == nil {
+				// Stop the processing of the other entries.
+				cancel()
+			}
+
+			ch <- helpers.ChannelError{Error: err, Closure: idx}
+		}()
+	}
+
+	waitGroup.Wait()
+	close(ch)
+
+	ans := make([]error, len(names))
+	for resp := range ch {
+		ans[resp.Closure.(int)] = resp.Error
+	}
+
+	return ans
+}
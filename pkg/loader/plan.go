@@ -0,0 +1,233 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"strings"
+	"sync"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+)
+
+// The state of the instances changed by the plan. It's used to
+// answer to the checks of the apply after a simulated change.
+type planInstance struct {
+	Present bool
+	Running bool
+}
+
+// Collect the steps of the apply executed in dry-run mode.
+type planRecorder struct {
+	mutex     sync.Mutex
+	plan      *specs.LxdCProjectPlan
+	groups    map[string]*specs.LxdCGroupPlan
+	nodes     map[string]*specs.LxdCNodePlan
+	instances map[string]*planInstance
+}
+
+func newPlanRecorder(projectName string) *planRecorder {
+	return &planRecorder{
+		plan: &specs.LxdCProjectPlan{
+			Name:   projectName,
+			Groups: []*specs.LxdCGroupPlan{},
+		},
+		groups:    make(map[string]*specs.LxdCGroupPlan, 0),
+		nodes:     make(map[string]*specs.LxdCNodePlan, 0),
+		instances: make(map[string]*planInstance, 0),
+	}
+}
+
+// Return the execution plan of the apply of the project without
+// modify the instances. The apply is executed in dry-run mode: only
+// read-only calls are done to the LXD/Incus servers and the hooks,
+// the changes of the instances and the syncs are recorded.
+func (i *LxdCInstance) PlanProject(projectName string) (*specs.LxdCProjectPlan, error) {
+	i.planner = newPlanRecorder(projectName)
+	ask := i.Ask
+	i.Ask = false
+	defer func() {
+		i.planner = nil
+		i.Ask = ask
+	}()
+
+	err := i.ApplyProject(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.planner.plan, nil
+}
+
+func (i *LxdCInstance) isDryRun() bool {
+	return i.planner != nil
+}
+
+// Register the group with the nodes in the order of the dependencies.
+func (i *LxdCInstance) planGroup(group *specs.LxdCGroup) {
+	if i.planner == nil {
+		return
+	}
+
+	i.planner.mutex.Lock()
+	defer i.planner.mutex.Unlock()
+
+	gplan := &specs.LxdCGroupPlan{
+		Name:       group.Name,
+		Connection: group.Connection,
+		Nodes:      []*specs.LxdCNodePlan{},
+	}
+
+	nodes := []*specs.LxdCNode{}
+	levels, err := group.GetNodesLevels()
+	if err == nil {
+		for _, level := range levels {
+			nodes = append(nodes, level...)
+		}
+	} else {
+		// The error is returned by the apply of the group.
+		for idx := range group.Nodes {
+			nodes = append(nodes, &group.Nodes[idx])
+		}
+	}
+
+	for _, node := range nodes {
+		nplan := &specs.LxdCNodePlan{
+			Name:   node.GetName(),
+			Action: specs.PlanActionNone,
+			Hooks:  []specs.LxdCPlanHook{},
+		}
+		gplan.Nodes = append(gplan.Nodes, nplan)
+		i.planner.nodes[nplan.Name] = nplan
+	}
+
+	i.planner.groups[group.Name] = gplan
+	i.planner.plan.Groups = append(i.planner.plan.Groups, gplan)
+}
+
+// Register the templates compiled by the project and by the group.
+func (i *LxdCInstance) planTemplates(proj *specs.LxdCProject, group *specs.LxdCGroup) {
+	if i.planner == nil {
+		return
+	}
+
+	i.planner.mutex.Lock()
+	defer i.planner.mutex.Unlock()
+
+	if group == nil {
+		i.planner.plan.ConfigTemplates = proj.ConfigTemplates
+	} else if gplan, ok := i.planner.groups[group.Name]; ok {
+		gplan.ConfigTemplates = group.ConfigTemplates
+	}
+}
+
+// Return the plan of the node. The caller must hold the mutex.
+// The instances used for the upgrade rollback are reported with
+// the node.
+func (p *planRecorder) getNode(name string) *specs.LxdCNodePlan {
+	if nplan, ok := p.nodes[name]; ok {
+		return nplan
+	}
+	return p.nodes[strings.TrimSuffix(name, upgradeBackupSuffix)]
+}
+
+// Update the plan of the node with the input function.
+func (i *LxdCInstance) planNode(node *specs.LxdCNode, f func(nplan *specs.LxdCNodePlan)) {
+	if i.planner == nil {
+		return
+	}
+
+	i.planner.mutex.Lock()
+	defer i.planner.mutex.Unlock()
+
+	if nplan := i.planner.getNode(node.GetName()); nplan != nil {
+		f(nplan)
+	}
+}
+
+func (i *LxdCInstance) planNodeAction(node *specs.LxdCNode, action string) {
+	i.planNode(node, func(nplan *specs.LxdCNodePlan) {
+		// The update is reported only when no other action is done.
+		if action != specs.PlanActionUpdate || nplan.Action == specs.PlanActionNone {
+			nplan.Action = action
+		}
+	})
+}
+
+func (i *LxdCInstance) planNodeState(node *specs.LxdCNode, present, running bool) {
+	i.planNode(node, func(nplan *specs.LxdCNodePlan) {
+		nplan.Present = present
+		nplan.Running = running
+	})
+}
+
+// Register an operation done on the instance.
+func (p *planRecorder) addOperation(name, op string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if nplan := p.getNode(name); nplan != nil {
+		nplan.Operations = append(nplan.Operations, op)
+	}
+}
+
+// Return the state of the instance changed by the plan.
+func (p *planRecorder) getInstance(name string) (*planInstance, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	ans, ok := p.instances[name]
+	return ans, ok
+}
+
+func (p *planRecorder) setInstance(name string, present, running bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.instances[name] = &planInstance{
+		Present: present,
+		Running: running,
+	}
+}
+
+// Register the command of the hook in the plan of the project, of
+// the group or of the node where the hook is processed.
+func (i *LxdCInstance) planHook(hc hookCommand, group *specs.LxdCGroup,
+	targetNode *specs.LxdCNode) {
+
+	i.planner.mutex.Lock()
+	defer i.planner.mutex.Unlock()
+
+	h := specs.LxdCPlanHook{
+		Event:   hc.Hook.Event,
+		Node:    hc.Node,
+		Command: hc.Command,
+		Flags:   hc.Hook.Flags,
+		Out2Var: hc.Hook.Out2Var,
+		Err2Var: hc.Hook.Err2Var,
+	}
+
+	pre := hc.Hook.Event == specs.HookPreProject || hc.Hook.Event == specs.HookPreGroup
+
+	switch {
+	case targetNode != nil:
+		if nplan := i.planner.getNode(targetNode.GetName()); nplan != nil {
+			nplan.Hooks = append(nplan.Hooks, h)
+		}
+	case group != nil:
+		gplan, ok := i.planner.groups[group.Name]
+		if !ok {
+			return
+		}
+		if pre {
+			gplan.PreHooks = append(gplan.PreHooks, h)
+		} else {
+			gplan.PostHooks = append(gplan.PostHooks, h)
+		}
+	case pre:
+		i.planner.plan.PreHooks = append(i.planner.plan.PreHooks, h)
+	default:
+		i.planner.plan.PostHooks = append(i.planner.plan.PostHooks, h)
+	}
+}
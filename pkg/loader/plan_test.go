@@ -0,0 +1,187 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Plan", func() {
+
+	var executor *fakeExecutor
+	var instance *LxdCInstance
+
+	BeforeEach(func() {
+		executor = newFakeExecutor()
+		executor.AddInstance("node2", false)
+		executor.AddInstance("node3", true)
+		executor.Instances["node3"].Config["user.role"] = "db"
+		executor.Instances["node3"].Config[specs.InstanceManagedConfigKey] = "user.role"
+
+		instance = newTestInstance(specs.LxdCProject{
+			Name: "proj1",
+			Hooks: []specs.LxdCHook{
+				{Event: specs.HookPreProject, Node: "host", Commands: []string{"echo pre"}},
+			},
+			Groups: []specs.LxdCGroup{
+				{
+					Name: "group1",
+					Hooks: []specs.LxdCHook{
+						{
+							Event:    specs.HookPostNodeSync,
+							Commands: []string{"systemctl restart app"},
+							When:     `node.labels.role == "web"`,
+						},
+					},
+					Nodes: []specs.LxdCNode{
+						{Name: "node1", ImageSource: "alpine/3.20"},
+						{Name: "node2"},
+						{
+							Name:   "node3",
+							Labels: map[string]string{"role": "web"},
+							SyncResources: []specs.LxdCSyncResource{
+								{Source: "files/", Destination: "/etc/app/"},
+							},
+						},
+					},
+				},
+			},
+		}, executor)
+	})
+
+	It("Record the apply without changes to the instances", func() {
+		plan, err := instance.PlanProject("proj1")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(plan.PreHooks).To(Equal([]specs.LxdCPlanHook{
+			{Event: specs.HookPreProject, Node: "host", Command: "echo pre"},
+		}))
+		Expect(plan.Groups).To(HaveLen(1))
+
+		nodes := plan.Groups[0].Nodes
+		Expect(nodes).To(HaveLen(3))
+
+		Expect(nodes[0].Action).To(Equal(specs.PlanActionCreate))
+		Expect(nodes[0].Operations).To(Equal([]string{
+			"create container from image alpine/3.20 (profiles: )",
+		}))
+		Expect(nodes[1].Action).To(Equal(specs.PlanActionStart))
+		Expect(nodes[1].Operations).To(Equal([]string{"start"}))
+
+		// The when of the hook is evaluated for every node.
+		Expect(nodes[2].Action).To(Equal(specs.PlanActionUpdate))
+		Expect(nodes[2].Operations).To(Equal([]string{"update user.role"}))
+		Expect(nodes[2].Hooks).To(Equal([]specs.LxdCPlanHook{
+			{Event: specs.HookPostNodeSync, Node: "node3", Command: "systemctl restart app"},
+		}))
+		Expect(nodes[2].SyncResources).To(HaveLen(1))
+		Expect(nodes[0].Hooks).To(BeEmpty())
+
+		Expect(plan.GetActionsSummary()).To(Equal(map[string]int{
+			specs.PlanActionCreate:  1,
+			specs.PlanActionUpgrade: 0,
+			specs.PlanActionStart:   1,
+			specs.PlanActionUpdate:  1,
+			specs.PlanActionNone:    0,
+		}))
+
+		Expect(executor.Calls).To(BeEmpty())
+		Expect(executor.Commands).To(BeEmpty())
+		Expect(executor.Instances).ToNot(HaveKey("node1"))
+		Expect(executor.Instances["node2"].Running).To(BeFalse())
+		Expect(executor.Instances["node3"].Config["user.role"]).To(Equal("db"))
+
+		journal, err := instance.getJournalFile("proj1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(journal).ToNot(BeAnExistingFile())
+		Expect(instance.isDryRun()).To(BeFalse())
+	})
+
+	It("Report the same config changes of the apply", func() {
+		executor.Instances["node3"].Config["user.role"] = "web"
+		executor.Instances["node3"].Config["user.user-data"] = "#cloud-config"
+
+		plan, err := instance.PlanProject("proj1")
+		Expect(err).ToNot(HaveOccurred())
+
+		// The user keys not set by lxd-compose are not removed.
+		node := plan.Groups[0].Nodes[2]
+		Expect(node.Action).To(Equal(specs.PlanActionNone))
+		Expect(node.Operations).To(BeEmpty())
+		Expect(executor.Instances["node3"].Config).To(HaveLen(3))
+	})
+
+	It("Report only the changes of the devices managed", func() {
+		executor.Instances["node3"].Config["user.role"] = "web"
+		executor.Instances["node3"].Devices["debug"] = map[string]string{
+			"type": "disk", "path": "/debug", "source": "/srv/debug",
+		}
+
+		plan, err := instance.PlanProject("proj1")
+		Expect(err).ToNot(HaveOccurred())
+
+		// The devices not set by lxd-compose are not removed.
+		Expect(plan.Groups[0].Nodes[2].Operations).To(BeEmpty())
+
+		node := &instance.Environments[0].Projects[0].Groups[0].Nodes[2]
+		node.Devices = map[string]map[string]string{
+			"data": {"type": "disk", "path": "/data", "source": "/srv/data"},
+		}
+		plan, err = instance.PlanProject("proj1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plan.Groups[0].Nodes[2].Operations).To(Equal([]string{
+			"update devices.data, " + specs.InstanceManagedDevicesKey,
+		}))
+		Expect(executor.Instances["node3"].Devices).To(HaveLen(1))
+		Expect(executor.Instances["node3"].Config).ToNot(HaveKey(specs.InstanceManagedDevicesKey))
+	})
+
+	It("Skip the steps already done on resume", func() {
+		file, err := instance.getJournalFile("proj1")
+		Expect(err).ToNot(HaveOccurred())
+		journal := specs.NewLxdCJournal("proj1", file)
+		Expect(journal.AddStep("", "", specs.HookPreProject)).To(Succeed())
+		Expect(journal.AddStep("group1", "node3", specs.JournalStepSync)).To(Succeed())
+
+		instance.SetResumeMode(true)
+		plan, err := instance.PlanProject("proj1")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(plan.PreHooks).To(BeEmpty())
+		Expect(plan.Groups[0].Nodes[2].SyncResources).To(BeEmpty())
+
+		// The journal isn't updated by the plan.
+		loaded, err := specs.LoadLxdCJournal("proj1", file)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(loaded.Steps).To(HaveLen(2))
+	})
+
+	It("Report the upgrade with the snapshot strategy", func() {
+		group := &instance.Environments[0].Projects[0].Groups[0]
+		group.UpgradeStrategy = specs.UpgradeStrategySnapshot
+		group.Nodes = group.Nodes[2:]
+		group.Nodes[0].ImageSource = "alpine/3.21"
+
+		instance.SetUpgradeMode(true)
+		plan, err := instance.PlanProject("proj1")
+		Expect(err).ToNot(HaveOccurred())
+
+		node := plan.Groups[0].Nodes[0]
+		Expect(node.Action).To(Equal(specs.PlanActionUpgrade))
+		Expect(node.Present).To(BeTrue())
+		Expect(node.Running).To(BeTrue())
+		Expect(node.Operations).To(Equal([]string{
+			"copy to node3-lxdc-upgrade",
+			"delete node3",
+			"create container from image alpine/3.21 (profiles: )",
+			"delete node3-lxdc-upgrade",
+		}))
+		Expect(executor.Calls).To(BeEmpty())
+		Expect(executor.Instances).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,172 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+)
+
+// Run all the readiness probes of the node one time and
+// return the status of every probe.
+func (i *LxdCInstance) CheckNodeReadiness(proj *specs.LxdCProject,
+	node *specs.LxdCNode,
+	executor lxd_executor.LxdCExecutor) ([]specs.LxdCProbeStatus, bool) {
+
+	ans := []specs.LxdCProbeStatus{}
+	ready := true
+
+	if node.Readiness == nil {
+		return ans, ready
+	}
+
+	timeout := time.Duration(node.Readiness.GetTimeout()) * time.Second
+	address := ""
+
+	for idx := range node.Readiness.Probes {
+		probe := &node.Readiness.Probes[idx]
+		status := specs.LxdCProbeStatus{
+			Node:  node.GetName(),
+			Probe: probe.String(),
+		}
+
+		var err error
+		if probe.GetType() != specs.ProbeCommand && address == "" {
+			address, err = executor.GetContainerIpv4(node.GetName())
+			if err == nil && address == "" {
+				err = errors.New("node without IP address")
+			}
+		}
+
+		if err == nil {
+			switch probe.GetType() {
+			case specs.ProbeCommand:
+				err = i.runCommandProbe(proj, node, probe, executor, timeout)
+			case specs.ProbeHttp:
+				err = i.runHttpProbe(probe, address, timeout)
+			default:
+				err = i.runTcpProbe(probe, address, timeout)
+			}
+		}
+
+		if err != nil {
+			status.Message = err.Error()
+			ready = false
+		} else {
+			status.Ready = true
+		}
+
+		ans = append(ans, status)
+	}
+
+	return ans, ready
+}
+
+// Wait until all the readiness probes of the node are ready or
+// the max number of retries is reached.
+func (i *LxdCInstance) waitNodeReadiness(proj *specs.LxdCProject,
+	group *specs.LxdCGroup, node *specs.LxdCNode,
+	executor lxd_executor.LxdCExecutor) error {
+
+	retries := node.Readiness.GetRetries()
+	interval := time.Duration(node.Readiness.GetInterval()) * time.Second
+
+	i.Logger.InfoC(
+		i.Logger.Aurora.Bold(
+			i.Logger.Aurora.BrightCyan(
+				fmt.Sprintf(">>> [%s] Waiting for readiness of %d probes... - :hourglass:",
+					node.GetName(), len(node.Readiness.Probes)))))
+
+	for attempt := 1; attempt <= retries; attempt++ {
+		status, ready := i.CheckNodeReadiness(proj, node, executor)
+		if ready {
+			i.Logger.InfoC(
+				i.Logger.Aurora.BrightCyan(
+					fmt.Sprintf(">>> [%s] Node ready. - :check_mark:", node.GetName())))
+			return nil
+		}
+
+		for _, s := range status {
+			if !s.Ready {
+				i.Logger.Debug(fmt.Sprintf("[%s - %s] Attempt %d/%d: probe %s not ready: %s",
+					proj.Name, group.Name, attempt, retries, s.Probe, s.Message))
+			}
+		}
+
+		if attempt < retries {
+			time.Sleep(interval)
+		}
+	}
+
+	return fmt.Errorf("node %s not ready after %d checks", node.GetName(), retries)
+}
+
+func (i *LxdCInstance) runCommandProbe(proj *specs.LxdCProject,
+	node *specs.LxdCNode, probe *specs.LxdCProbe,
+	executor lxd_executor.LxdCExecutor, timeout time.Duration) error {
+
+	i.varsMutex.Lock()
+	envs, err := proj.GetEnvsMap()
+	i.varsMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if _, ok := envs["HOME"]; !ok {
+		envs["HOME"] = "/"
+	}
+
+	// The timeout of the probe is applied by the executor that stops
+	// the command. The timeout used by the hooks is restored after the
+	// probe.
+	prevTimeout := executor.GetCommandTimeout()
+	executor.SetCommandTimeout(timeout)
+	defer executor.SetCommandTimeout(prevTimeout)
+
+	res, err := executor.RunCommand(node.GetName(), probe.Command, envs,
+		node.Entrypoint, nil, nil, "")
+	if err != nil {
+		return err
+	}
+	if res != 0 {
+		return fmt.Errorf("command exit with %d", res)
+	}
+
+	return nil
+}
+
+func (i *LxdCInstance) runTcpProbe(probe *specs.LxdCProbe,
+	address string, timeout time.Duration) error {
+
+	conn, err := net.DialTimeout("tcp",
+		net.JoinHostPort(address, strconv.Itoa(probe.TcpPort)), timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (i *LxdCInstance) runHttpProbe(probe *specs.LxdCProbe,
+	address string, timeout time.Duration) error {
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(probe.GetUrl(address))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"time"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Readiness", func() {
+
+	var executor *fakeExecutor
+	var instance *LxdCInstance
+	var proj *specs.LxdCProject
+	var node *specs.LxdCNode
+
+	setup := func(probes []specs.LxdCProbe) {
+		executor = newFakeExecutor()
+		executor.AddInstance("node1", true)
+		instance = newTestInstance(specs.LxdCProject{
+			Name: "proj1",
+			Groups: []specs.LxdCGroup{
+				{
+					Name: "group1",
+					Nodes: []specs.LxdCNode{
+						{
+							Name: "node1",
+							Readiness: &specs.LxdCReadiness{
+								Timeout: 3,
+								Probes:  probes,
+							},
+						},
+					},
+				},
+			},
+		}, executor)
+		proj = &instance.Environments[0].Projects[0]
+		node = &proj.Groups[0].Nodes[0]
+	}
+
+	Context("Command probe", func() {
+
+		It("Run the command with the timeout of the probe", func() {
+			setup([]specs.LxdCProbe{{Command: "pgrep nginx"}})
+			executor.SetCommandTimeout(10 * time.Second)
+
+			status, ready := instance.CheckNodeReadiness(proj, node, executor)
+			Expect(ready).To(BeTrue())
+			Expect(status).To(HaveLen(1))
+			Expect(status[0].Probe).To(Equal("command: pgrep nginx"))
+			Expect(executor.Commands).To(Equal([]string{"node1 pgrep nginx"}))
+			Expect(executor.Timeouts).To(Equal([]time.Duration{3 * time.Second}))
+			// The timeout of the executor is restored.
+			Expect(executor.GetCommandTimeout()).To(Equal(10 * time.Second))
+		})
+
+		It("Fails with an exit code not zero", func() {
+			setup([]specs.LxdCProbe{{Command: "pgrep nginx"}})
+			executor.FailCmds["pgrep nginx"] = true
+
+			status, ready := instance.CheckNodeReadiness(proj, node, executor)
+			Expect(ready).To(BeFalse())
+			Expect(status[0].Ready).To(BeFalse())
+			Expect(status[0].Message).To(Equal("command exit with 1"))
+		})
+	})
+
+	Context("TCP probe", func() {
+
+		It("Connect to the port of the node", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			defer listener.Close()
+
+			port := listener.Addr().(*net.TCPAddr).Port
+			setup([]specs.LxdCProbe{{TcpPort: port}})
+
+			status, ready := instance.CheckNodeReadiness(proj, node, executor)
+			Expect(ready).To(BeTrue())
+			Expect(status[0].Probe).To(Equal("tcp: " + strconv.Itoa(port)))
+
+			listener.Close()
+			_, ready = instance.CheckNodeReadiness(proj, node, executor)
+			Expect(ready).To(BeFalse())
+		})
+
+		It("Fails without the IP address of the node", func() {
+			setup([]specs.LxdCProbe{{TcpPort: 80}})
+			executor.Address = ""
+
+			status, ready := instance.CheckNodeReadiness(proj, node, executor)
+			Expect(ready).To(BeFalse())
+			Expect(status[0].Message).To(Equal("node without IP address"))
+		})
+	})
+
+	Context("HTTP probe", func() {
+
+		It("Check the status code", func() {
+			server := httptest.NewServer(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/health" {
+						w.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+					w.WriteHeader(http.StatusOK)
+				}))
+			defer server.Close()
+
+			u, err := url.Parse(server.URL)
+			Expect(err).ToNot(HaveOccurred())
+			port, err := strconv.Atoi(u.Port())
+			Expect(err).ToNot(HaveOccurred())
+
+			setup([]specs.LxdCProbe{
+				{HttpPort: port, HttpPath: "health"},
+				{HttpPort: port, HttpPath: "/"},
+			})
+
+			status, ready := instance.CheckNodeReadiness(proj, node, executor)
+			Expect(ready).To(BeFalse())
+			Expect(status[0].Ready).To(BeTrue())
+			Expect(status[1].Ready).To(BeFalse())
+			Expect(status[1].Message).To(Equal("unexpected status code 503"))
+		})
+	})
+
+	It("Wait the readiness until the max retries", func() {
+		setup([]specs.LxdCProbe{{Command: "pgrep nginx"}})
+		node.Readiness.Retries = 2
+		node.Readiness.Interval = 1
+		executor.FailCmds["pgrep nginx"] = true
+
+		err := instance.waitNodeReadiness(proj, &proj.Groups[0], node, executor)
+		Expect(err).To(MatchError("node node1 not ready after 2 checks"))
+		Expect(executor.Commands).To(HaveLen(2))
+	})
+})
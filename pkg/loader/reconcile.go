@@ -0,0 +1,96 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+)
+
+// Align the profiles, the config, the labels and the devices of an existing
+// instance with the node definition. A running instance is restarted
+// when a config key updated requires the restart.
+func (i *LxdCInstance) reconcileInstance(
+	proj *specs.LxdCProject,
+	group *specs.LxdCGroup,
+	node *specs.LxdCNode,
+	executor lxd_executor.LxdCExecutor,
+	instanceProfiles []string, isRunning bool) error {
+
+	profiles := []string{}
+	profiles = append(profiles, group.CommonProfiles...)
+	profiles = append(profiles, node.Profiles...)
+
+	configMap := node.GetLxdConfig(group.GetLxdConfig())
+	devicesMap := node.GetLxdDevices(group.GetLxdDevices())
+
+	err := i.validateProfiles(instanceProfiles, profiles)
+	if err != nil {
+		return err
+	}
+
+	changes, err := executor.SyncInstanceConfig(node.GetName(), profiles,
+		configMap, devicesMap)
+	if err != nil {
+		i.Logger.Error(fmt.Sprintf("Error on reconcile the instance %s: %s",
+			node.GetName(), err.Error()))
+		return err
+	}
+
+	if len(changes) == 0 {
+		i.Logger.Debug(fmt.Sprintf("[%s - %s] Node %s already aligned.",
+			proj.Name, group.Name, node.GetName()))
+		return nil
+	}
+	i.planNodeAction(node, specs.PlanActionUpdate)
+
+	sort.Strings(changes)
+	i.Logger.InfoC(
+		i.Logger.Aurora.Bold(
+			i.Logger.Aurora.BrightCyan(
+				fmt.Sprintf(">>> [%s] Updated %s - :wrench:",
+					node.GetName(), strings.Join(changes, ", ")))))
+
+	restart := false
+	for _, k := range changes {
+		if specs.ConfigKeyRequiresRestart(k) {
+			restart = true
+			break
+		}
+	}
+
+	if !restart || !isRunning {
+		return nil
+	}
+
+	if group.Ephemeral {
+		i.Logger.Warning(fmt.Sprintf(
+			"[%s - %s] Node %s requires a restart but it's ephemeral. Restart skipped.",
+			proj.Name, group.Name, node.GetName()))
+		return nil
+	}
+
+	i.Logger.InfoC(
+		i.Logger.Aurora.Bold(
+			i.Logger.Aurora.BrightCyan(
+				fmt.Sprintf(">>> [%s] Restarting node to apply the config... - :arrows_counterclockwise:",
+					node.GetName()))))
+
+	err = executor.StopContainer(node.GetName())
+	if err != nil {
+		return err
+	}
+
+	err = executor.StartContainer(node.GetName())
+	if err != nil {
+		return err
+	}
+
+	return i.waitNodeAgent(node, executor)
+}
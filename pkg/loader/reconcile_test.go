@@ -0,0 +1,150 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reconcile", func() {
+
+	var executor *fakeExecutor
+	var instance *LxdCInstance
+	var proj *specs.LxdCProject
+	var group *specs.LxdCGroup
+	var node *specs.LxdCNode
+
+	setup := func(running bool) {
+		executor = newFakeExecutor()
+		executor.AddInstance("node1", running)
+		executor.Instances["node1"].Config = map[string]string{
+			"user.user-data":               "#cloud-config",
+			"user.role":                    "db",
+			"user.old":                     "1",
+			specs.InstanceManagedLabelsKey: "user.old,user.role",
+		}
+		instance = newTestInstance(specs.LxdCProject{
+			Name: "proj1",
+			Groups: []specs.LxdCGroup{
+				{
+					Name: "group1",
+					Nodes: []specs.LxdCNode{
+						{
+							Name:   "node1",
+							Labels: map[string]string{"role": "web"},
+						},
+					},
+				},
+			},
+		}, executor)
+		proj = &instance.Environments[0].Projects[0]
+		group = &proj.Groups[0]
+		node = &group.Nodes[0]
+	}
+
+	It("Update the labels without restart", func() {
+		setup(true)
+
+		err := instance.reconcileInstance(proj, group, node, executor, nil, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(executor.Instances["node1"].Config).To(Equal(map[string]string{
+			"user.user-data":               "#cloud-config",
+			"user.role":                    "web",
+			specs.InstanceManagedConfigKey: "user.role",
+		}))
+		Expect(executor.Calls).To(Equal([]string{"SyncInstanceConfig node1"}))
+	})
+
+	It("Keep the devices not managed", func() {
+		setup(true)
+		executor.Instances["node1"].Devices = map[string]map[string]string{
+			"debug": {"type": "disk", "path": "/debug", "source": "/srv/debug"},
+		}
+
+		err := instance.reconcileInstance(proj, group, node, executor, nil, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(executor.Instances["node1"].Devices).To(HaveKey("debug"))
+
+		node.Devices = map[string]map[string]string{
+			"data": {"type": "disk", "path": "/data", "source": "/srv/data"},
+		}
+		err = instance.reconcileInstance(proj, group, node, executor, nil, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(executor.Instances["node1"].Devices).To(HaveLen(2))
+		Expect(executor.Instances["node1"].Config[specs.InstanceManagedDevicesKey]).To(Equal("data"))
+
+		// The device removed from the node is removed from the instance.
+		node.Devices = nil
+		err = instance.reconcileInstance(proj, group, node, executor, nil, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(executor.Instances["node1"].Devices).To(Equal(map[string]map[string]string{
+			"debug": {"type": "disk", "path": "/debug", "source": "/srv/debug"},
+		}))
+		Expect(executor.Instances["node1"].Config).ToNot(HaveKey(specs.InstanceManagedDevicesKey))
+	})
+
+	It("Remove the config keys set previously", func() {
+		setup(true)
+		node.Config = map[string]string{"limits.cpu": "2"}
+
+		err := instance.reconcileInstance(proj, group, node, executor, nil, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(executor.Instances["node1"].Config[specs.InstanceManagedConfigKey]).To(
+			Equal("limits.cpu,user.role"))
+
+		node.Config = nil
+		err = instance.reconcileInstance(proj, group, node, executor, nil, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(executor.Instances["node1"].Config).ToNot(HaveKey("limits.cpu"))
+		Expect(executor.Instances["node1"].Config[specs.InstanceManagedConfigKey]).To(
+			Equal("user.role"))
+	})
+
+	It("Restart the running node for the keys that require restart", func() {
+		setup(true)
+		node.Config = map[string]string{"security.nesting": "true"}
+
+		err := instance.reconcileInstance(proj, group, node, executor, nil, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(executor.Calls).To(Equal([]string{
+			"SyncInstanceConfig node1",
+			"StopContainer node1",
+			"StartContainer node1",
+		}))
+		Expect(executor.Instances["node1"].Running).To(BeTrue())
+	})
+
+	It("Doesn't restart a stopped node", func() {
+		setup(false)
+		node.Config = map[string]string{"security.nesting": "true"}
+
+		err := instance.reconcileInstance(proj, group, node, executor, nil, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(executor.Calls).To(Equal([]string{"SyncInstanceConfig node1"}))
+	})
+
+	It("Doesn't restart an ephemeral node", func() {
+		setup(true)
+		group.Ephemeral = true
+		node.Config = map[string]string{"raw.lxc": "lxc.apparmor.profile=unconfined"}
+
+		err := instance.reconcileInstance(proj, group, node, executor, nil, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(executor.Calls).To(Equal([]string{"SyncInstanceConfig node1"}))
+	})
+
+	It("Fails with profiles not available", func() {
+		setup(true)
+		node.Profiles = []string{"net"}
+
+		err := instance.reconcileInstance(proj, group, node, executor,
+			[]string{"default"}, true)
+		Expect(err).To(HaveOccurred())
+		Expect(executor.Calls).To(BeEmpty())
+	})
+})
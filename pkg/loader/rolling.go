@@ -0,0 +1,185 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+	"github.com/MottainaiCI/lxd-compose/pkg/template"
+)
+
+// Apply the nodes of the group in batches of max_unavailable nodes.
+// After every batch the health check is executed on the nodes of the
+// batch and the rollout is aborted when the failed nodes are more
+// than the failure budget. The nodes that depend on a failed node are
+// skipped and the rollout with failed nodes returns an error also when
+// the failures are inside the budget.
+func (i *LxdCInstance) applyNodesRolling(
+	levels [][]*specs.LxdCNode,
+	group *specs.LxdCGroup, proj *specs.LxdCProject,
+	compiler template.LxdCTemplateCompiler,
+	instanceProfiles []string, envBaseAbs string) error {
+
+	rolling := group.RollingUpgrade
+	batchSize := rolling.GetMaxUnavailable()
+	if i.Ask {
+		batchSize = 1
+	}
+	nbatch := 0
+
+	// The nodes failed or skipped. The dependencies are defined
+	// with the name of the node without prefix.
+	failed := []string{}
+	skipped := []string{}
+	notDone := make(map[string]bool, 0)
+
+	// The batches are created inside the dependencies levels.
+	batches := [][]*specs.LxdCNode{}
+	for _, level := range levels {
+		for start := 0; start < len(level); start += batchSize {
+			end := start + batchSize
+			if end > len(level) {
+				end = len(level)
+			}
+			batches = append(batches, level[start:end])
+		}
+	}
+
+	for _, b := range batches {
+		nbatch++
+
+		batch := []*specs.LxdCNode{}
+		names := []string{}
+		for _, node := range b {
+			dep := ""
+			for _, d := range node.DependsOn {
+				if notDone[d] {
+					dep = d
+					break
+				}
+			}
+
+			if dep != "" {
+				i.Logger.Warning(fmt.Sprintf(
+					"[%s - %s] Node %s skipped: the dependency %s is not upgraded.",
+					proj.Name, group.Name, node.GetName(), dep))
+				notDone[node.Name] = true
+				skipped = append(skipped, node.GetName())
+				continue
+			}
+
+			batch = append(batch, node)
+			names = append(names, node.GetName())
+		}
+
+		if len(batch) == 0 {
+			continue
+		}
+
+		i.Logger.InfoC(
+			i.Logger.Aurora.Bold(
+				i.Logger.Aurora.BrightCyan(
+					fmt.Sprintf(">>> [%s - %s] Rolling upgrade batch %d/%d: %v - :arrows_counterclockwise:",
+						proj.Name, group.Name, nbatch, len(batches), names))))
+
+		errs := i.runWorkers(names, len(batch), false,
+			func(ctx context.Context, idx int) error {
+				executor, err := i.newGroupExecutor(group)
+				if err != nil {
+					return err
+				}
+
+				err = i.applyNode(ctx, batch[idx], group, proj, compiler,
+					executor, instanceProfiles, envBaseAbs)
+				if err != nil {
+					return err
+				}
+
+				return i.checkNodeHealth(batch[idx], group, proj, executor)
+			})
+
+		for idx, err := range errs {
+			if err != nil {
+				failed = append(failed, names[idx])
+				notDone[batch[idx].Name] = true
+				i.Logger.Error(fmt.Sprintf("[%s - %s] Node %s failed: %s",
+					proj.Name, group.Name, names[idx], err.Error()))
+			}
+		}
+
+		if len(failed) > rolling.FailureBudget {
+			return fmt.Errorf(
+				"rolling upgrade of the group %s aborted: %d nodes failed with a failure budget of %d",
+				group.Name, len(failed), rolling.FailureBudget)
+		}
+
+		if rolling.PauseBetween > 0 && nbatch < len(batches) && !i.isDryRun() {
+			i.Logger.Debug(fmt.Sprintf("[%s - %s] Waiting %d seconds before the next batch.",
+				proj.Name, group.Name, rolling.PauseBetween))
+			time.Sleep(time.Duration(rolling.PauseBetween) * time.Second)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf(
+			"rolling upgrade of the group %s completed with failed nodes %v and skipped nodes %v",
+			group.Name, failed, skipped)
+	}
+
+	return nil
+}
+
+// Run the health check command of the rolling upgrade inside the node.
+func (i *LxdCInstance) checkNodeHealth(node *specs.LxdCNode,
+	group *specs.LxdCGroup, proj *specs.LxdCProject,
+	executor lxd_executor.LxdCExecutor) error {
+
+	rolling := group.RollingUpgrade
+	if rolling.HealthCheck == "" {
+		return nil
+	}
+
+	i.varsMutex.Lock()
+	envs, err := proj.GetEnvsMap()
+	i.varsMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if _, ok := envs["HOME"]; !ok {
+		envs["HOME"] = "/"
+	}
+
+	// Every attempt is stopped by the executor after the timeout.
+	// The timeout used by the hooks is restored after the check.
+	prevTimeout := executor.GetCommandTimeout()
+	executor.SetCommandTimeout(time.Duration(rolling.GetHealthCheckTimeout()) * time.Second)
+	defer executor.SetCommandTimeout(prevTimeout)
+
+	retries := rolling.GetHealthCheckRetries()
+	for attempt := 1; attempt <= retries; attempt++ {
+		res, err := executor.RunCommand(node.GetName(), rolling.HealthCheck,
+			envs, node.Entrypoint, nil, nil, "")
+		if err == nil && res == 0 {
+			i.Logger.InfoC(
+				i.Logger.Aurora.BrightCyan(
+					fmt.Sprintf(">>> [%s] Health check passed. - :check_mark:",
+						node.GetName())))
+			return nil
+		}
+
+		i.Logger.Debug(fmt.Sprintf("[%s] Health check attempt %d/%d failed (%d, %v).",
+			node.GetName(), attempt, retries, res, err))
+
+		if attempt < retries && rolling.HealthCheckDelay > 0 {
+			time.Sleep(time.Duration(rolling.HealthCheckDelay) * time.Second)
+		}
+	}
+
+	return fmt.Errorf("health check of the node %s failed", node.GetName())
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"time"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+	template "github.com/MottainaiCI/lxd-compose/pkg/template"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func newRollingProject(budget int, deps map[string][]string) specs.LxdCProject {
+	nodes := []specs.LxdCNode{}
+	for _, name := range []string{"node1", "node2", "node3", "node4"} {
+		nodes = append(nodes, specs.LxdCNode{
+			Name:        name,
+			ImageSource: "alpine/3.20",
+			DependsOn:   deps[name],
+		})
+	}
+
+	return specs.LxdCProject{
+		Name: "proj1",
+		Groups: []specs.LxdCGroup{
+			{
+				Name:       "group1",
+				Connection: "local",
+				RollingUpgrade: &specs.LxdCRollingUpgrade{
+					MaxUnavailable: 2,
+					HealthCheck:    "health",
+					FailureBudget:  budget,
+				},
+				Nodes: nodes,
+			},
+		},
+	}
+}
+
+var _ = Describe("Rolling upgrade", func() {
+
+	var executor *fakeExecutor
+	var instance *LxdCInstance
+
+	setup := func(budget int, deps map[string][]string) {
+		executor = newFakeExecutor()
+		for _, name := range []string{"node1", "node2", "node3", "node4"} {
+			executor.AddInstance(name, true)
+		}
+		instance = newTestInstance(newRollingProject(budget, deps), executor)
+		instance.Upgrade = true
+	}
+
+	applyRolling := func() error {
+		env := &instance.Environments[0]
+		proj := &env.Projects[0]
+		group := &proj.Groups[0]
+
+		compiler, err := template.NewProjectTemplateCompiler(env, proj)
+		Expect(err).ToNot(HaveOccurred())
+
+		levels, err := group.GetNodesLevels()
+		Expect(err).ToNot(HaveOccurred())
+
+		return instance.applyNodesRolling(levels, group, proj, compiler,
+			[]string{"default"}, GinkgoT().TempDir())
+	}
+
+	It("Upgrade all nodes in batches", func() {
+		setup(0, nil)
+
+		err := applyRolling()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(executor.Commands).To(ConsistOf(
+			"node1 health", "node2 health", "node3 health", "node4 health"))
+		// The nodes of the second batch are upgraded after the health
+		// check of the first batch.
+		Expect(executor.Commands[2:]).To(ConsistOf("node3 health", "node4 health"))
+	})
+
+	It("Abort the rollout when the failure budget is exceeded", func() {
+		setup(0, nil)
+		executor.FailCmds["node1 health"] = true
+
+		err := applyRolling()
+		Expect(err).To(MatchError(ContainSubstring("aborted")))
+		Expect(executor.Commands).To(ConsistOf("node1 health", "node2 health"))
+		Expect(executor.Calls).ToNot(ContainElement("DeleteContainer node3"))
+		Expect(executor.Calls).ToNot(ContainElement("DeleteContainer node4"))
+	})
+
+	It("Continue inside the failure budget and return an error", func() {
+		setup(1, nil)
+		executor.FailCmds["node1 health"] = true
+
+		err := applyRolling()
+		Expect(err).To(MatchError(ContainSubstring("failed nodes [node1]")))
+		Expect(executor.Commands).To(ConsistOf(
+			"node1 health", "node2 health", "node3 health", "node4 health"))
+	})
+
+	It("Stop the hanging health check with the timeout", func() {
+		setup(0, nil)
+		group := &instance.Environments[0].Projects[0].Groups[0]
+		group.RollingUpgrade.MaxUnavailable = 1
+		group.RollingUpgrade.HealthCheckRetries = 2
+		group.RollingUpgrade.HealthCheckTimeout = 5
+		executor.SetCommandTimeout(10 * time.Second)
+		executor.HangCmds["node1 health"] = true
+
+		err := applyRolling()
+		Expect(err).To(MatchError(ContainSubstring("aborted")))
+		Expect(executor.Commands).To(Equal([]string{"node1 health", "node1 health"}))
+		Expect(executor.Timeouts).To(Equal([]time.Duration{5 * time.Second, 5 * time.Second}))
+		// The timeout of the executor is restored.
+		Expect(executor.GetCommandTimeout()).To(Equal(10 * time.Second))
+	})
+
+	It("Skip the nodes that depend on a failed node", func() {
+		setup(1, map[string][]string{
+			"node3": {"node1"},
+			"node4": {"node3"},
+		})
+		executor.FailCmds["node1 health"] = true
+
+		err := applyRolling()
+		Expect(err).To(MatchError(ContainSubstring("skipped nodes [node3 node4]")))
+		Expect(executor.Commands).To(ConsistOf("node1 health", "node2 health"))
+		Expect(executor.Calls).ToNot(ContainElement("DeleteContainer node3"))
+		Expect(executor.Calls).ToNot(ContainElement("DeleteContainer node4"))
+	})
+})
@@ -0,0 +1,207 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"errors"
+	"fmt"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+)
+
+// Call the function for every node of the enabled groups of the project
+// present in the remotes. The nodes not present are skipped.
+func (i *LxdCInstance) forEachPresentNode(projectName string,
+	f func(executor lxd_executor.LxdCExecutor, grp *specs.LxdCGroup, node *specs.LxdCNode) error) error {
+
+	env := i.GetEnvByProjectName(projectName)
+	if env == nil {
+		return errors.New("No environment found for project " + projectName)
+	}
+
+	proj := env.GetProjectByName(projectName)
+	if proj == nil {
+		return errors.New("No project found with name " + projectName)
+	}
+
+	if i.NodesPrefix != "" {
+		proj.SetNodesPrefix(i.NodesPrefix)
+	}
+
+	groups, err := proj.GetGroupsOrdered(i.GroupsEnabled, i.GroupsDisabled, false)
+	if err != nil {
+		return err
+	}
+
+	for _, grp := range groups {
+		executor, err := i.newGroupExecutor(grp)
+		if err != nil {
+			return fmt.Errorf("Error on initialize executor for group %s: %s",
+				grp.Name, err.Error())
+		}
+
+		for idx := range grp.Nodes {
+			node := &grp.Nodes[idx]
+
+			present, err := executor.IsPresentContainer(node.GetName())
+			if err != nil {
+				return err
+			}
+
+			if !present {
+				i.Logger.Warning(fmt.Sprintf(
+					"[%s] Node not present on group %s. Skipped.",
+					node.GetName(), grp.Name))
+				continue
+			}
+
+			err = f(executor, grp, node)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Create a snapshot with the same name for all the nodes of the project.
+func (i *LxdCInstance) CreateProjectSnapshot(projectName, snapshot string, stateful bool) error {
+	return i.forEachPresentNode(projectName,
+		func(executor lxd_executor.LxdCExecutor, grp *specs.LxdCGroup, node *specs.LxdCNode) error {
+			err := executor.CreateSnapshot(node.GetName(), snapshot, stateful)
+			if err != nil {
+				return fmt.Errorf("error on create snapshot %s of node %s: %s",
+					snapshot, node.GetName(), err.Error())
+			}
+
+			i.Logger.InfoC(
+				i.Logger.Aurora.Bold(
+					i.Logger.Aurora.BrightCyan(
+						fmt.Sprintf(">>> [%s] Snapshot %s created - :camera:",
+							node.GetName(), snapshot))))
+			return nil
+		})
+}
+
+// Return the snapshots of all the nodes of the project.
+func (i *LxdCInstance) GetProjectSnapshots(projectName string) ([]*specs.LxdCSnapshot, error) {
+	ans := []*specs.LxdCSnapshot{}
+
+	err := i.forEachPresentNode(projectName,
+		func(executor lxd_executor.LxdCExecutor, grp *specs.LxdCGroup, node *specs.LxdCNode) error {
+			snapshots, err := executor.GetSnapshots(node.GetName())
+			if err != nil {
+				return fmt.Errorf("error on retrieve snapshots of node %s: %s",
+					node.GetName(), err.Error())
+			}
+			ans = append(ans, snapshots...)
+			return nil
+		})
+
+	return ans, err
+}
+
+// Restore all the nodes of the project to the snapshot and return
+// the nodes restored. The restore starts only if the snapshot is
+// available for all the nodes. On failure the nodes already restored
+// and the nodes not restored are reported in the error.
+func (i *LxdCInstance) RestoreProjectSnapshot(projectName, snapshot string, stateful bool) ([]string, error) {
+	restored := []string{}
+	pending := []string{}
+
+	err := i.forEachPresentNode(projectName,
+		func(executor lxd_executor.LxdCExecutor, grp *specs.LxdCGroup, node *specs.LxdCNode) error {
+			snapshots, err := executor.GetSnapshots(node.GetName())
+			if err != nil {
+				return err
+			}
+
+			for _, s := range snapshots {
+				if s.Name == snapshot {
+					pending = append(pending, node.GetName())
+					return nil
+				}
+			}
+
+			return fmt.Errorf("snapshot %s not available for node %s",
+				snapshot, node.GetName())
+		})
+	if err != nil {
+		return restored, err
+	}
+
+	err = i.forEachPresentNode(projectName,
+		func(executor lxd_executor.LxdCExecutor, grp *specs.LxdCGroup, node *specs.LxdCNode) error {
+			err := executor.RestoreSnapshot(node.GetName(), snapshot, stateful)
+			if err != nil {
+				return fmt.Errorf("error on restore snapshot %s of node %s: %s",
+					snapshot, node.GetName(), err.Error())
+			}
+			restored = append(restored, node.GetName())
+
+			i.Logger.InfoC(
+				i.Logger.Aurora.Bold(
+					i.Logger.Aurora.BrightCyan(
+						fmt.Sprintf(">>> [%s] Restored snapshot %s - :rewind:",
+							node.GetName(), snapshot))))
+			return nil
+		})
+	if err != nil {
+		notRestored := []string{}
+		for _, n := range pending[len(restored):] {
+			notRestored = append(notRestored, n)
+		}
+
+		i.Logger.Warning(fmt.Sprintf(
+			"[%s] Restore of the snapshot %s interrupted. Nodes restored: %v. Nodes not restored: %v.",
+			projectName, snapshot, restored, notRestored))
+
+		return restored, fmt.Errorf("%s (restored nodes %v, not restored nodes %v)",
+			err.Error(), restored, notRestored)
+	}
+
+	return restored, nil
+}
+
+// Delete the snapshot from all the nodes of the project. The nodes
+// without the snapshot are skipped.
+func (i *LxdCInstance) DeleteProjectSnapshot(projectName, snapshot string) error {
+	return i.forEachPresentNode(projectName,
+		func(executor lxd_executor.LxdCExecutor, grp *specs.LxdCGroup, node *specs.LxdCNode) error {
+			snapshots, err := executor.GetSnapshots(node.GetName())
+			if err != nil {
+				return err
+			}
+
+			present := false
+			for _, s := range snapshots {
+				if s.Name == snapshot {
+					present = true
+					break
+				}
+			}
+
+			if !present {
+				i.Logger.Debug(fmt.Sprintf("[%s] Snapshot %s not present. Skipped.",
+					node.GetName(), snapshot))
+				return nil
+			}
+
+			err = executor.DeleteSnapshot(node.GetName(), snapshot)
+			if err != nil {
+				return fmt.Errorf("error on delete snapshot %s of node %s: %s",
+					snapshot, node.GetName(), err.Error())
+			}
+
+			i.Logger.InfoC(
+				i.Logger.Aurora.Bold(
+					i.Logger.Aurora.BrightCyan(
+						fmt.Sprintf(">>> [%s] Snapshot %s removed - :knife:",
+							node.GetName(), snapshot))))
+			return nil
+		})
+}
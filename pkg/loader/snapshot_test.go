@@ -0,0 +1,68 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"errors"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Project snapshots", func() {
+
+	var executor *fakeExecutor
+	var instance *LxdCInstance
+
+	BeforeEach(func() {
+		executor = newFakeExecutor()
+		executor.AddInstance("node1", true)
+		executor.AddInstance("node2", true)
+		executor.AddInstance("node3", true)
+		for _, n := range []string{"node1", "node2", "node3"} {
+			executor.Instances[n].Snapshots = []string{"lxdc-20260101-100000"}
+		}
+		instance = newTestInstance(specs.LxdCProject{
+			Name: "proj1",
+			Groups: []specs.LxdCGroup{
+				{
+					Name: "group1",
+					Nodes: []specs.LxdCNode{
+						{Name: "node1"},
+						{Name: "node2"},
+						{Name: "node3"},
+						{Name: "node4"},
+					},
+				},
+			},
+		}, executor)
+	})
+
+	It("Restore all the nodes present", func() {
+		restored, err := instance.RestoreProjectSnapshot("proj1", "lxdc-20260101-100000", false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(restored).To(Equal([]string{"node1", "node2", "node3"}))
+	})
+
+	It("Restore nothing when the snapshot is missing on a node", func() {
+		executor.Instances["node2"].Snapshots = []string{}
+
+		restored, err := instance.RestoreProjectSnapshot("proj1", "lxdc-20260101-100000", false)
+		Expect(err).To(MatchError("snapshot lxdc-20260101-100000 not available for node node2"))
+		Expect(restored).To(BeEmpty())
+		Expect(executor.Calls).ToNot(ContainElement(HavePrefix("RestoreSnapshot")))
+	})
+
+	It("Report the nodes restored on failure", func() {
+		executor.Fails["RestoreSnapshot node2 lxdc-20260101-100000"] = errors.New("restore error")
+
+		restored, err := instance.RestoreProjectSnapshot("proj1", "lxdc-20260101-100000", false)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("restored nodes [node1], not restored nodes [node2 node3]"))
+		Expect(restored).To(Equal([]string{"node1"}))
+	})
+})
@@ -0,0 +1,112 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"errors"
+	"fmt"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+)
+
+// Compare the nodes of the project with the instances available
+// in the remotes and return the status of every node with the
+// drifts found. With checkImage the image of the instance is compared
+// with the fingerprint of the image_source available in the remotes.
+func (i *LxdCInstance) GetProjectStatus(projectName string, checkImage bool) ([]*specs.LxdCNodeStatus, error) {
+	ans := []*specs.LxdCNodeStatus{}
+
+	env := i.GetEnvByProjectName(projectName)
+	if env == nil {
+		return nil, errors.New("No environment found for project " + projectName)
+	}
+
+	proj := env.GetProjectByName(projectName)
+	if proj == nil {
+		return nil, errors.New("No project found with name " + projectName)
+	}
+
+	if i.NodesPrefix != "" {
+		proj.SetNodesPrefix(i.NodesPrefix)
+	}
+
+	groups, err := proj.GetGroupsOrdered(i.GroupsEnabled, i.GroupsDisabled, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, grp := range groups {
+		executor, err := i.newGroupExecutor(grp)
+		if err != nil {
+			return nil, fmt.Errorf("Error on initialize executor for group %s: %s",
+				grp.Name, err.Error())
+		}
+
+		// Cache of the fingerprints of the images of the group.
+		images := make(map[string]string, 0)
+
+		for idx := range grp.Nodes {
+			node := &grp.Nodes[idx]
+
+			status := &specs.LxdCNodeStatus{
+				Name:        node.GetName(),
+				Group:       grp.Name,
+				ImageSource: node.ImageSource,
+				Drifts:      []specs.LxdCDrift{},
+			}
+			ans = append(ans, status)
+
+			status.Present, err = executor.IsPresentContainer(node.GetName())
+			if err != nil {
+				return nil, err
+			}
+
+			if !status.Present {
+				status.AddDrift("present", "true", "false")
+				continue
+			}
+
+			info, err := executor.GetInstanceInfo(node.GetName())
+			if err != nil {
+				return nil, err
+			}
+
+			status.Running = info.Status == "Running"
+			status.Addresses = info.Addresses
+			status.ImageFingerprint = info.ImageFingerprint
+
+			if !status.Running {
+				status.AddDrift("running", "true", "false")
+			}
+
+			profiles := []string{}
+			profiles = append(profiles, grp.CommonProfiles...)
+			profiles = append(profiles, node.Profiles...)
+
+			status.CompareInstance(info, profiles,
+				node.GetLxdConfig(grp.GetLxdConfig()),
+				node.GetLxdDevices(grp.GetLxdDevices()))
+
+			if checkImage && node.ImageSource != "" {
+				key := node.ImageRemoteServer + "/" + node.ImageSource
+				fingerprint, ok := images[key]
+				if !ok {
+					fingerprint, err = executor.GetImageFingerprint(
+						node.ImageSource, node.ImageRemoteServer)
+					if err != nil {
+						status.Errors = append(status.Errors, err.Error())
+					}
+					images[key] = fingerprint
+				}
+
+				if fingerprint != "" && fingerprint != info.ImageFingerprint {
+					status.AddDrift("image", fingerprint, info.ImageFingerprint)
+				}
+			}
+		}
+	}
+
+	return ans, nil
+}
@@ -41,18 +41,17 @@ func (i *LxdCInstance) StopProject(projectName string) error {
 		return err
 	}
 
-	for _, grp := range proj.Groups {
-
-		if !grp.ToProcess(i.GroupsEnabled, i.GroupsDisabled) {
-			i.Logger.Debug("Skipped group ", grp.Name)
-			continue
-		}
+	// Groups are processed in the reverse order of the dependencies.
+	groups, err := proj.GetGroupsOrdered(i.GroupsEnabled, i.GroupsDisabled, true)
+	if err != nil {
+		return err
+	}
 
-		err := i.StopGroup(&grp, proj, env)
+	for _, grp := range groups {
+		err := i.StopGroup(grp, proj, env)
 		if err != nil {
 			return err
 		}
-
 	}
 
 	// Execute pre-project hooks
@@ -91,7 +90,13 @@ func (i *LxdCInstance) StopGroup(group *specs.LxdCGroup, proj *specs.LxdCProject
 		return err
 	}
 
-	for _, node := range group.Nodes {
+	// Nodes are processed in the reverse order of the dependencies.
+	nodes, err := group.GetNodesOrdered(true)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
 
 		isPresent, err := executor.IsPresentContainer(node.GetName())
 		if err != nil {
@@ -103,8 +108,8 @@ func (i *LxdCInstance) StopGroup(group *specs.LxdCGroup, proj *specs.LxdCProject
 		if isPresent {
 
 			// Retrieve and run pre-node-shutdown hooks of the node from project
-			preNodeShutdownHooks := i.GetNodeHooks4Event(specs.HookPreNodeShutdown, proj, group, &node)
-			err = i.ProcessHooks(&preNodeShutdownHooks, proj, group, &node)
+			preNodeShutdownHooks := i.GetNodeHooks4Event(specs.HookPreNodeShutdown, proj, group, node)
+			err = i.ProcessHooks(&preNodeShutdownHooks, proj, group, node)
 			if err != nil {
 				return err
 			}
@@ -117,8 +122,8 @@ func (i *LxdCInstance) StopGroup(group *specs.LxdCGroup, proj *specs.LxdCProject
 			}
 
 			// Retrieve and run post-node-shutdown hooks of the node from project
-			postNodeShutdownHooks := i.GetNodeHooks4Event("post-node-shutdown", proj, group, &node)
-			err = i.ProcessHooks(&postNodeShutdownHooks, proj, group, &node)
+			postNodeShutdownHooks := i.GetNodeHooks4Event("post-node-shutdown", proj, group, node)
+			err = i.ProcessHooks(&postNodeShutdownHooks, proj, group, node)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,256 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	helpers "github.com/MottainaiCI/lxd-compose/pkg/helpers"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+)
+
+// Push only the files of the resource changed compared with the
+// files present on the node and remove the files not present in
+// the source when delete is enabled.
+func (i *LxdCInstance) syncResourceIncremental(node *specs.LxdCNode,
+	executor lxd_executor.LxdCExecutor, sourcePath string,
+	resource *specs.LxdCSyncResource) (*specs.LxdCSyncSummary, error) {
+
+	fInfo, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteRoot := specs.GetSyncRemoteRoot(resource.Source,
+		resource.Destination, fInfo.IsDir())
+
+	local := []*specs.LxdCSyncFile{}
+	manifestRoot := remoteRoot
+	depth := ""
+
+	getLocalPath := func(p string) string {
+		if !fInfo.IsDir() {
+			return sourcePath
+		}
+		return filepath.Join(sourcePath, filepath.FromSlash(p))
+	}
+
+	if fInfo.IsDir() {
+		err = filepath.Walk(sourcePath, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if p == sourcePath {
+				return nil
+			}
+			rel, err := filepath.Rel(sourcePath, p)
+			if err != nil {
+				return err
+			}
+			if !resource.IsToPush(filepath.ToSlash(rel), info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			local = append(local, &specs.LxdCSyncFile{
+				Path:  filepath.ToSlash(rel),
+				Dir:   info.IsDir(),
+				Size:  info.Size(),
+				Mtime: info.ModTime().Unix(),
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// On single file only the file is compared.
+		manifestRoot = path.Dir(remoteRoot)
+		depth = "-maxdepth 1"
+		local = append(local, &specs.LxdCSyncFile{
+			Path:  path.Base(remoteRoot),
+			Size:  fInfo.Size(),
+			Mtime: fInfo.ModTime().Unix(),
+		})
+	}
+
+	remote, err := i.getSyncManifest(node, executor, manifestRoot, depth)
+	if err != nil {
+		return nil, err
+	}
+	if !fInfo.IsDir() {
+		// Only the target file is relevant.
+		r, ok := remote[local[0].Path]
+		remote = make(map[string]*specs.LxdCSyncFile, 0)
+		if ok {
+			remote[r.Path] = r
+		}
+	}
+
+	summary, err := specs.CompareSyncFiles(local, remote,
+		resource.Delete && fInfo.IsDir(),
+		func(paths []string) (map[string]*specs.LxdCSyncFile, error) {
+			return i.getSyncChecksums(node, executor, manifestRoot, paths)
+		},
+		func(f *specs.LxdCSyncFile) (string, error) {
+			return getFileSha256(getLocalPath(f.Path))
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range summary.Deleted {
+		if r, ok := remote[p]; ok && !resource.IsToPush(p, r.Dir) {
+			// The excluded files of the node are maintained.
+			continue
+		}
+		i.Logger.Debug(fmt.Sprintf("[%s] Removing %s...", node.GetName(),
+			path.Join(manifestRoot, p)))
+		err = executor.DeleteContainerDir(node.GetName(), path.Join(manifestRoot, p))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mode := os.FileMode(0755)
+	dirMode, err := resource.GetDirMode()
+	if err != nil {
+		return nil, err
+	}
+	if dirMode != nil {
+		mode = *dirMode
+	}
+	var uid, gid int64 = 0, 0
+	if resource.Uid != nil {
+		uid = *resource.Uid
+	}
+	if resource.Gid != nil {
+		gid = *resource.Gid
+	}
+	for _, p := range summary.NewDirs {
+		err = executor.RecursiveMkdir(node.GetName(), path.Join(manifestRoot, p),
+			&mode, uid, gid)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pushFiles := append([]string{}, summary.Added...)
+	pushFiles = append(pushFiles, summary.Updated...)
+	for _, p := range pushFiles {
+		lpath := getLocalPath(p)
+		rpath := path.Join(manifestRoot, p)
+
+		i.Logger.Debug(fmt.Sprintf("[%s] Pushing %s => %s", node.GetName(), lpath, rpath))
+		err = executor.RecursivePushFileWithOpts(node.GetName(), lpath, rpath,
+			&resource.LxdCFileOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return summary, nil
+}
+
+// Retrieve the files present under the root directory of the node.
+func (i *LxdCInstance) getSyncManifest(node *specs.LxdCNode,
+	executor lxd_executor.LxdCExecutor,
+	root, depth string) (map[string]*specs.LxdCSyncFile, error) {
+
+	var outBuffer, errBuffer bytes.Buffer
+
+	envs := map[string]string{
+		"LXDC_SYNC_ROOT":  root,
+		"LXDC_SYNC_DEPTH": depth,
+	}
+
+	res, err := executor.RunCommandWithOutput(node.GetName(),
+		specs.SyncManifestScript, envs,
+		helpers.NewNopCloseWriter(&outBuffer),
+		helpers.NewNopCloseWriter(&errBuffer),
+		[]string{"/bin/sh", "-c"}, nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if res != 0 {
+		return nil, fmt.Errorf("error on retrieve the files of %s (%d): %s",
+			root, res, errBuffer.String())
+	}
+
+	return specs.ParseSyncManifest(outBuffer.String())
+}
+
+// Retrieve the checksums of the files of the node under the root
+// directory. The files are processed in chunks to avoid too long
+// environment variables.
+func (i *LxdCInstance) getSyncChecksums(node *specs.LxdCNode,
+	executor lxd_executor.LxdCExecutor,
+	root string, paths []string) (map[string]*specs.LxdCSyncFile, error) {
+
+	ans := make(map[string]*specs.LxdCSyncFile, 0)
+	chunkSize := 200
+
+	for start := 0; start < len(paths); start += chunkSize {
+		var outBuffer, errBuffer bytes.Buffer
+
+		end := start + chunkSize
+		if end > len(paths) {
+			end = len(paths)
+		}
+
+		envs := map[string]string{
+			"LXDC_SYNC_ROOT":  root,
+			"LXDC_SYNC_FILES": strings.Join(paths[start:end], "\n"),
+		}
+
+		res, err := executor.RunCommandWithOutput(node.GetName(),
+			specs.SyncChecksumScript, envs,
+			helpers.NewNopCloseWriter(&outBuffer),
+			helpers.NewNopCloseWriter(&errBuffer),
+			[]string{"/bin/sh", "-c"}, nil, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		if res != 0 {
+			return nil, fmt.Errorf("error on retrieve the checksums of %s (%d): %s",
+				root, res, errBuffer.String())
+		}
+
+		sums, err := specs.ParseSyncManifest(outBuffer.String())
+		if err != nil {
+			return nil, err
+		}
+		for p, f := range sums {
+			ans[p] = f
+		}
+	}
+
+	return ans, nil
+}
+
+func getFileSha256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,43 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"fmt"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+	"github.com/MottainaiCI/lxd-compose/pkg/template"
+)
+
+// Push the templates compiled in memory to the node.
+func (i *LxdCInstance) pushNodeTemplates(node *specs.LxdCNode,
+	executor lxd_executor.LxdCExecutor,
+	templates []template.LxdCRenderedTemplate) error {
+
+	nTemplates := len(templates)
+	i.Logger.InfoC(
+		i.Logger.Aurora.Bold(
+			i.Logger.Aurora.BrightCyan(
+				fmt.Sprintf(">>> [%s] Pushing %d templates... - :icecream:",
+					node.GetName(), nTemplates))))
+
+	for idx, t := range templates {
+		err := executor.PushFileContent(node.GetName(), t.Template.Destination,
+			t.Content, &t.Template.LxdCFileOpts)
+		if err != nil {
+			i.Logger.Error(fmt.Sprintf("Error on push template %s: %s",
+				t.Template.Source, err.Error()))
+			return err
+		}
+
+		i.Logger.InfoC(
+			i.Logger.Aurora.BrightCyan(
+				fmt.Sprintf(">>> [%s] - [%2d/%2d] %s - :check_mark:",
+					node.GetName(), idx+1, nTemplates, t.Template.Destination)))
+	}
+
+	return nil
+}
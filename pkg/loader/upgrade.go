@@ -0,0 +1,164 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"fmt"
+
+	lxd_executor "github.com/MottainaiCI/lxd-compose/pkg/executor"
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+)
+
+// Suffix of the copy of the instance used for the upgrade rollback.
+const upgradeBackupSuffix = "-lxdc-upgrade"
+
+// Destroy and recreate the instance of the node and run the
+// post-node-upgrade hooks.
+func (i *LxdCInstance) upgradeInstance(
+	proj *specs.LxdCProject,
+	group *specs.LxdCGroup,
+	node *specs.LxdCNode,
+	executor lxd_executor.LxdCExecutor,
+	instanceProfiles []string) error {
+
+	// POST: The running container is stopped
+	//       and destroyed.
+	err := executor.DeleteContainer(node.GetName())
+	if err != nil {
+		i.Logger.Error("Error on destroy container " + node.GetName() +
+			": " + err.Error())
+		return err
+	}
+
+	// Execute the pre-node-creation hooks,
+	// create the container and run the post-node-creation
+	// hooks.
+	err = i.createInstance(
+		proj, group, node,
+		executor,
+		instanceProfiles,
+	)
+	if err != nil {
+		return err
+	}
+
+	postNodeUpgradeHooks := i.GetNodeHooks4Event(
+		specs.HookPostNodeUpgrade,
+		proj, group, node)
+
+	// Run post-node-creation hooks
+	i.Logger.Debug(fmt.Sprintf(
+		"[%s - %s] Running %d %s hooks for node %s... ",
+		proj.Name, group.Name, len(postNodeUpgradeHooks),
+		specs.HookPostNodeUpgrade, node.GetName()))
+
+	return i.ProcessHooks(&postNodeUpgradeHooks, proj, group, node)
+}
+
+func (i *LxdCInstance) getUpgradeBackupName(node *specs.LxdCNode) string {
+	return node.GetName() + upgradeBackupSuffix
+}
+
+// Copy the instance of the node before the upgrade. The copy is
+// used by rollbackInstance on failure.
+func (i *LxdCInstance) backupInstance(
+	proj *specs.LxdCProject,
+	group *specs.LxdCGroup,
+	node *specs.LxdCNode,
+	executor lxd_executor.LxdCExecutor) (string, error) {
+
+	backupName := i.getUpgradeBackupName(node)
+
+	present, err := executor.IsPresentContainer(backupName)
+	if err != nil {
+		return "", err
+	}
+
+	if present {
+		// POST: a previous rollback is been failed. I avoid to
+		//       override the last working instance.
+		return "", fmt.Errorf(
+			"The instance %s used for the rollback of the node %s is already present. Check and remove it before upgrade.",
+			backupName, node.GetName())
+	}
+
+	err = executor.CopyContainerOnInstance(node.GetName(), backupName)
+	if err != nil {
+		i.Logger.Error(fmt.Sprintf("Error on copy container %s to %s: %s",
+			node.GetName(), backupName, err.Error()))
+		return "", err
+	}
+
+	i.Logger.InfoC(
+		i.Logger.Aurora.Bold(
+			i.Logger.Aurora.BrightCyan(
+				fmt.Sprintf(">>> [%s - %s] Node %s copied to %s before upgrade. - :camera:",
+					proj.Name, group.Name, node.GetName(), backupName))))
+
+	return backupName, nil
+}
+
+// Restore the instance of the node from the copy created before the
+// upgrade. The returned error reports the upgrade error and the result
+// of the rollback.
+func (i *LxdCInstance) rollbackInstance(
+	proj *specs.LxdCProject,
+	group *specs.LxdCGroup,
+	node *specs.LxdCNode,
+	executor lxd_executor.LxdCExecutor,
+	backupName string, wasRunning bool, upgradeErr error) error {
+
+	i.Logger.Warning(fmt.Sprintf(
+		"[%s - %s] Upgrade of the node %s failed: %s. Restoring the previous instance...",
+		proj.Name, group.Name, node.GetName(), upgradeErr.Error()))
+
+	rollback := func() error {
+		present, err := executor.IsPresentContainer(node.GetName())
+		if err != nil {
+			return err
+		}
+
+		if present {
+			err = executor.DeleteContainer(node.GetName())
+			if err != nil {
+				return err
+			}
+		}
+
+		err = executor.CopyContainerOnInstance(backupName, node.GetName())
+		if err != nil {
+			return err
+		}
+
+		if wasRunning {
+			err = executor.StartContainer(node.GetName())
+			if err != nil {
+				return err
+			}
+		}
+
+		return executor.DeleteContainer(backupName)
+	}
+
+	// The node is restored to the state before the upgrade: on
+	// resume all the steps of the node must be executed again.
+	i.resetNodeSteps(node.GetName())
+
+	err := rollback()
+	if err != nil {
+		return fmt.Errorf(
+			"upgrade of the node %s failed (%s) and rollback failed (%s): the previous instance is available as %s",
+			node.GetName(), upgradeErr.Error(), err.Error(), backupName)
+	}
+
+	i.Logger.InfoC(
+		i.Logger.Aurora.Bold(
+			i.Logger.Aurora.BrightYellow(
+				fmt.Sprintf(">>> [%s - %s] Node %s restored to the previous instance. - :back:",
+					proj.Name, group.Name, node.GetName()))))
+
+	return fmt.Errorf("upgrade of the node %s failed and rollback done: %s",
+		node.GetName(), upgradeErr.Error())
+}
@@ -0,0 +1,173 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"context"
+	"errors"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+	template "github.com/MottainaiCI/lxd-compose/pkg/template"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func newUpgradeProject(strategy string) specs.LxdCProject {
+	return specs.LxdCProject{
+		Name: "proj1",
+		Groups: []specs.LxdCGroup{
+			{
+				Name:            "group1",
+				Connection:      "local",
+				UpgradeStrategy: strategy,
+				Nodes: []specs.LxdCNode{
+					{
+						Name:        "node1",
+						ImageSource: "alpine/3.20",
+						Hooks: []specs.LxdCHook{
+							{
+								Event:    specs.HookPostNodeSync,
+								Node:     "node1",
+								Commands: []string{"check-service"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("Upgrade", func() {
+
+	var executor *fakeExecutor
+	var instance *LxdCInstance
+	var proj *specs.LxdCProject
+	var group *specs.LxdCGroup
+	var node *specs.LxdCNode
+
+	setup := func(strategy string) {
+		executor = newFakeExecutor()
+		executor.AddInstance("node1", true)
+		instance = newTestInstance(newUpgradeProject(strategy), executor)
+		proj = &instance.Environments[0].Projects[0]
+		group = &proj.Groups[0]
+		node = &group.Nodes[0]
+	}
+
+	applyNode := func() error {
+		env := &instance.Environments[0]
+		compiler, err := template.NewProjectTemplateCompiler(env, proj)
+		Expect(err).ToNot(HaveOccurred())
+
+		return instance.applyNodeSteps(context.Background(), node, group, proj,
+			compiler, executor, []string{"default"}, GinkgoT().TempDir())
+	}
+
+	Context("backupInstance", func() {
+
+		It("Copy the instance", func() {
+			setup(specs.UpgradeStrategySnapshot)
+
+			backupName, err := instance.backupInstance(proj, group, node, executor)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(backupName).To(Equal("node1-lxdc-upgrade"))
+			Expect(executor.Instances).To(HaveKey("node1-lxdc-upgrade"))
+			Expect(executor.Instances["node1"].Running).To(BeTrue())
+		})
+
+		It("Fails if a previous copy is present", func() {
+			setup(specs.UpgradeStrategySnapshot)
+			executor.AddInstance("node1-lxdc-upgrade", false)
+
+			_, err := instance.backupInstance(proj, group, node, executor)
+			Expect(err).To(HaveOccurred())
+			Expect(executor.Calls).To(BeEmpty())
+		})
+	})
+
+	Context("rollbackInstance", func() {
+
+		It("Restore and start the instance", func() {
+			setup(specs.UpgradeStrategySnapshot)
+			executor.AddInstance("node1-lxdc-upgrade", false)
+			executor.Instances["node1-lxdc-upgrade"].Config["user.version"] = "1"
+
+			err := instance.rollbackInstance(proj, group, node, executor,
+				"node1-lxdc-upgrade", true, errors.New("upgrade error"))
+			Expect(err).To(MatchError(ContainSubstring("rollback done")))
+			Expect(executor.Instances).ToNot(HaveKey("node1-lxdc-upgrade"))
+			Expect(executor.Instances["node1"].Running).To(BeTrue())
+			Expect(executor.Instances["node1"].Config["user.version"]).To(Equal("1"))
+		})
+
+		It("Keep stopped an instance not running before the upgrade", func() {
+			setup(specs.UpgradeStrategySnapshot)
+			delete(executor.Instances, "node1")
+			executor.AddInstance("node1-lxdc-upgrade", false)
+
+			err := instance.rollbackInstance(proj, group, node, executor,
+				"node1-lxdc-upgrade", false, errors.New("upgrade error"))
+			Expect(err).To(MatchError(ContainSubstring("rollback done")))
+			Expect(executor.Instances).To(HaveKey("node1"))
+			Expect(executor.Instances["node1"].Running).To(BeFalse())
+		})
+
+		It("Keep the copy when the rollback fails", func() {
+			setup(specs.UpgradeStrategySnapshot)
+			executor.AddInstance("node1-lxdc-upgrade", false)
+			executor.Fails["CopyContainerOnInstance"] = errors.New("copy error")
+
+			err := instance.rollbackInstance(proj, group, node, executor,
+				"node1-lxdc-upgrade", true, errors.New("upgrade error"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("node1-lxdc-upgrade"))
+			Expect(executor.Instances).To(HaveKey("node1-lxdc-upgrade"))
+		})
+	})
+
+	Context("applyNodeSteps", func() {
+
+		It("Rollback the node when the post-node-sync hooks fail", func() {
+			setup(specs.UpgradeStrategySnapshot)
+			executor.Instances["node1"].Config["user.version"] = "1"
+			executor.FailCmds["check-service"] = true
+			instance.Upgrade = true
+
+			err := applyNode()
+			Expect(err).To(HaveOccurred())
+			Expect(executor.Commands).To(Equal([]string{"node1 check-service"}))
+			Expect(executor.Instances).ToNot(HaveKey("node1-lxdc-upgrade"))
+			Expect(executor.Instances["node1"].Running).To(BeTrue())
+			Expect(executor.Instances["node1"].Config["user.version"]).To(Equal("1"))
+		})
+
+		It("Remove the copy when all the steps are completed", func() {
+			setup(specs.UpgradeStrategySnapshot)
+			executor.Instances["node1"].Config["user.version"] = "1"
+			instance.Upgrade = true
+
+			err := applyNode()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(executor.Instances).ToNot(HaveKey("node1-lxdc-upgrade"))
+			Expect(executor.Instances["node1"].Running).To(BeTrue())
+			// The instance is recreated.
+			Expect(executor.Instances["node1"].Config).ToNot(HaveKey("user.version"))
+			Expect(executor.Calls).To(ContainElement("DeleteContainer node1-lxdc-upgrade"))
+		})
+
+		It("Doesn't copy the instance with the recreate strategy", func() {
+			setup(specs.UpgradeStrategyRecreate)
+			executor.FailCmds["check-service"] = true
+			instance.Upgrade = true
+
+			err := applyNode()
+			Expect(err).To(HaveOccurred())
+			Expect(executor.Calls).ToNot(ContainElement(
+				"CopyContainerOnInstance node1 node1-lxdc-upgrade"))
+		})
+	})
+})
@@ -0,0 +1,125 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+
+	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
+
+	"github.com/icza/dyno"
+)
+
+// Return the variables available to the when expression of the hooks
+// executed on the input node: the vars of the project, included the
+// out2var/err2var results, and the node with its labels.
+func (i *LxdCInstance) GetHookWhenVars(proj *specs.LxdCProject, node string) (map[string]interface{}, error) {
+	ans := make(map[string]interface{}, 0)
+
+	i.varsMutex.Lock()
+	for _, e := range proj.Environments {
+		for k, v := range e.EnvVars {
+			ans[k] = dyno.ConvertMapI2MapS(v)
+		}
+	}
+	i.varsMutex.Unlock()
+
+	nodeMap := map[string]interface{}{
+		"name": node,
+	}
+
+	if node != "host" {
+		_, _, _, nodeEntity := i.GetEntitiesByNodeName(node)
+		if nodeEntity == nil && i.NodesPrefix != "" {
+			_, _, _, nodeEntity = i.GetEntitiesByNodeName(
+				fmt.Sprintf("%s-%s", i.NodesPrefix, node))
+		}
+
+		if nodeEntity != nil {
+			data, err := nodeEntity.ToJson()
+			if err != nil {
+				return ans, err
+			}
+			err = json.Unmarshal([]byte(data), &nodeMap)
+			if err != nil {
+				return ans, fmt.Errorf("error on convert node %s: %s",
+					node, err.Error())
+			}
+		}
+	}
+
+	// The labels are always available to avoid errors on
+	// nodes without labels.
+	if _, ok := nodeMap["labels"]; !ok {
+		nodeMap["labels"] = map[string]interface{}{}
+	}
+	ans["node"] = nodeMap
+
+	return ans, nil
+}
+
+func (i *LxdCInstance) evalHookWhen(h *specs.LxdCHook, proj *specs.LxdCProject, node string) (bool, error) {
+	if h.When == "" {
+		return true, nil
+	}
+
+	vars, err := i.GetHookWhenVars(proj, node)
+	if err != nil {
+		return false, err
+	}
+
+	return h.EvalWhen(vars)
+}
+
+// Evaluate the when expression of all the hooks of the project
+// for every node where the hooks are executed.
+func (i *LxdCInstance) DiagnoseHooksWhen(proj *specs.LxdCProject) []specs.LxdCHookWhenStatus {
+	ans := []specs.LxdCHookWhenStatus{}
+
+	addHooks := func(hooks []specs.LxdCHook, group *specs.LxdCGroup, node *specs.LxdCNode) {
+		groupName := ""
+		if group != nil {
+			groupName = group.Name
+		}
+
+		var lastHook *specs.LxdCHook = nil
+		lastNode := ""
+		for _, hc := range i.getHooksCommands(&hooks, proj, group, node) {
+			// Every command of the hook is evaluated only one time.
+			if hc.Hook == lastHook && hc.Node == lastNode {
+				continue
+			}
+			lastHook, lastNode = hc.Hook, hc.Node
+
+			status := specs.LxdCHookWhenStatus{
+				Event: hc.Hook.Event,
+				Group: groupName,
+				Node:  hc.Node,
+				When:  hc.Hook.When,
+			}
+			res, err := i.evalHookWhen(hc.Hook, proj, hc.Node)
+			if err != nil {
+				status.Error = err.Error()
+			} else {
+				status.Result = res
+			}
+
+			ans = append(ans, status)
+		}
+	}
+
+	addHooks(proj.Hooks, nil, nil)
+	for gidx := range proj.Groups {
+		grp := &proj.Groups[gidx]
+		addHooks(grp.Hooks, grp, nil)
+
+		for nidx := range grp.Nodes {
+			addHooks(grp.Nodes[nidx].Hooks, grp, &grp.Nodes[nidx])
+		}
+	}
+
+	return ans
+}
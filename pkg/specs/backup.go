@@ -0,0 +1,171 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+const (
+	BackupManifestFile = "manifest.yaml"
+	// Format of the directories of the exported backups.
+	BackupExportDateFormat = "20060102-150405"
+	// Format used in the name of the backup copies.
+	BackupCopyDateFormat = "20060102"
+	// Compression algorithm used when it isn't defined. The algorithm
+	// is always passed to the server to avoid a different default.
+	BackupDefaultCompression = "gzip"
+)
+
+type LxdCBackupManifest struct {
+	Project   string               `json:"project" yaml:"project"`
+	CreatedAt time.Time            `json:"created_at" yaml:"created_at"`
+	Optimized bool                 `json:"optimized,omitempty" yaml:"optimized,omitempty"`
+	Nodes     []LxdCBackupNodeInfo `json:"nodes" yaml:"nodes"`
+}
+
+type LxdCBackupNodeInfo struct {
+	Name           string   `json:"name" yaml:"name"`
+	Group          string   `json:"group" yaml:"group"`
+	Connection     string   `json:"connection,omitempty" yaml:"connection,omitempty"`
+	ConnectionType string   `json:"connection_type,omitempty" yaml:"connection_type,omitempty"`
+	File           string   `json:"file" yaml:"file"`
+	Spec           LxdCNode `json:"spec" yaml:"spec"`
+}
+
+// Define the backups to keep. The backups that match at least one
+// rule are maintained.
+type LxdCBackupRetention struct {
+	// Number of the last backups to keep.
+	KeepLast int
+	// Number of days with the last backup of the day to keep.
+	KeepDaily int
+	// Number of weeks with the last backup of the week to keep.
+	KeepWeekly int
+}
+
+func BackupManifestFromFile(file string) (*LxdCBackupManifest, error) {
+	ans := &LxdCBackupManifest{}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(data, ans)
+	if err != nil {
+		return nil, fmt.Errorf("error on parse manifest %s: %s", file, err.Error())
+	}
+
+	return ans, nil
+}
+
+func (m *LxdCBackupManifest) Write(file string) error {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, data, 0644)
+}
+
+func (m *LxdCBackupManifest) GetNode(name string) *LxdCBackupNodeInfo {
+	for idx := range m.Nodes {
+		if m.Nodes[idx].Name == name {
+			return &m.Nodes[idx]
+		}
+	}
+	return nil
+}
+
+// Return the name of the tarball of the node based on the
+// compression algorithm used.
+func GetBackupFileName(node, compression string) string {
+	switch compression {
+	case "gzip":
+		return node + ".tar.gz"
+	case "none":
+		return node + ".tar"
+	default:
+		return node + ".tar." + compression
+	}
+}
+
+func (r *LxdCBackupRetention) IsEnabled() bool {
+	return r.KeepLast > 0 || r.KeepDaily > 0 || r.KeepWeekly > 0
+}
+
+func parseBackupDate(date string) (time.Time, error) {
+	if len(date) == len(BackupCopyDateFormat) {
+		return time.Parse(BackupCopyDateFormat, date)
+	}
+	return time.Parse(BackupExportDateFormat, date)
+}
+
+// Return the dates of the backups to remove. The dates are in the
+// format used by the backup copies or by the exported backups.
+// The dates not valid are ignored.
+func (r *LxdCBackupRetention) GetBackupsToRemove(dates []string) []string {
+	ans := []string{}
+
+	if !r.IsEnabled() {
+		return ans
+	}
+
+	type backupDate struct {
+		Value string
+		Time  time.Time
+	}
+
+	backups := []backupDate{}
+	for _, d := range dates {
+		t, err := parseBackupDate(d)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupDate{Value: d, Time: t})
+	}
+
+	// From the newest to the oldest
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+
+	keep := make(map[string]bool, 0)
+	days := make(map[string]bool, 0)
+	weeks := make(map[string]bool, 0)
+
+	for idx, b := range backups {
+		if idx < r.KeepLast {
+			keep[b.Value] = true
+		}
+
+		day := b.Time.Format("20060102")
+		if _, ok := days[day]; !ok && len(days) < r.KeepDaily {
+			days[day] = true
+			keep[b.Value] = true
+		}
+
+		year, week := b.Time.ISOWeek()
+		wkey := fmt.Sprintf("%d-%02d", year, week)
+		if _, ok := weeks[wkey]; !ok && len(weeks) < r.KeepWeekly {
+			weeks[wkey] = true
+			keep[b.Value] = true
+		}
+	}
+
+	for _, b := range backups {
+		if !keep[b.Value] {
+			ans = append(ans, b.Value)
+		}
+	}
+
+	return ans
+}
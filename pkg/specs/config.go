@@ -44,6 +44,10 @@ type LxdCGeneral struct {
 	LegacyApi       bool   `mapstructure:"legacyapi,omitempty" json:"legacyapi,omitempty" yaml:"legacyapi,omitempty"`
 
 	Concurrency int `mapstructure:"concurrency,omitempty" json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+
+	// Directory where are stored the journals of the apply. A relative
+	// path is resolved from the directory of the environment file.
+	StateDir string `mapstructure:"state_dir,omitempty" json:"state_dir,omitempty" yaml:"state_dir,omitempty"`
 }
 
 type LxdCSecurity struct {
@@ -107,6 +111,7 @@ func (c *LxdComposeConfig) Clone() *LxdComposeConfig {
 	ans.General.LxdLocalDisable = c.General.LxdLocalDisable
 	ans.General.P2PMode = c.General.P2PMode
 	ans.General.Concurrency = c.General.Concurrency
+	ans.General.StateDir = c.General.StateDir
 
 	ans.Logging.Path = c.Logging.Path
 	ans.Logging.EnableLogFile = c.Logging.EnableLogFile
@@ -281,6 +286,7 @@ func GenDefault(viper *v.Viper) {
 	viper.SetDefault("general.lxd_local_disable", false)
 	viper.SetDefault("general.lxd_confdir", "")
 	viper.SetDefault("general.concurrency", runtime.NumCPU())
+	viper.SetDefault("general.state_dir", "./.lxd-compose-state")
 	viper.SetDefault("render_default_file", "")
 	viper.SetDefault("render_values_file", "")
 	viper.SetDefault("render_secret_file", "")
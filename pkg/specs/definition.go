@@ -26,6 +26,16 @@ import (
 const (
 	ConnectionLxd6  = "lxd-6"
 	ConnectionIncus = "incus"
+
+	UpgradeStrategyRecreate = "recreate"
+	UpgradeStrategySnapshot = "snapshot"
+
+	NodeTypeContainer      = "container"
+	NodeTypeVirtualMachine = "virtual-machine"
+
+	// Default timeout in seconds used to wait the agent of the
+	// virtual machines when wait_ip is not defined.
+	DefaultAgentTimeout = 300
 )
 
 type LxdCEnvironment struct {
@@ -225,6 +235,50 @@ type LxdCHook struct {
 	Uid        *uint32  `json:"uid,omitempty" yaml:"uid,omitempty"`
 	Gid        *uint32  `json:"gid,omitempty" yaml:"gid,omitempty"`
 	Cwd        string   `json:"cwd,omitempty" yaml:"cwd,omitempty"`
+
+	// Number of retries of the commands on failure.
+	Retries int `json:"retries,omitempty" yaml:"retries,omitempty"`
+	// Seconds to wait between the retries.
+	RetryDelay int `json:"retry_delay,omitempty" yaml:"retry_delay,omitempty"`
+	// Max seconds of execution of every command.
+	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Policy to apply on failure: abort (default), continue, warn.
+	OnFailure string `json:"on_failure,omitempty" yaml:"on_failure,omitempty"`
+	// Expression evaluated with the project vars and the node
+	// to decide if the hook must be executed.
+	When string `json:"when,omitempty" yaml:"when,omitempty"`
+	// Typed actions executed before the commands.
+	Actions []LxdCHookAction `json:"actions,omitempty" yaml:"actions,omitempty"`
+	// Format used to parse the output stored with out2var:
+	// raw (default), json, yaml, lines.
+	Out2VarFormat string `json:"out2var_format,omitempty" yaml:"out2var_format,omitempty"`
+	// Scope of the out2var/err2var vars: project (default) or node.
+	// With the node scope the vars are stored as nodes.<name>.<var>.
+	VarsScope string `json:"vars_scope,omitempty" yaml:"vars_scope,omitempty"`
+	// Execute the hook on the nodes in parallel.
+	Parallel    bool `json:"parallel,omitempty" yaml:"parallel,omitempty"`
+	MaxParallel int  `json:"max_parallel,omitempty" yaml:"max_parallel,omitempty"`
+}
+
+type LxdCHookAction struct {
+	// Type of the action: push, pull, template, wait, restart.
+	Type        string `json:"type" yaml:"type"`
+	Source      string `json:"source,omitempty" yaml:"source,omitempty"`
+	Destination string `json:"dst,omitempty" yaml:"dst,omitempty"`
+	// Probes of the wait action. Without probes are used
+	// the readiness probes of the node.
+	Readiness *LxdCReadiness `json:"readiness,omitempty" yaml:"readiness,omitempty"`
+}
+
+// Result of the evaluation of the when expression of an hook
+// on a specific node.
+type LxdCHookWhenStatus struct {
+	Event  string `json:"event" yaml:"event"`
+	Group  string `json:"group,omitempty" yaml:"group,omitempty"`
+	Node   string `json:"node" yaml:"node"`
+	When   string `json:"when,omitempty" yaml:"when,omitempty"`
+	Result bool   `json:"result" yaml:"result"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
 }
 
 type LxdCHooks struct {
@@ -278,9 +332,28 @@ type LxdCGroup struct {
 
 	CommonProfiles []string          `json:"common_profiles,omitempty" yaml:"common_profiles,omitempty"`
 	Config         map[string]string `json:"config,omitempty" yaml:"config,omitempty"`
+	// Devices added to the instances of the nodes of the group
+	// with the same schema of the devices of the profiles.
+	Devices map[string]map[string]string `json:"devices,omitempty" yaml:"devices,omitempty"`
 
 	Ephemeral bool `json:"ephemeral,omitempty" yaml:"ephemeral,omitempty"`
 
+	// Enable the provisioning of the nodes of the group in parallel.
+	Parallel bool `json:"parallel,omitempty" yaml:"parallel,omitempty"`
+	// Define the max number of nodes processed in parallel. If it's not
+	// set is used the general.concurrency option.
+	MaxParallel int `json:"max_parallel,omitempty" yaml:"max_parallel,omitempty"`
+
+	// List of the groups of the project that must be applied
+	// before this group.
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+
+	// Define the strategy used on upgrade the nodes: recreate|snapshot.
+	UpgradeStrategy string `json:"upgrade_strategy,omitempty" yaml:"upgrade_strategy,omitempty"`
+	// Upgrade the nodes in batches checking the health of the nodes
+	// between the batches.
+	RollingUpgrade *LxdCRollingUpgrade `json:"rolling_upgrade,omitempty" yaml:"rolling_upgrade,omitempty"`
+
 	Nodes       []LxdCNode `json:"nodes" yaml:"nodes"`
 	NodesPrefix string     `json:"nodes_prefix,omitempty" yaml:"nodes_prefix,omitempty"`
 
@@ -289,6 +362,27 @@ type LxdCGroup struct {
 	ConfigTemplates   []LxdCConfigTemplate `json:"config_templates,omitempty" yaml:"config_templates,omitempty"`
 }
 
+type LxdCRollingUpgrade struct {
+	// Max number of nodes upgraded at the same time. Default 1.
+	MaxUnavailable int `json:"max_unavailable,omitempty" yaml:"max_unavailable,omitempty"`
+	// Seconds to wait between two batches.
+	PauseBetween int `json:"pause_between,omitempty" yaml:"pause_between,omitempty"`
+
+	// Command executed in the nodes of the batch after the upgrade.
+	// The node is healthy if the command exits with 0.
+	HealthCheck string `json:"health_check,omitempty" yaml:"health_check,omitempty"`
+	// Number of attempts of the health check. Default 1.
+	HealthCheckRetries int `json:"health_check_retries,omitempty" yaml:"health_check_retries,omitempty"`
+	// Seconds to wait between two attempts of the health check.
+	HealthCheckDelay int `json:"health_check_delay,omitempty" yaml:"health_check_delay,omitempty"`
+	// Seconds after that an attempt of the health check is stopped.
+	// Default 30.
+	HealthCheckTimeout int `json:"health_check_timeout,omitempty" yaml:"health_check_timeout,omitempty"`
+
+	// Number of failed nodes admitted before abort the rollout.
+	FailureBudget int `json:"failure_budget,omitempty" yaml:"failure_budget,omitempty"`
+}
+
 type LxdCEnvVars struct {
 	EnvVars          map[string]interface{} `json:"envs,omitempty" yaml:"envs,omitempty"`
 	Encrypted        bool                   `json:"encrypted,omitempty" yaml:"encrypted,omitempty"`
@@ -300,9 +394,26 @@ type LxdCNode struct {
 	NamePrefix        string `json:"name_prefix,omitempty" yaml:"name_prefix,omitempty"`
 	ImageSource       string `json:"image_source" yaml:"image_source"`
 	ImageRemoteServer string `json:"image_remote_server,omitempty" yaml:"image_remote_server,omitempty"`
+	// Type of the instance: container (default) or virtual-machine.
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
 
 	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
 	Config map[string]string `json:"config,omitempty" yaml:"config,omitempty"`
+	// Devices of the instance merged with the devices of the group.
+	// A device of the node overrides the device of the group with the
+	// same name. On reconcile only the devices previously set by
+	// lxd-compose and no more defined are removed.
+	Devices map[string]map[string]string `json:"devices,omitempty" yaml:"devices,omitempty"`
+
+	// Expand the node on load in the defined number of nodes.
+	Replicas int `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	// Expand the node on load in a node for every combination
+	// of the values. The values are available as labels.
+	Matrix map[string][]string `json:"matrix,omitempty" yaml:"matrix,omitempty"`
+
+	// List of the nodes of the same group that must be applied
+	// before this node.
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
 
 	SourceDir string `json:"source_dir,omitempty" yaml:"source_dir,omitempty"`
 
@@ -310,6 +421,7 @@ type LxdCNode struct {
 
 	ConfigTemplates []LxdCConfigTemplate `json:"config_templates,omitempty" yaml:"config_templates,omitempty"`
 	SyncResources   []LxdCSyncResource   `json:"sync_resources,omitempty" yaml:"sync_resources,omitempty"`
+	FetchResources  []LxdCFetchResource  `json:"fetch_resources,omitempty" yaml:"fetch_resources,omitempty"`
 	Profiles        []string             `json:"profiles,omitempty" yaml:"profiles,omitempty"`
 
 	Hooks             []LxdCHook `json:"hooks" yaml:"hooks"`
@@ -318,16 +430,90 @@ type LxdCNode struct {
 	// Wait ip address before execute post-node-creation hooks for the timeout
 	// in seconds defined. A value 0 means skip waiting.
 	WaitIp int64 `json:"wait_ip,omitempty" yaml:"wait_ip,omitempty"`
+
+	// Override the upgrade strategy of the group.
+	UpgradeStrategy string `json:"upgrade_strategy,omitempty" yaml:"upgrade_strategy,omitempty"`
+
+	// Probes to check before execute the post-node-sync hooks.
+	Readiness *LxdCReadiness `json:"readiness,omitempty" yaml:"readiness,omitempty"`
+}
+
+type LxdCReadiness struct {
+	// Seconds to wait between two checks. Default 2.
+	Interval int `json:"interval,omitempty" yaml:"interval,omitempty"`
+	// Timeout in seconds of every probe. Default 5.
+	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Max number of checks before fail. Default 30.
+	Retries int `json:"retries,omitempty" yaml:"retries,omitempty"`
+
+	Probes []LxdCProbe `json:"probes,omitempty" yaml:"probes,omitempty"`
+}
+
+type LxdCProbe struct {
+	// Command executed inside the node. The probe is ready
+	// when the command exits with 0.
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+	// TCP port of the node IP address to connect.
+	TcpPort int `json:"tcp_port,omitempty" yaml:"tcp_port,omitempty"`
+	// HTTP port of the node IP address to call. The probe is ready
+	// with a 2xx/3xx status code.
+	HttpPort   int    `json:"http_port,omitempty" yaml:"http_port,omitempty"`
+	HttpPath   string `json:"http_path,omitempty" yaml:"http_path,omitempty"`
+	HttpScheme string `json:"http_scheme,omitempty" yaml:"http_scheme,omitempty"`
+}
+
+type LxdCProbeStatus struct {
+	Node    string `json:"node" yaml:"node"`
+	Probe   string `json:"probe" yaml:"probe"`
+	Ready   bool   `json:"ready" yaml:"ready"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
 }
 
 type LxdCConfigTemplate struct {
 	Source      string `json:"source" yaml:"source"`
 	Destination string `json:"dst" yaml:"dst"`
+	// Compile the template in memory and push it to the dst path
+	// of the node. Supported only on the templates of the nodes and
+	// not with the jinja2 engine.
+	Push bool `json:"push,omitempty" yaml:"push,omitempty"`
+
+	// The uid and gid are supported only with push. The filters
+	// are not supported.
+	LxdCFileOpts `json:",inline" yaml:",inline"`
+}
+
+// Ownership, permissions and filters of the files pushed to the nodes.
+type LxdCFileOpts struct {
+	Uid *int64 `json:"uid,omitempty" yaml:"uid,omitempty"`
+	Gid *int64 `json:"gid,omitempty" yaml:"gid,omitempty"`
+	// Octal permissions of the files and of the directories.
+	Mode    string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	DirMode string `json:"dir_mode,omitempty" yaml:"dir_mode,omitempty"`
+	// Glob patterns matched with the relative path or the name
+	// of the files.
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
 }
 
 type LxdCSyncResource struct {
 	Source      string `json:"source" yaml:"source"`
 	Destination string `json:"dst" yaml:"dst"`
+
+	LxdCFileOpts `json:",inline" yaml:",inline"`
+
+	// Push only the files changed compared with the files
+	// of the node.
+	Incremental bool `json:"incremental,omitempty" yaml:"incremental,omitempty"`
+	// Remove the files of the destination not present in
+	// the source. It enables the incremental mode.
+	Delete bool `json:"delete,omitempty" yaml:"delete,omitempty"`
+}
+
+// A file or directory of the node to copy on the host. The
+// destination is rendered with the template engine of the project.
+type LxdCFetchResource struct {
+	Source      string `json:"source" yaml:"source"`
+	Destination string `json:"dst" yaml:"dst"`
 }
 
 type LxdCCommand struct {
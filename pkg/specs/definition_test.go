@@ -20,6 +20,11 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package specs_test
 
 import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
 	. "github.com/MottainaiCI/lxd-compose/pkg/specs"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -57,6 +62,966 @@ template_engine:
 
 	})
 
+	Context("Journal", func() {
+
+		It("Restart from step", func() {
+			dir, err := os.MkdirTemp("", "lxd-compose-journal")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(dir)
+
+			file := filepath.Join(dir, "proj1.journal.json")
+			j := NewLxdCJournal("proj1", file)
+			Expect(j.AddStep("grp1", "", HookPreGroup)).Should(BeNil())
+			Expect(j.AddStep("grp1", "node1", HookPreNodeSync)).Should(BeNil())
+			Expect(j.AddStep("grp1", "node1", JournalStepSync)).Should(BeNil())
+			Expect(j.AddStep("grp1", "node1", HookPostNodeSync)).Should(BeNil())
+
+			j2, err := LoadLxdCJournal("proj1", file)
+			Expect(err).Should(BeNil())
+			Expect(len(j2.Steps)).To(Equal(4))
+			Expect(j2.IsDone("grp1", "node1", JournalStepSync)).To(BeTrue())
+
+			node, step, err := ParseJournalRestartFrom("node1/sync")
+			Expect(err).Should(BeNil())
+			Expect(j2.RestartFrom(node, step)).To(BeTrue())
+			Expect(len(j2.Steps)).To(Equal(2))
+			Expect(j2.IsDone("grp1", "node1", JournalStepSync)).To(BeFalse())
+			Expect(j2.IsDone("grp1", "node1", HookPreNodeSync)).To(BeTrue())
+		})
+
+		It("Restart from step with parallel nodes", func() {
+			dir, err := os.MkdirTemp("", "lxd-compose-journal")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(dir)
+
+			j := NewLxdCJournal("proj1", filepath.Join(dir, "proj1.journal.json"))
+			Expect(j.AddStep("grp1", "", HookPreGroup)).Should(BeNil())
+			Expect(j.AddStep("grp1", "node1", HookPreNodeSync)).Should(BeNil())
+			Expect(j.AddStep("grp1", "node2", HookPreNodeSync)).Should(BeNil())
+			Expect(j.AddStep("grp1", "node1", JournalStepSync)).Should(BeNil())
+			Expect(j.AddStep("grp1", "node2", JournalStepSync)).Should(BeNil())
+			Expect(j.AddStep("grp2", "node3", JournalStepSync)).Should(BeNil())
+
+			Expect(j.RestartFrom("node1", HookPreNodeSync)).To(BeTrue())
+			Expect(len(j.Steps)).To(Equal(4))
+			Expect(j.IsDone("grp1", "node1", HookPreNodeSync)).To(BeFalse())
+			Expect(j.IsDone("grp1", "node1", JournalStepSync)).To(BeFalse())
+			Expect(j.IsDone("grp1", "node2", HookPreNodeSync)).To(BeTrue())
+			Expect(j.IsDone("grp1", "node2", JournalStepSync)).To(BeTrue())
+
+			Expect(j.RestartFrom("grp1", "")).To(BeTrue())
+			Expect(len(j.Steps)).To(Equal(1))
+			Expect(j.IsDone("grp2", "node3", JournalStepSync)).To(BeTrue())
+
+			Expect(j.RestartFrom("node4", "")).To(BeFalse())
+		})
+
+		It("Vars of the hooks", func() {
+			dir, err := os.MkdirTemp("", "lxd-compose-journal")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(dir)
+
+			file := filepath.Join(dir, "proj1.journal.json")
+			j := NewLxdCJournal("proj1", file)
+			Expect(j.AddVar("version", "", "1.0")).Should(BeNil())
+			Expect(j.AddVar("ip", "node1", "10.0.0.1")).Should(BeNil())
+
+			j2, err := LoadLxdCJournal("proj1", file)
+			Expect(err).Should(BeNil())
+			Expect(j2.Vars).To(Equal([]LxdCJournalVar{
+				{Name: "version", Value: "1.0"},
+				{Name: "ip", Node: "node1", Value: "10.0.0.1"},
+			}))
+
+			proj := &LxdCProject{Name: "proj1"}
+			for _, v := range j2.Vars {
+				proj.SetHookVar(v.Name, v.Value, v.Node)
+			}
+			envs, err := proj.GetEnvsMap()
+			Expect(err).Should(BeNil())
+			Expect(envs["version"]).To(Equal("1.0"))
+			Expect(envs["nodes"]).To(Equal(`{"node1":{"ip":"10.0.0.1"}}`))
+		})
+
+	})
+
+	Context("Status", func() {
+
+		It("Compare instance", func() {
+			status := &LxdCNodeStatus{Name: "node1"}
+			info := &LxdCInstanceInfo{
+				Name:     "node1",
+				Profiles: []string{"default", "net"},
+				Config: map[string]string{
+					"limits.cpu":              "2",
+					"security.nesting":        "true",
+					"user.role":               "db",
+					"user.old":                "1",
+					"user.user-data":          "#cloud-config",
+					"user.lxd-compose.config": "limits.cpu,security.nesting,user.old,user.role",
+					"volatile.base_image":     "abc",
+				},
+			}
+
+			status.CompareInstance(info, []string{"default", "net"},
+				map[string]string{
+					"limits.cpu": "4",
+					"user.role":  "db",
+				}, nil)
+
+			Expect(status.HasDrift()).To(BeTrue())
+			Expect(status.Drifts).To(Equal([]LxdCDrift{
+				{Field: "config.limits.cpu", Expected: "4", Current: "2"},
+				{Field: "config.security.nesting", Expected: "", Current: "true"},
+				{Field: "label.old", Expected: "", Current: "1"},
+			}))
+		})
+
+		It("Compare instance devices", func() {
+			status := &LxdCNodeStatus{Name: "node1"}
+			info := &LxdCInstanceInfo{
+				Name: "node1",
+				Config: map[string]string{
+					"user.lxd-compose.devices": "data,old",
+				},
+				Devices: map[string]map[string]string{
+					"data":  {"type": "disk", "path": "/data", "source": "/srv/data"},
+					"old":   {"type": "disk", "path": "/old", "source": "/srv/old"},
+					"debug": {"type": "disk", "path": "/debug", "source": "/srv/debug"},
+				},
+			}
+
+			status.CompareInstance(info, nil, map[string]string{},
+				map[string]map[string]string{
+					"data": {"type": "disk", "path": "/data", "source": "/srv/data2"},
+					"logs": {"type": "disk", "path": "/logs", "source": "/srv/logs"},
+				})
+
+			Expect(status.Drifts).To(Equal([]LxdCDrift{
+				{
+					Field:    "devices.data",
+					Expected: "path=/data,source=/srv/data2,type=disk",
+					Current:  "path=/data,source=/srv/data,type=disk",
+				},
+				{
+					Field:    "devices.logs",
+					Expected: "path=/logs,source=/srv/logs,type=disk",
+					Current:  "",
+				},
+				{
+					Field:    "devices.old",
+					Expected: "",
+					Current:  "path=/old,source=/srv/old,type=disk",
+				},
+			}))
+		})
+
+	})
+
+	Context("Hooks options", func() {
+
+		It("Defaults", func() {
+			h := &LxdCHook{Event: HookPostNodeSync}
+
+			Expect(h.GetOnFailure()).To(Equal(HookOnFailureAbort))
+			Expect(h.GetRetries()).To(Equal(0))
+			Expect(h.GetTimeout()).To(Equal(time.Duration(0)))
+			Expect(h.Validate()).Should(BeNil())
+		})
+
+		It("Validate", func() {
+			h := &LxdCHook{
+				Event:      HookPostNodeSync,
+				Retries:    3,
+				RetryDelay: 2,
+				Timeout:    30,
+				OnFailure:  HookOnFailureWarn,
+			}
+
+			Expect(h.GetRetryDelay()).To(Equal(2 * time.Second))
+			Expect(h.GetTimeout()).To(Equal(30 * time.Second))
+			Expect(h.Validate()).Should(BeNil())
+
+			h.OnFailure = "ignore"
+			Expect(h.Validate()).ShouldNot(BeNil())
+		})
+
+		It("When expression", func() {
+			h := &LxdCHook{
+				Event: HookPostNodeSync,
+				When:  `node.labels.role == "db" && env == "prod"`,
+			}
+			vars := map[string]interface{}{
+				"env": "prod",
+				"node": map[string]interface{}{
+					"labels": map[string]interface{}{"role": "db"},
+				},
+			}
+
+			Expect(h.Validate()).Should(BeNil())
+			res, err := h.EvalWhen(vars)
+			Expect(err).Should(BeNil())
+			Expect(res).To(BeTrue())
+
+			vars["env"] = "dev"
+			res, err = h.EvalWhen(vars)
+			Expect(err).Should(BeNil())
+			Expect(res).To(BeFalse())
+
+			h.When = "env"
+			_, err = h.EvalWhen(vars)
+			Expect(err).ShouldNot(BeNil())
+
+			h.When = "env =="
+			Expect(h.Validate()).ShouldNot(BeNil())
+		})
+
+		It("Actions", func() {
+			h := &LxdCHook{
+				Event: HookPostNodeSync,
+				Actions: []LxdCHookAction{
+					{Type: HookActionPush, Source: "files/", Destination: "/etc/app/"},
+					{Type: HookActionRestart},
+				},
+			}
+			Expect(h.Validate()).Should(BeNil())
+			Expect(h.Actions[0].String()).To(Equal("push files/ -> /etc/app/"))
+
+			h.Actions = append(h.Actions, LxdCHookAction{Type: HookActionPull})
+			Expect(h.Validate()).ShouldNot(BeNil())
+
+			h.Actions = []LxdCHookAction{{Type: "copy"}}
+			Expect(h.Validate()).ShouldNot(BeNil())
+
+			h.Actions = []LxdCHookAction{{Type: HookActionWait}}
+			h.Node = "host"
+			Expect(h.Validate()).ShouldNot(BeNil())
+		})
+
+		It("Parallel", func() {
+			h := &LxdCHook{Event: HookPostGroup}
+			Expect(h.GetMaxParallel(8)).To(Equal(1))
+
+			h.Parallel = true
+			Expect(h.GetMaxParallel(8)).To(Equal(8))
+
+			h.MaxParallel = 3
+			Expect(h.GetMaxParallel(8)).To(Equal(3))
+		})
+
+		It("Out2var formats", func() {
+			h := &LxdCHook{Event: HookPostNodeSync, Out2Var: "out"}
+
+			v, err := h.ParseOut2Var("a\n")
+			Expect(err).Should(BeNil())
+			Expect(v).To(Equal("a\n"))
+
+			h.Out2VarFormat = HookVarFormatLines
+			v, err = h.ParseOut2Var("a\n\nb\n")
+			Expect(err).Should(BeNil())
+			Expect(v).To(Equal([]string{"a", "b"}))
+
+			h.Out2VarFormat = HookVarFormatJson
+			v, err = h.ParseOut2Var(`{"port": "8080"}`)
+			Expect(err).Should(BeNil())
+			Expect(v).To(Equal(map[string]interface{}{"port": "8080"}))
+
+			h.Out2VarFormat = HookVarFormatYaml
+			v, err = h.ParseOut2Var("port: \"8080\"\n")
+			Expect(err).Should(BeNil())
+			Expect(v).To(Equal(map[string]interface{}{"port": "8080"}))
+
+			h.Out2VarFormat = "xml"
+			Expect(h.Validate()).ShouldNot(BeNil())
+		})
+
+		It("Node scoped vars", func() {
+			p := &LxdCProject{Name: "p1"}
+
+			p.SetHookVar("ip", "10.0.0.1", "node1")
+			p.SetHookVar("ip", "10.0.0.2", "node2")
+			p.SetHookVar("version", "1.0", "")
+
+			Expect(p.Environments[0].EnvVars).To(Equal(map[string]interface{}{
+				"version": "1.0",
+				"nodes": map[string]interface{}{
+					"node1": map[string]interface{}{"ip": "10.0.0.1"},
+					"node2": map[string]interface{}{"ip": "10.0.0.2"},
+				},
+			}))
+		})
+
+		It("Failure events", func() {
+			Expect(IsFailureHookEvent(HookOnNodeFailure)).To(BeTrue())
+			Expect(IsFailureHookEvent(HookOnProjectFailure)).To(BeTrue())
+			Expect(IsFailureHookEvent(HookPostGroup)).To(BeFalse())
+		})
+
+	})
+
+	Context("Incremental sync", func() {
+
+		It("Remote root", func() {
+			Expect(GetSyncRemoteRoot("files/", "/etc/app", true)).To(Equal("/etc/app"))
+			Expect(GetSyncRemoteRoot("files", "/etc/app", true)).To(Equal("/etc/app/files"))
+			Expect(GetSyncRemoteRoot("app.conf", "/etc/app/", false)).To(Equal("/etc/app/app.conf"))
+			Expect(GetSyncRemoteRoot("app.conf", "/etc/app.conf", false)).To(Equal("/etc/app.conf"))
+		})
+
+		It("Compare files", func() {
+			sha := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+			out := "d ./conf\n" +
+				"d ./old\n" +
+				"f 10 100 ./conf/a.conf\n" +
+				"f 5 100 ./conf/b.conf\n" +
+				"f 0 100 ./conf/c d.conf\n" +
+				"f 3 100 ./old/x\n"
+
+			remote, err := ParseSyncManifest(out)
+			Expect(err).Should(BeNil())
+			Expect(remote["conf/c d.conf"].Size).To(Equal(int64(0)))
+			Expect(remote["old"].Dir).To(BeTrue())
+
+			local := []*LxdCSyncFile{
+				{Path: "conf", Dir: true},
+				{Path: "conf/a.conf", Size: 10, Mtime: 50},
+				{Path: "conf/b.conf", Size: 6, Mtime: 50},
+				{Path: "conf/c d.conf", Size: 0, Mtime: 200},
+				{Path: "conf/new.conf", Size: 1, Mtime: 200},
+				{Path: "data", Dir: true},
+			}
+
+			// Only the files with the same size and a newer mtime
+			// are verified with the checksums.
+			verified := []string{}
+			summary, err := CompareSyncFiles(local, remote, true,
+				func(paths []string) (map[string]*LxdCSyncFile, error) {
+					verified = append(verified, paths...)
+					return ParseSyncManifest(sha + "  ./conf/c d.conf\n")
+				},
+				func(f *LxdCSyncFile) (string, error) {
+					verified = append(verified, "local:"+f.Path)
+					return sha, nil
+				})
+			Expect(err).Should(BeNil())
+			Expect(verified).To(Equal([]string{"conf/c d.conf", "local:conf/c d.conf"}))
+			Expect(summary.Added).To(Equal([]string{"conf/new.conf"}))
+			Expect(summary.Updated).To(Equal([]string{"conf/b.conf"}))
+			Expect(summary.Deleted).To(Equal([]string{"old"}))
+			Expect(summary.NewDirs).To(Equal([]string{"data"}))
+			Expect(summary.Unchanged).To(Equal(2))
+		})
+
+		It("Update the newer files without remote checksum", func() {
+			remote, err := ParseSyncManifest("f 4 100 ./a.conf\nf 4 100 ./b.conf\n")
+			Expect(err).Should(BeNil())
+
+			local := []*LxdCSyncFile{
+				{Path: "a.conf", Size: 4, Mtime: 200},
+				{Path: "b.conf", Size: 4, Mtime: 200},
+			}
+
+			summary, err := CompareSyncFiles(local, remote, false,
+				func(paths []string) (map[string]*LxdCSyncFile, error) {
+					return ParseSyncManifest(
+						"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa  ./b.conf\n")
+				},
+				func(f *LxdCSyncFile) (string, error) {
+					return "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", nil
+				})
+			Expect(err).Should(BeNil())
+			Expect(summary.Updated).To(Equal([]string{"a.conf", "b.conf"}))
+			Expect(summary.Unchanged).To(Equal(0))
+
+			summary, err = CompareSyncFiles(local, remote, false, nil, nil)
+			Expect(err).Should(BeNil())
+			Expect(summary.Updated).To(Equal([]string{"a.conf", "b.conf"}))
+		})
+
+	})
+
+	Context("File options", func() {
+
+		It("Mode and filters", func() {
+			opts := &LxdCFileOpts{
+				Mode:    "0640",
+				Exclude: []string{"*.log", "cache"},
+				Include: []string{"*.conf"},
+			}
+			Expect(opts.Validate()).Should(BeNil())
+
+			mode, err := opts.GetMode()
+			Expect(err).Should(BeNil())
+			Expect(*mode).To(Equal(os.FileMode(0640)))
+
+			dirMode, err := opts.GetDirMode()
+			Expect(err).Should(BeNil())
+			Expect(dirMode).To(BeNil())
+
+			Expect(opts.IsToPush("etc/app.conf", false)).To(BeTrue())
+			Expect(opts.IsToPush("etc/app.yml", false)).To(BeFalse())
+			Expect(opts.IsToPush("etc", true)).To(BeTrue())
+			Expect(opts.IsToPush("var/cache", true)).To(BeFalse())
+			Expect(opts.IsToPush("var/app.log", false)).To(BeFalse())
+
+			var empty *LxdCFileOpts
+			Expect(empty.IsToPush("any", false)).To(BeTrue())
+		})
+
+		It("Config templates", func() {
+			uid := int64(1000)
+
+			ct := &LxdCConfigTemplate{Source: "app.tmpl", Destination: "app.conf"}
+			Expect(ct.Validate(false)).Should(BeNil())
+
+			ct.Mode = "0640"
+			Expect(ct.Validate(false)).Should(BeNil())
+
+			ct.Uid = &uid
+			Expect(ct.Validate(true)).ShouldNot(BeNil())
+
+			ct.Push = true
+			Expect(ct.Validate(true)).ShouldNot(BeNil())
+			Expect(ct.Validate(false)).ShouldNot(BeNil())
+
+			ct.Destination = "/etc/app.conf"
+			Expect(ct.Validate(true)).Should(BeNil())
+
+			ct.Exclude = []string{"*.log"}
+			Expect(ct.Validate(true)).ShouldNot(BeNil())
+
+			ct.Exclude = []string{}
+			ct.Mode = "999"
+			Expect(ct.Validate(true)).ShouldNot(BeNil())
+		})
+
+		It("Fetch resources", func() {
+			f := &LxdCFetchResource{Source: "/var/log/app", Destination: "logs/"}
+			Expect(f.Validate()).Should(BeNil())
+
+			f.Source = "var/log/app"
+			Expect(f.Validate()).ShouldNot(BeNil())
+
+			f.Source = "/var/log/app"
+			f.Destination = ""
+			Expect(f.Validate()).ShouldNot(BeNil())
+		})
+
+		It("Invalid mode", func() {
+			opts := &LxdCFileOpts{DirMode: "rwx"}
+			Expect(opts.Validate()).ShouldNot(BeNil())
+		})
+
+	})
+
+	Context("Nodes expansion", func() {
+
+		It("Replicas and matrix", func() {
+			proj := &LxdCProject{
+				Name: "project1",
+				Hooks: []LxdCHook{
+					{Event: HookPostNodeSync, Node: "web", Commands: []string{"echo 1"}},
+					{Event: HookPostNodeSync, Node: "db", Commands: []string{"echo 2"}},
+				},
+				Groups: []LxdCGroup{
+					{
+						Name: "group1",
+						Nodes: []LxdCNode{
+							{
+								Name:     "web",
+								Replicas: 2,
+								Matrix: map[string][]string{
+									"python": {"3.11", "3.12"},
+								},
+								Labels: map[string]string{"role": "web"},
+							},
+							{
+								Name:      "db",
+								DependsOn: []string{"web"},
+							},
+						},
+					},
+				},
+			}
+
+			err := proj.ExpandNodes()
+			Expect(err).Should(BeNil())
+
+			nodes := proj.Groups[0].Nodes
+			Expect(len(nodes)).To(Equal(5))
+			Expect(nodes[0].Name).To(Equal("web-3-11-1"))
+			Expect(nodes[0].Labels).To(Equal(map[string]string{
+				"role": "web", "python": "3.11", "replica": "1", "index": "1",
+			}))
+			Expect(nodes[3].Name).To(Equal("web-3-12-2"))
+			Expect(nodes[3].Labels["index"]).To(Equal("4"))
+			Expect(nodes[3].Replicas).To(Equal(0))
+			Expect(nodes[4].DependsOn).To(Equal([]string{
+				"web-3-11-1", "web-3-11-2", "web-3-12-1", "web-3-12-2",
+			}))
+
+			Expect(len(proj.Hooks)).To(Equal(5))
+			Expect(proj.Hooks[1].Node).To(Equal("web-3-11-2"))
+			Expect(proj.Hooks[4].Node).To(Equal("db"))
+
+			proj.SetNodesPrefix("prod")
+			Expect(proj.Groups[0].Nodes[2].GetName()).To(Equal("prod-web-3-12-1"))
+		})
+
+		It("Same node name on multiple groups", func() {
+			proj := &LxdCProject{
+				Name: "project1",
+				Hooks: []LxdCHook{
+					{Event: HookPostNodeSync, Node: "web", Commands: []string{"echo 1"}},
+				},
+				Groups: []LxdCGroup{
+					{
+						Name: "group1",
+						Hooks: []LxdCHook{
+							{Event: HookPostNodeSync, Node: "web", Commands: []string{"echo 2"}},
+						},
+						Nodes: []LxdCNode{
+							{
+								Name:     "web",
+								Replicas: 2,
+								Hooks: []LxdCHook{
+									{Event: HookPostNodeSync, Node: "web", Commands: []string{"echo 3"}},
+									{Event: HookPostNodeSync, Commands: []string{"echo 4"}},
+								},
+							},
+							{
+								Name: "db",
+								Hooks: []LxdCHook{
+									{Event: HookPostNodeSync, Node: "web", Commands: []string{"echo 5"}},
+								},
+							},
+						},
+					},
+					{
+						Name: "group2",
+						Hooks: []LxdCHook{
+							{Event: HookPostNodeSync, Node: "web", Commands: []string{"echo 6"}},
+						},
+						Nodes: []LxdCNode{
+							{
+								Name:   "web",
+								Matrix: map[string][]string{"zone": {"eu"}},
+							},
+						},
+					},
+				},
+			}
+
+			err := proj.ExpandNodes()
+			Expect(err).Should(BeNil())
+
+			getNodes := func(hooks []LxdCHook) []string {
+				ans := []string{}
+				for _, h := range hooks {
+					ans = append(ans, h.Node)
+				}
+				return ans
+			}
+
+			Expect(getNodes(proj.Hooks)).To(Equal([]string{"web-1", "web-2", "web-eu"}))
+			Expect(getNodes(proj.Groups[0].Hooks)).To(Equal([]string{"web-1", "web-2"}))
+			Expect(getNodes(proj.Groups[1].Hooks)).To(Equal([]string{"web-eu"}))
+
+			// The hooks of the expanded node are related to the node.
+			Expect(getNodes(proj.Groups[0].Nodes[0].Hooks)).To(Equal([]string{"web-1", ""}))
+			Expect(getNodes(proj.Groups[0].Nodes[1].Hooks)).To(Equal([]string{"web-2", ""}))
+			Expect(getNodes(proj.Groups[0].Nodes[2].Hooks)).To(Equal([]string{"web-1", "web-2"}))
+		})
+
+		It("Invalid matrix", func() {
+			node := &LxdCNode{
+				Name:   "web",
+				Matrix: map[string][]string{"python": {}},
+			}
+			_, err := node.Expand()
+			Expect(err).ShouldNot(BeNil())
+		})
+
+	})
+
+	Context("Backup retention", func() {
+
+		It("Keep last, daily and weekly", func() {
+			dates := []string{
+				"20260105-100000",
+				"20260105-200000",
+				"20260106-100000",
+				"20260112-100000",
+				"20260113-100000",
+				"20260113-180000",
+				"invalid",
+			}
+
+			r := &LxdCBackupRetention{KeepLast: 1}
+			Expect(len(r.GetBackupsToRemove(dates))).To(Equal(5))
+
+			r = &LxdCBackupRetention{KeepDaily: 2, KeepWeekly: 2}
+			Expect(r.GetBackupsToRemove(dates)).To(Equal([]string{
+				"20260113-100000",
+				"20260105-200000",
+				"20260105-100000",
+			}))
+
+			r = &LxdCBackupRetention{KeepWeekly: 3}
+			Expect(r.GetBackupsToRemove([]string{"20260101", "20260102", "20260110"})).To(
+				Equal([]string{"20260101"}))
+
+			r = &LxdCBackupRetention{}
+			Expect(len(r.GetBackupsToRemove(dates))).To(Equal(0))
+		})
+
+		It("Keep only the daily backups without keep last", func() {
+			dates := []string{
+				"20260105-100000",
+				"20260105-200000",
+				"20260106-100000",
+				"20260112-100000",
+				"20260113-100000",
+				"20260113-180000",
+			}
+
+			// A keep last of 0 doesn't maintain the backups outside
+			// the daily rule.
+			r := &LxdCBackupRetention{KeepLast: 0, KeepDaily: 1}
+			Expect(r.GetBackupsToRemove(dates)).To(Equal([]string{
+				"20260113-100000",
+				"20260112-100000",
+				"20260106-100000",
+				"20260105-200000",
+				"20260105-100000",
+			}))
+		})
+
+		It("Backup file name", func() {
+			Expect(GetBackupFileName("node1", BackupDefaultCompression)).To(Equal("node1.tar.gz"))
+			Expect(GetBackupFileName("node1", "none")).To(Equal("node1.tar"))
+			Expect(GetBackupFileName("node1", "zstd")).To(Equal("node1.tar.zstd"))
+		})
+
+	})
+
+	Context("Node type", func() {
+
+		It("Container and virtual machine", func() {
+			c := &LxdCNode{Name: "c1", WaitIp: 30}
+			vm := &LxdCNode{Name: "vm1", Type: NodeTypeVirtualMachine}
+
+			Expect(c.GetType()).To(Equal(NodeTypeContainer))
+			Expect(c.IsVirtualMachine()).To(Equal(false))
+			Expect(c.Wait4Agent()).To(Equal(int64(0)))
+
+			Expect(vm.IsVirtualMachine()).To(Equal(true))
+			Expect(vm.Wait4Agent()).To(Equal(int64(DefaultAgentTimeout)))
+			vm.WaitIp = 60
+			Expect(vm.Wait4Agent()).To(Equal(int64(60)))
+
+			Expect(IsValidNodeType("")).To(Equal(true))
+			Expect(IsValidNodeType(NodeTypeVirtualMachine)).To(Equal(true))
+			Expect(IsValidNodeType("vm")).To(Equal(false))
+		})
+
+	})
+
+	Context("Node devices", func() {
+
+		It("Merge with group devices", func() {
+			grp := &LxdCGroup{
+				Name: "group1",
+				Devices: map[string]map[string]string{
+					"eth0": {"type": "nic", "network": "lxdbr0"},
+					"data": {"type": "disk", "source": "/srv/data", "path": "/data"},
+				},
+			}
+			node := &LxdCNode{
+				Name: "node1",
+				Devices: map[string]map[string]string{
+					"eth0": {"type": "nic", "network": "lxdbr0", "ipv4.address": "10.0.0.10"},
+				},
+			}
+
+			devices := node.GetLxdDevices(grp.GetLxdDevices())
+			Expect(devices).To(Equal(map[string]map[string]string{
+				"eth0": {"type": "nic", "network": "lxdbr0", "ipv4.address": "10.0.0.10"},
+				"data": {"type": "disk", "source": "/srv/data", "path": "/data"},
+			}))
+
+			devices["data"]["path"] = "/mnt"
+			Expect(grp.Devices["data"]["path"]).To(Equal("/data"))
+
+			Expect(ValidateDevices(devices)).Should(BeNil())
+			Expect(ValidateDevices(map[string]map[string]string{
+				"gpu": {"path": "/dev/dri"},
+			})).ShouldNot(BeNil())
+		})
+
+	})
+
+	Context("Upgrade strategy", func() {
+
+		It("Validate the strategies", func() {
+			Expect(IsValidUpgradeStrategy("")).To(BeTrue())
+			Expect(IsValidUpgradeStrategy(UpgradeStrategyRecreate)).To(BeTrue())
+			Expect(IsValidUpgradeStrategy(UpgradeStrategySnapshot)).To(BeTrue())
+			Expect(IsValidUpgradeStrategy("rolling")).To(BeFalse())
+		})
+
+		It("Node strategy overrides the group", func() {
+			grp := &LxdCGroup{Name: "group1"}
+			node := &LxdCNode{Name: "node1"}
+			Expect(node.GetUpgradeStrategy(grp.GetUpgradeStrategy())).To(Equal(UpgradeStrategyRecreate))
+
+			grp.UpgradeStrategy = UpgradeStrategySnapshot
+			Expect(node.GetUpgradeStrategy(grp.GetUpgradeStrategy())).To(Equal(UpgradeStrategySnapshot))
+
+			node.UpgradeStrategy = UpgradeStrategyRecreate
+			Expect(node.GetUpgradeStrategy(grp.GetUpgradeStrategy())).To(Equal(UpgradeStrategyRecreate))
+		})
+
+		It("Health check timeout of the rolling upgrade", func() {
+			r := &LxdCRollingUpgrade{HealthCheck: "health"}
+			Expect(r.Validate()).Should(BeNil())
+			Expect(r.GetHealthCheckTimeout()).To(Equal(30))
+
+			r.HealthCheckTimeout = 5
+			Expect(r.GetHealthCheckTimeout()).To(Equal(5))
+
+			r.HealthCheckTimeout = -1
+			Expect(r.Validate()).ShouldNot(BeNil())
+		})
+
+	})
+
+	Context("Instance config", func() {
+
+		It("Track the config keys", func() {
+			configMap := map[string]string{
+				"limits.cpu": "2",
+				"user.role":  "db",
+				"user.env":   "prod",
+			}
+			SetManagedConfigKeys(configMap)
+			Expect(configMap[InstanceManagedConfigKey]).To(Equal("limits.cpu,user.env,user.role"))
+			Expect(GetManagedConfigKeys(configMap)).To(Equal([]string{
+				"limits.cpu", "user.env", "user.role",
+			}))
+
+			configMap = map[string]string{}
+			SetManagedConfigKeys(configMap)
+			Expect(configMap).ToNot(HaveKey(InstanceManagedConfigKey))
+			Expect(GetManagedConfigKeys(configMap)).To(BeEmpty())
+
+			// The user keys tracked by the previous releases are managed.
+			configMap = map[string]string{
+				InstanceManagedConfigKey: "limits.cpu",
+				InstanceManagedLabelsKey: "user.role",
+			}
+			Expect(GetManagedConfigKeys(configMap)).To(Equal([]string{
+				"limits.cpu", "user.role",
+			}))
+		})
+
+		It("Remove only the managed keys", func() {
+			config := map[string]string{
+				"limits.cpu":             "2",
+				"limits.memory":          "1GiB",
+				"security.nesting":       "true",
+				"user.role":              "db",
+				"user.old":               "1",
+				"user.user-data":         "#cloud-config",
+				"user.network-config":    "version: 2",
+				"volatile.base_image":    "abc",
+				InstanceManagedConfigKey: "limits.cpu,security.nesting,user.old,user.role",
+			}
+
+			changes := ReconcileInstanceConfig(config, map[string]string{
+				"limits.cpu": "4",
+				"user.role":  "web",
+			})
+			sort.Strings(changes)
+
+			Expect(changes).To(Equal([]string{
+				"limits.cpu", "security.nesting", "user.lxd-compose.config", "user.old", "user.role",
+			}))
+			Expect(config).To(Equal(map[string]string{
+				"limits.cpu":             "4",
+				"limits.memory":          "1GiB",
+				"user.role":              "web",
+				"user.user-data":         "#cloud-config",
+				"user.network-config":    "version: 2",
+				"volatile.base_image":    "abc",
+				InstanceManagedConfigKey: "limits.cpu,user.role",
+			}))
+
+			// Without changes the config is untouched.
+			Expect(ReconcileInstanceConfig(config, map[string]string{
+				"limits.cpu": "4",
+				"user.role":  "web",
+			})).To(BeEmpty())
+
+			// Removing all the keys also the tracking key is removed.
+			changes = ReconcileInstanceConfig(config, map[string]string{})
+			sort.Strings(changes)
+			Expect(changes).To(Equal([]string{"limits.cpu", "user.lxd-compose.config", "user.role"}))
+			Expect(config).To(HaveKey("user.user-data"))
+			Expect(config).To(HaveKey("limits.memory"))
+			Expect(config).ToNot(HaveKey(InstanceManagedConfigKey))
+		})
+
+		It("Replace the tracking of the user keys", func() {
+			config := map[string]string{
+				"user.role":              "db",
+				"user.old":               "1",
+				InstanceManagedLabelsKey: "user.old,user.role",
+			}
+
+			changes := ReconcileInstanceConfig(config, map[string]string{
+				"user.role": "db",
+			})
+			sort.Strings(changes)
+
+			Expect(changes).To(Equal([]string{
+				"user.lxd-compose.config", "user.lxd-compose.labels", "user.old",
+			}))
+			Expect(config).To(Equal(map[string]string{
+				"user.role":              "db",
+				InstanceManagedConfigKey: "user.role",
+			}))
+		})
+
+		It("Keep the user keys of an instance without tracking", func() {
+			config := map[string]string{
+				"user.user-data": "#cloud-config",
+				"user.old":       "1",
+			}
+
+			changes := ReconcileInstanceConfig(config, map[string]string{
+				"user.role": "db",
+			})
+			sort.Strings(changes)
+
+			Expect(changes).To(Equal([]string{"user.lxd-compose.config", "user.role"}))
+			Expect(config).To(HaveKey("user.user-data"))
+			Expect(config).To(HaveKey("user.old"))
+		})
+
+		It("Remove only the managed devices", func() {
+			config := map[string]string{
+				"user.user-data":          "#cloud-config",
+				InstanceManagedDevicesKey: "data,old",
+			}
+			devices := map[string]map[string]string{
+				"data":  {"type": "disk", "path": "/data", "source": "/srv/data"},
+				"old":   {"type": "disk", "path": "/old", "source": "/srv/old"},
+				"debug": {"type": "disk", "path": "/debug", "source": "/srv/debug"},
+			}
+
+			changes := ReconcileInstanceDevices(config, devices, map[string]map[string]string{
+				"data": {"type": "disk", "path": "/data", "source": "/srv/data2"},
+			})
+			sort.Strings(changes)
+
+			Expect(changes).To(Equal([]string{
+				"devices.data", "devices.old", InstanceManagedDevicesKey,
+			}))
+			// The device added manually is maintained.
+			Expect(devices).To(Equal(map[string]map[string]string{
+				"data":  {"type": "disk", "path": "/data", "source": "/srv/data2"},
+				"debug": {"type": "disk", "path": "/debug", "source": "/srv/debug"},
+			}))
+			Expect(config[InstanceManagedDevicesKey]).To(Equal("data"))
+			Expect(config).To(HaveKey("user.user-data"))
+		})
+
+		It("Keep the devices of an instance without tracking", func() {
+			config := map[string]string{}
+			devices := map[string]map[string]string{
+				"debug": {"type": "disk", "path": "/debug", "source": "/srv/debug"},
+			}
+
+			Expect(ReconcileInstanceDevices(config, devices, nil)).To(BeEmpty())
+			Expect(devices).To(HaveKey("debug"))
+			Expect(config).ToNot(HaveKey(InstanceManagedDevicesKey))
+		})
+
+		It("Keep the devices tracking on config reconcile", func() {
+			configMap := map[string]string{"user.role": "db"}
+			SetManagedDevices(configMap, map[string]map[string]string{
+				"data": {"type": "disk"},
+			})
+			SetManagedConfigKeys(configMap)
+			Expect(configMap[InstanceManagedConfigKey]).To(Equal("user.role"))
+
+			config := map[string]string{
+				"user.role":               "db",
+				InstanceManagedConfigKey:  "user.role",
+				InstanceManagedDevicesKey: "data",
+			}
+			Expect(ReconcileInstanceConfig(config, map[string]string{
+				"user.role": "db",
+			})).To(BeEmpty())
+			Expect(config[InstanceManagedDevicesKey]).To(Equal("data"))
+		})
+
+		It("Config keys that require restart", func() {
+			Expect(ConfigKeyRequiresRestart("raw.lxc")).To(BeTrue())
+			Expect(ConfigKeyRequiresRestart("environment.HTTP_PROXY")).To(BeTrue())
+			Expect(ConfigKeyRequiresRestart("security.privileged")).To(BeTrue())
+			Expect(ConfigKeyRequiresRestart("security.nesting")).To(BeTrue())
+			Expect(ConfigKeyRequiresRestart("security.idmap.isolated")).To(BeTrue())
+			Expect(ConfigKeyRequiresRestart("linux.kernel_modules")).To(BeTrue())
+			Expect(ConfigKeyRequiresRestart("limits.cpu")).To(BeFalse())
+			Expect(ConfigKeyRequiresRestart("limits.memory")).To(BeFalse())
+			Expect(ConfigKeyRequiresRestart("user.role")).To(BeFalse())
+			Expect(ConfigKeyRequiresRestart("security.privileged.foo")).To(BeFalse())
+		})
+
+	})
+
+	Context("Snapshot name", func() {
+
+		It("Use the prefix and the time", func() {
+			t := time.Date(2026, time.March, 5, 9, 7, 3, 0, time.UTC)
+			Expect(GetSnapshotName(t)).To(Equal("lxdc-20260305-090703"))
+		})
+	})
+
+	Context("Node placement", func() {
+
+		It("Move to another group", func() {
+			p := &LxdCNodePlacement{
+				FromGroup: "group1",
+				Group:     "group2",
+				Nodes:     []LxdCNode{{Name: "node1", NamePrefix: "-"}},
+			}
+			out, err := p.ToYaml()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out).To(HavePrefix(
+				"# Remove the nodes node1 from the group group1 and add them to the group:\n"))
+			Expect(out).To(ContainSubstring("- name: group2\n"))
+			Expect(out).To(ContainSubstring("name_prefix: '-'"))
+		})
+
+		It("Move to another remote", func() {
+			p := &LxdCNodePlacement{
+				FromGroup:  "group1",
+				Group:      "group1",
+				Connection: "remote1",
+				Nodes:      []LxdCNode{{Name: "node1"}},
+			}
+			out, err := p.ToYaml()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out).To(HavePrefix(
+				"# Move the nodes node1 from the group group1 to a group with connection remote1:\n"))
+			Expect(out).To(ContainSubstring("connection: remote1\n"))
+			Expect(out).ToNot(ContainSubstring("name_prefix"))
+		})
+	})
+
 	Context("Envs", func() {
 
 		It("Convert env1", func() {
@@ -137,6 +1102,130 @@ nodes:
 
 	})
 
+	Context("Group parallel", func() {
+
+		g2 := []byte(`
+name: "group2"
+parallel: true
+max_parallel: 2
+
+nodes:
+- name: "node1"
+  image_source: "alpine"
+- name: "node2"
+  image_source: "alpine"
+- name: "node3"
+  image_source: "alpine"
+`)
+
+		grp, err := GroupFromYaml(g2)
+
+		It("Max parallel from group", func() {
+			Expect(err).Should(BeNil())
+			Expect(grp.IsParallel()).To(BeTrue())
+			Expect(grp.GetMaxParallel(8)).To(Equal(2))
+		})
+
+		It("Max parallel from concurrency", func() {
+			grp.MaxParallel = 0
+			Expect(grp.GetMaxParallel(8)).To(Equal(3))
+			Expect(grp.GetMaxParallel(2)).To(Equal(2))
+		})
+
+		It("Parallel disabled", func() {
+			grp.Parallel = false
+			Expect(grp.GetMaxParallel(8)).To(Equal(1))
+		})
+
+		It("Node config without changes of the group config", func() {
+			grp.Config = map[string]string{"limits.cpu": "1"}
+			node := &grp.Nodes[0]
+			node.Config = map[string]string{"limits.memory": "1GB"}
+			node.Labels = map[string]string{"role": "web"}
+
+			config := node.GetLxdConfig(grp.GetLxdConfig())
+			Expect(config).To(Equal(map[string]string{
+				"limits.cpu":    "1",
+				"limits.memory": "1GB",
+				"user.role":     "web",
+			}))
+			Expect(grp.Config).To(Equal(map[string]string{"limits.cpu": "1"}))
+		})
+
+	})
+
+	Context("Dependencies", func() {
+
+		It("Sort levels", func() {
+			levels, err := SortByDependencies(
+				[]string{"db", "app", "proxy", "cache"},
+				map[string][]string{
+					"app":   []string{"db", "cache"},
+					"proxy": []string{"app"},
+				},
+			)
+			Expect(err).Should(BeNil())
+			Expect(levels).To(Equal([][]string{
+				[]string{"db", "cache"},
+				[]string{"app"},
+				[]string{"proxy"},
+			}))
+		})
+
+		It("Detect cycle", func() {
+			_, err := SortByDependencies(
+				[]string{"a", "b", "c"},
+				map[string][]string{
+					"a": []string{"c"},
+					"b": []string{"a"},
+					"c": []string{"b"},
+				},
+			)
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Detect unknown dependency", func() {
+			_, err := SortByDependencies(
+				[]string{"a"},
+				map[string][]string{"a": []string{"b"}},
+			)
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		g3 := []byte(`
+name: "group3"
+nodes:
+- name: "node1"
+  image_source: "alpine"
+  depends_on:
+  - node2
+- name: "node2"
+  image_source: "alpine"
+- name: "node3"
+  image_source: "alpine"
+`)
+
+		grp, err := GroupFromYaml(g3)
+
+		It("Nodes levels", func() {
+			Expect(err).Should(BeNil())
+			Expect(grp.HasNodesDependencies()).To(BeTrue())
+
+			levels, err := grp.GetNodesLevels()
+			Expect(err).Should(BeNil())
+			Expect(len(levels)).To(Equal(2))
+			Expect(levels[0][0].Name).To(Equal("node2"))
+			Expect(levels[0][1].Name).To(Equal("node3"))
+			Expect(levels[1][0].Name).To(Equal("node1"))
+
+			nodes, err := grp.GetNodesOrdered(true)
+			Expect(err).Should(BeNil())
+			Expect(nodes[0].Name).To(Equal("node1"))
+			Expect(nodes[2].Name).To(Equal("node2"))
+		})
+
+	})
+
 	Context("Envs", func() {
 
 		It("Convert env1", func() {
@@ -0,0 +1,244 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Sort the input names in levels where every entry depends only on
+// entries of the previous levels. Inside every level the order of the
+// input names is maintained.
+func SortByDependencies(names []string, deps map[string][]string) ([][]string, error) {
+	ans := [][]string{}
+	mnames := make(map[string]bool, len(names))
+	done := make(map[string]bool, len(names))
+
+	for _, n := range names {
+		mnames[n] = true
+	}
+
+	for _, n := range names {
+		for _, d := range deps[n] {
+			if _, ok := mnames[d]; !ok {
+				return ans, fmt.Errorf("%s depends on unknown entry %s", n, d)
+			}
+			if d == n {
+				return ans, fmt.Errorf("%s depends on itself", n)
+			}
+		}
+	}
+
+	for len(done) < len(names) {
+		level := []string{}
+
+		for _, n := range names {
+			if done[n] {
+				continue
+			}
+
+			ready := true
+			for _, d := range deps[n] {
+				if !done[d] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				level = append(level, n)
+			}
+		}
+
+		if len(level) == 0 {
+			pending := []string{}
+			for _, n := range names {
+				if !done[n] {
+					pending = append(pending, n)
+				}
+			}
+			return ans, fmt.Errorf("found dependencies cycle between: %s",
+				strings.Join(pending, ", "))
+		}
+
+		for _, n := range level {
+			done[n] = true
+		}
+		ans = append(ans, level)
+	}
+
+	return ans, nil
+}
+
+func (p *LxdCProject) HasGroupsDependencies() bool {
+	for idx := range p.Groups {
+		if len(p.Groups[idx].DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the groups to process splitted in levels. The groups of the same
+// level could be processed in parallel. The dependencies with groups
+// not enabled are ignored.
+func (p *LxdCProject) GetGroupsLevels(groupsEnabled, groupsDisabled []string) ([][]*LxdCGroup, error) {
+	ans := [][]*LxdCGroup{}
+	names := []string{}
+	deps := make(map[string][]string, 0)
+	mgroups := make(map[string]*LxdCGroup, 0)
+
+	for idx := range p.Groups {
+		if !p.Groups[idx].ToProcess(groupsEnabled, groupsDisabled) {
+			continue
+		}
+		names = append(names, p.Groups[idx].Name)
+		mgroups[p.Groups[idx].Name] = &p.Groups[idx]
+	}
+
+	for _, name := range names {
+		for _, d := range mgroups[name].DependsOn {
+			if _, ok := mgroups[d]; ok {
+				deps[name] = append(deps[name], d)
+			}
+		}
+	}
+
+	levels, err := SortByDependencies(names, deps)
+	if err != nil {
+		return ans, fmt.Errorf("project %s: %s", p.Name, err.Error())
+	}
+
+	for _, level := range levels {
+		groups := []*LxdCGroup{}
+		for _, name := range level {
+			groups = append(groups, mgroups[name])
+		}
+		ans = append(ans, groups)
+	}
+
+	return ans, nil
+}
+
+// Check that the groups dependencies are valid and without cycles.
+func (p *LxdCProject) ValidateDependencies() error {
+	names := []string{}
+	deps := make(map[string][]string, 0)
+
+	for _, g := range p.Groups {
+		names = append(names, g.Name)
+		deps[g.Name] = g.DependsOn
+	}
+
+	_, err := SortByDependencies(names, deps)
+	if err != nil {
+		return fmt.Errorf("project %s: %s", p.Name, err.Error())
+	}
+
+	return nil
+}
+
+func (g *LxdCGroup) HasNodesDependencies() bool {
+	for idx := range g.Nodes {
+		if len(g.Nodes[idx].DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the nodes of the group splitted in levels. The nodes of the
+// same level could be processed in parallel. The dependencies are
+// defined with the name of the node without prefix.
+func (g *LxdCGroup) GetNodesLevels() ([][]*LxdCNode, error) {
+	ans := [][]*LxdCNode{}
+	names := []string{}
+	deps := make(map[string][]string, 0)
+	mnodes := make(map[string]*LxdCNode, 0)
+
+	for idx := range g.Nodes {
+		names = append(names, g.Nodes[idx].Name)
+		deps[g.Nodes[idx].Name] = g.Nodes[idx].DependsOn
+		mnodes[g.Nodes[idx].Name] = &g.Nodes[idx]
+	}
+
+	levels, err := SortByDependencies(names, deps)
+	if err != nil {
+		return ans, fmt.Errorf("group %s: %s", g.Name, err.Error())
+	}
+
+	for _, level := range levels {
+		nodes := []*LxdCNode{}
+		for _, name := range level {
+			nodes = append(nodes, mnodes[name])
+		}
+		ans = append(ans, nodes)
+	}
+
+	return ans, nil
+}
+
+// Return the nodes of the group in the order used for the apply. On
+// reverse the order is used for stop and destroy.
+func (g *LxdCGroup) GetNodesOrdered(reverse bool) ([]*LxdCNode, error) {
+	ans := []*LxdCNode{}
+
+	if !g.HasNodesDependencies() {
+		for idx := range g.Nodes {
+			ans = append(ans, &g.Nodes[idx])
+		}
+		return ans, nil
+	}
+
+	levels, err := g.GetNodesLevels()
+	if err != nil {
+		return ans, err
+	}
+
+	for _, level := range levels {
+		ans = append(ans, level...)
+	}
+
+	if reverse {
+		for l, r := 0, len(ans)-1; l < r; l, r = l+1, r-1 {
+			ans[l], ans[r] = ans[r], ans[l]
+		}
+	}
+
+	return ans, nil
+}
+
+// Return the groups to process in the order used for the apply. On
+// reverse the order is used for stop and destroy.
+func (p *LxdCProject) GetGroupsOrdered(groupsEnabled, groupsDisabled []string, reverse bool) ([]*LxdCGroup, error) {
+	ans := []*LxdCGroup{}
+
+	if !p.HasGroupsDependencies() {
+		for idx := range p.Groups {
+			if p.Groups[idx].ToProcess(groupsEnabled, groupsDisabled) {
+				ans = append(ans, &p.Groups[idx])
+			}
+		}
+		return ans, nil
+	}
+
+	levels, err := p.GetGroupsLevels(groupsEnabled, groupsDisabled)
+	if err != nil {
+		return ans, err
+	}
+
+	for _, level := range levels {
+		ans = append(ans, level...)
+	}
+
+	if reverse {
+		for l, r := 0, len(ans)-1; l < r; l, r = l+1, r-1 {
+			ans[l], ans[r] = ans[r], ans[l]
+		}
+	}
+
+	return ans, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+)
+
+func parseFileMode(mode string) (*os.FileMode, error) {
+	if mode == "" {
+		return nil, nil
+	}
+
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mode %s: %s", mode, err.Error())
+	}
+
+	ans := os.FileMode(m).Perm()
+	return &ans, nil
+}
+
+// Return the permissions of the files or nil if not defined.
+func (o *LxdCFileOpts) GetMode() (*os.FileMode, error) {
+	return parseFileMode(o.Mode)
+}
+
+// Return the permissions of the directories or nil if not defined.
+func (o *LxdCFileOpts) GetDirMode() (*os.FileMode, error) {
+	return parseFileMode(o.DirMode)
+}
+
+func (o *LxdCFileOpts) Validate() error {
+	if _, err := o.GetMode(); err != nil {
+		return err
+	}
+	if _, err := o.GetDirMode(); err != nil {
+		return err
+	}
+
+	for _, p := range append(append([]string{}, o.Exclude...), o.Include...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %s: %s", p, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func matchFilePatterns(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if m, _ := path.Match(p, rel); m {
+			return true
+		}
+		if m, _ := path.Match(p, path.Base(rel)); m {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if the file with the path relative to the source must be
+// pushed. The excluded directories are skipped with all the content,
+// the include patterns are used only with the files.
+func (o *LxdCFileOpts) IsToPush(rel string, isDir bool) bool {
+	if o == nil {
+		return true
+	}
+
+	if matchFilePatterns(o.Exclude, rel) {
+		return false
+	}
+
+	if !isDir && len(o.Include) > 0 {
+		return matchFilePatterns(o.Include, rel)
+	}
+
+	return true
+}
+
+// Check the options of the config template. The filters are not
+// supported and the ownership is applied only on the templates pushed
+// to the nodes: the compiled files on the host maintain the ownership
+// of the user running lxd-compose.
+func (t *LxdCConfigTemplate) Validate(isNode bool) error {
+	if t.Push && !isNode {
+		return errors.New("push is supported only on the templates of the nodes")
+	}
+	if t.Push && !path.IsAbs(t.Destination) {
+		return errors.New("the destination of a pushed template must be an absolute path")
+	}
+	if len(t.Exclude) > 0 || len(t.Include) > 0 {
+		return errors.New("exclude and include are not supported on the templates")
+	}
+	if !t.Push && (t.Uid != nil || t.Gid != nil) {
+		return errors.New("uid and gid are supported only on the pushed templates")
+	}
+
+	return t.LxdCFileOpts.Validate()
+}
+
+// Check the source and the destination of the fetch resource. An
+// empty destination is rejected because it's resolved to the
+// directory of the environment.
+func (f *LxdCFetchResource) Validate() error {
+	if f.Source == "" || f.Destination == "" {
+		return errors.New("source and dst are mandatory")
+	}
+	if !path.IsAbs(f.Source) {
+		return errors.New("the source must be an absolute path of the node")
+	}
+	return nil
+}
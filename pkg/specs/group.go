@@ -20,6 +20,8 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package specs
 
 import (
+	"errors"
+
 	"gopkg.in/yaml.v3"
 )
 
@@ -58,6 +60,45 @@ func (g *LxdCGroup) GetConnection() string       { return g.Connection }
 func (g *LxdCGroup) IsEphemeral() bool           { return g.Ephemeral }
 func (g *LxdCGroup) GetCommonProfiles() []string { return g.CommonProfiles }
 func (g *LxdCGroup) GetNodes() *[]LxdCNode       { return &g.Nodes }
+func (g *LxdCGroup) IsParallel() bool            { return g.Parallel }
+
+// Return the number of nodes to process in parallel. The concurrency
+// param is used when max_parallel is not defined.
+func (g *LxdCGroup) GetMaxParallel(concurrency int) int {
+	ans := 1
+
+	if g.Parallel {
+		ans = concurrency
+		if g.MaxParallel > 0 {
+			ans = g.MaxParallel
+		}
+	}
+
+	if ans > len(g.Nodes) {
+		ans = len(g.Nodes)
+	}
+	if ans < 1 {
+		ans = 1
+	}
+
+	return ans
+}
+
+// An empty strategy is valid and it means the default strategy.
+func IsValidUpgradeStrategy(s string) bool {
+	switch s {
+	case "", UpgradeStrategyRecreate, UpgradeStrategySnapshot:
+		return true
+	}
+	return false
+}
+
+func (g *LxdCGroup) GetUpgradeStrategy() string {
+	if g.UpgradeStrategy == "" {
+		return UpgradeStrategyRecreate
+	}
+	return g.UpgradeStrategy
+}
 
 func (g *LxdCGroup) SetNodesPrefix(prefix string) {
 	g.NodesPrefix = prefix
@@ -111,3 +152,36 @@ func (g *LxdCGroup) AddHooks(h *LxdCHooks) {
 func (g *LxdCGroup) GetLxdConfig() map[string]string {
 	return g.Config
 }
+
+func (g *LxdCGroup) GetLxdDevices() map[string]map[string]string {
+	return g.Devices
+}
+
+func (r *LxdCRollingUpgrade) GetMaxUnavailable() int {
+	if r.MaxUnavailable < 1 {
+		return 1
+	}
+	return r.MaxUnavailable
+}
+
+func (r *LxdCRollingUpgrade) GetHealthCheckRetries() int {
+	if r.HealthCheckRetries < 1 {
+		return 1
+	}
+	return r.HealthCheckRetries
+}
+
+func (r *LxdCRollingUpgrade) GetHealthCheckTimeout() int {
+	if r.HealthCheckTimeout < 1 {
+		return 30
+	}
+	return r.HealthCheckTimeout
+}
+
+// Check the options of the rolling upgrade.
+func (r *LxdCRollingUpgrade) Validate() error {
+	if r.HealthCheckRetries < 0 || r.HealthCheckDelay < 0 || r.HealthCheckTimeout < 0 {
+		return errors.New("the options of the health check must be positive")
+	}
+	return nil
+}
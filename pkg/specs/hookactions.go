@@ -0,0 +1,44 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"fmt"
+)
+
+const (
+	HookActionPush     = "push"
+	HookActionPull     = "pull"
+	HookActionTemplate = "template"
+	HookActionWait     = "wait"
+	HookActionRestart  = "restart"
+)
+
+func (a *LxdCHookAction) Validate() error {
+	switch a.Type {
+	case HookActionPush, HookActionPull, HookActionTemplate:
+		if a.Source == "" || a.Destination == "" {
+			return fmt.Errorf("action %s without source or dst", a.Type)
+		}
+	case HookActionWait:
+		if a.Readiness != nil && len(a.Readiness.Probes) == 0 {
+			return fmt.Errorf("action %s with readiness without probes", a.Type)
+		}
+	case HookActionRestart:
+	default:
+		return fmt.Errorf("invalid action type %s", a.Type)
+	}
+
+	return nil
+}
+
+func (a *LxdCHookAction) String() string {
+	switch a.Type {
+	case HookActionPush, HookActionPull, HookActionTemplate:
+		return fmt.Sprintf("%s %s -> %s", a.Type, a.Source, a.Destination)
+	default:
+		return a.Type
+	}
+}
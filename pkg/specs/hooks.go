@@ -20,6 +20,12 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package specs
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/expr-lang/expr"
 	"github.com/jinzhu/copier"
 	"gopkg.in/yaml.v3"
 )
@@ -42,8 +48,29 @@ const (
 
 	HookPreNodeUpgrade  = "pre-node-upgrade"
 	HookPostNodeUpgrade = "post-node-upgrade"
+
+	HookOnNodeFailure    = "on-node-failure"
+	HookOnGroupFailure   = "on-group-failure"
+	HookOnProjectFailure = "on-project-failure"
+
+	HookVarFormatRaw   = "raw"
+	HookVarFormatJson  = "json"
+	HookVarFormatYaml  = "yaml"
+	HookVarFormatLines = "lines"
+
+	HookVarsScopeProject = "project"
+	HookVarsScopeNode    = "node"
+
+	HookOnFailureAbort    = "abort"
+	HookOnFailureContinue = "continue"
+	HookOnFailureWarn     = "warn"
 )
 
+func IsFailureHookEvent(event string) bool {
+	return event == HookOnNodeFailure || event == HookOnGroupFailure ||
+		event == HookOnProjectFailure
+}
+
 func getHooks(hooks *[]LxdCHook, event string) []LxdCHook {
 	return getHooks4Nodes(hooks, event, []string{""})
 }
@@ -125,6 +152,174 @@ func (h *LxdCHook) ToProcess(enabledFlags, disabledFlags []string) bool {
 	return ans
 }
 
+func (h *LxdCHook) GetOnFailure() string {
+	if h.OnFailure == "" {
+		return HookOnFailureAbort
+	}
+	return h.OnFailure
+}
+
+func (h *LxdCHook) GetRetries() int {
+	if h.Retries < 0 {
+		return 0
+	}
+	return h.Retries
+}
+
+func (h *LxdCHook) GetRetryDelay() time.Duration {
+	return time.Duration(h.RetryDelay) * time.Second
+}
+
+func (h *LxdCHook) GetTimeout() time.Duration {
+	return time.Duration(h.Timeout) * time.Second
+}
+
+// Return the number of nodes where the hook is executed in parallel.
+// The concurrency param is used when max_parallel is not defined.
+func (h *LxdCHook) GetMaxParallel(concurrency int) int {
+	ans := 1
+
+	if h.Parallel {
+		ans = concurrency
+		if h.MaxParallel > 0 {
+			ans = h.MaxParallel
+		}
+	}
+
+	if ans < 1 {
+		ans = 1
+	}
+
+	return ans
+}
+
+func (h *LxdCHook) GetOut2VarFormat() string {
+	if h.Out2VarFormat == "" {
+		return HookVarFormatRaw
+	}
+	return h.Out2VarFormat
+}
+
+func (h *LxdCHook) GetVarsScope() string {
+	if h.VarsScope == "" {
+		return HookVarsScopeProject
+	}
+	return h.VarsScope
+}
+
+// Convert the output of the command stored with out2var
+// in the value of the configured format.
+func (h *LxdCHook) ParseOut2Var(out string) (interface{}, error) {
+	var ans interface{}
+
+	switch h.GetOut2VarFormat() {
+	case HookVarFormatJson:
+		err := json.Unmarshal([]byte(out), &ans)
+		if err != nil {
+			return nil, fmt.Errorf("error on parse json output of %s: %s",
+				h.Out2Var, err.Error())
+		}
+	case HookVarFormatYaml:
+		err := yaml.Unmarshal([]byte(out), &ans)
+		if err != nil {
+			return nil, fmt.Errorf("error on parse yaml output of %s: %s",
+				h.Out2Var, err.Error())
+		}
+	case HookVarFormatLines:
+		lines := []string{}
+		for _, l := range strings.Split(out, "\n") {
+			if strings.TrimSpace(l) != "" {
+				lines = append(lines, l)
+			}
+		}
+		ans = lines
+	default:
+		ans = out
+	}
+
+	return ans, nil
+}
+
+func (h *LxdCHook) Validate() error {
+	switch h.GetOnFailure() {
+	case HookOnFailureAbort, HookOnFailureContinue, HookOnFailureWarn:
+	default:
+		return fmt.Errorf("invalid on_failure value %s for hook %s",
+			h.OnFailure, h.Event)
+	}
+
+	switch h.GetOut2VarFormat() {
+	case HookVarFormatRaw, HookVarFormatJson, HookVarFormatYaml, HookVarFormatLines:
+	default:
+		return fmt.Errorf("invalid out2var_format value %s for hook %s",
+			h.Out2VarFormat, h.Event)
+	}
+
+	switch h.GetVarsScope() {
+	case HookVarsScopeProject, HookVarsScopeNode:
+	default:
+		return fmt.Errorf("invalid vars_scope value %s for hook %s",
+			h.VarsScope, h.Event)
+	}
+
+	if h.MaxParallel < 0 {
+		return fmt.Errorf("invalid negative max_parallel for hook %s", h.Event)
+	}
+
+	if h.Retries < 0 || h.RetryDelay < 0 || h.Timeout < 0 {
+		return fmt.Errorf("invalid negative retries, retry_delay or timeout for hook %s",
+			h.Event)
+	}
+
+	if len(h.Actions) > 0 && h.Node == "host" {
+		return fmt.Errorf("actions are not supported on host for hook %s",
+			h.Event)
+	}
+
+	for idx := range h.Actions {
+		if err := h.Actions[idx].Validate(); err != nil {
+			return fmt.Errorf("hook %s: %s", h.Event, err.Error())
+		}
+	}
+
+	if h.When != "" {
+		if _, err := expr.Compile(h.When); err != nil {
+			return fmt.Errorf("invalid when expression for hook %s: %s",
+				h.Event, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Evaluate the when expression of the hook with the input vars.
+// The hooks without expression are always executed.
+func (h *LxdCHook) EvalWhen(vars map[string]interface{}) (bool, error) {
+	if h.When == "" {
+		return true, nil
+	}
+
+	program, err := expr.Compile(h.When)
+	if err != nil {
+		return false, fmt.Errorf("invalid when expression %s: %s",
+			h.When, err.Error())
+	}
+
+	out, err := expr.Run(program, vars)
+	if err != nil {
+		return false, fmt.Errorf("error on evaluate when expression %s: %s",
+			h.When, err.Error())
+	}
+
+	ans, ok := out.(bool)
+	if !ok {
+		return false, fmt.Errorf("when expression %s returns %v and not a boolean",
+			h.When, out)
+	}
+
+	return ans, nil
+}
+
 func (h *LxdCHook) ContainsFlag(flag string) bool {
 	ans := false
 	if len(h.Flags) > 0 {
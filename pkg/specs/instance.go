@@ -0,0 +1,168 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// Config key of the instance with the list of the config keys
+	// set by lxd-compose. The other keys (for example the cloud-init
+	// keys or the keys set manually) are never removed.
+	InstanceManagedConfigKey = "user.lxd-compose.config"
+	// Config key used by the previous releases to track only the
+	// user keys. It's replaced by InstanceManagedConfigKey on reconcile.
+	InstanceManagedLabelsKey = "user.lxd-compose.labels"
+	// Config key of the instance with the list of the devices set
+	// by lxd-compose. The devices added manually are never removed.
+	InstanceManagedDevicesKey = "user.lxd-compose.devices"
+)
+
+func isTrackingKey(k string) bool {
+	return k == InstanceManagedConfigKey || k == InstanceManagedLabelsKey ||
+		k == InstanceManagedDevicesKey
+}
+
+// Return the keys of the instance config set by lxd-compose.
+func GetManagedConfigKeys(config map[string]string) []string {
+	ans := []string{}
+	for _, tk := range []string{InstanceManagedConfigKey, InstanceManagedLabelsKey} {
+		if config[tk] == "" {
+			continue
+		}
+		for _, k := range strings.Split(config[tk], ",") {
+			if !slices.Contains(ans, k) {
+				ans = append(ans, k)
+			}
+		}
+	}
+	return ans
+}
+
+// Set the key used to track the keys of the config map
+// managed by lxd-compose.
+func SetManagedConfigKeys(configMap map[string]string) {
+	keys := []string{}
+	for k := range configMap {
+		if !isTrackingKey(k) {
+			keys = append(keys, k)
+		}
+	}
+
+	if len(keys) == 0 {
+		delete(configMap, InstanceManagedConfigKey)
+		return
+	}
+
+	sort.Strings(keys)
+	configMap[InstanceManagedConfigKey] = strings.Join(keys, ",")
+}
+
+// Align the config of the instance with the config map of the node
+// and return the keys changed. The keys not available in the
+// config map are removed only if previously set by lxd-compose.
+func ReconcileInstanceConfig(config, configMap map[string]string) []string {
+	changes := []string{}
+	managed := GetManagedConfigKeys(config)
+
+	expected := make(map[string]string, len(configMap)+1)
+	for k, v := range configMap {
+		expected[k] = v
+	}
+	SetManagedConfigKeys(expected)
+
+	for k, v := range expected {
+		if cv, ok := config[k]; !ok || cv != v {
+			config[k] = v
+			changes = append(changes, k)
+		}
+	}
+
+	for _, k := range managed {
+		if _, ok := expected[k]; ok {
+			continue
+		}
+		if _, ok := config[k]; ok {
+			delete(config, k)
+			changes = append(changes, k)
+		}
+	}
+
+	for _, k := range []string{InstanceManagedConfigKey, InstanceManagedLabelsKey} {
+		if _, ok := expected[k]; ok {
+			continue
+		}
+		if _, ok := config[k]; ok {
+			delete(config, k)
+			changes = append(changes, k)
+		}
+	}
+
+	return changes
+}
+
+// Return the devices of the instance set by lxd-compose.
+func GetManagedDevices(config map[string]string) []string {
+	ans := []string{}
+	if config[InstanceManagedDevicesKey] == "" {
+		return ans
+	}
+	return strings.Split(config[InstanceManagedDevicesKey], ",")
+}
+
+// Set the key used to track the devices managed by lxd-compose.
+func SetManagedDevices(configMap map[string]string,
+	devicesMap map[string]map[string]string) {
+	if len(devicesMap) == 0 {
+		delete(configMap, InstanceManagedDevicesKey)
+		return
+	}
+
+	devices := []string{}
+	for d := range devicesMap {
+		devices = append(devices, d)
+	}
+	sort.Strings(devices)
+	configMap[InstanceManagedDevicesKey] = strings.Join(devices, ",")
+}
+
+// Align the devices of the instance with the devices map of the node
+// and return the devices changed. The devices not available in the
+// devices map are removed only if previously set by lxd-compose.
+// The config of the instance is updated with the devices managed.
+func ReconcileInstanceDevices(config map[string]string,
+	devices, devicesMap map[string]map[string]string) []string {
+	changes := []string{}
+	managed := GetManagedDevices(config)
+
+	for d, device := range devicesMap {
+		if cd, ok := devices[d]; !ok || !maps.Equal(cd, device) {
+			devices[d] = device
+			changes = append(changes, "devices."+d)
+		}
+	}
+
+	for _, d := range managed {
+		if _, ok := devicesMap[d]; ok {
+			continue
+		}
+		if _, ok := devices[d]; ok {
+			delete(devices, d)
+			changes = append(changes, "devices."+d)
+		}
+	}
+
+	current := config[InstanceManagedDevicesKey]
+	SetManagedDevices(config, devicesMap)
+	if config[InstanceManagedDevicesKey] != current {
+		changes = append(changes, InstanceManagedDevicesKey)
+	}
+
+	return changes
+}
@@ -0,0 +1,202 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Journal steps not related to hooks events
+	JournalStepCreate  = "create"
+	JournalStepSync    = "sync"
+	JournalStepFetch   = "fetch"
+	JournalStepUpgrade = "upgrade"
+)
+
+type LxdCJournalStep struct {
+	Group     string `json:"group,omitempty" yaml:"group,omitempty"`
+	Node      string `json:"node,omitempty" yaml:"node,omitempty"`
+	Step      string `json:"step" yaml:"step"`
+	Timestamp string `json:"timestamp" yaml:"timestamp"`
+}
+
+// A var stored by the hooks with out2var/err2var. On resume the hooks
+// already executed are skipped and the vars are restored from the journal.
+type LxdCJournalVar struct {
+	Name  string      `json:"name" yaml:"name"`
+	Node  string      `json:"node,omitempty" yaml:"node,omitempty"`
+	Value interface{} `json:"value" yaml:"value"`
+}
+
+// The journal of the steps completed by the apply of a project.
+type LxdCJournal struct {
+	Project string            `json:"project" yaml:"project"`
+	Steps   []LxdCJournalStep `json:"steps" yaml:"steps"`
+	Vars    []LxdCJournalVar  `json:"vars,omitempty" yaml:"vars,omitempty"`
+
+	file  string
+	mutex sync.Mutex
+}
+
+func NewLxdCJournal(project, file string) *LxdCJournal {
+	return &LxdCJournal{
+		Project: project,
+		Steps:   []LxdCJournalStep{},
+		file:    file,
+	}
+}
+
+func LoadLxdCJournal(project, file string) (*LxdCJournal, error) {
+	ans := NewLxdCJournal(project, file)
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ans, nil
+		}
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, ans)
+	if err != nil {
+		return nil, fmt.Errorf("error on parse journal %s: %s", file, err.Error())
+	}
+
+	return ans, nil
+}
+
+func (j *LxdCJournal) GetFile() string { return j.file }
+
+func (j *LxdCJournal) IsDone(group, node, step string) bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.indexOf(group, node, step) >= 0
+}
+
+func (j *LxdCJournal) indexOf(group, node, step string) int {
+	for idx, s := range j.Steps {
+		if s.Group == group && s.Node == node && s.Step == step {
+			return idx
+		}
+	}
+	return -1
+}
+
+// Register a completed step and write the journal file.
+func (j *LxdCJournal) AddStep(group, node, step string) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.Steps = append(j.Steps, LxdCJournalStep{
+		Group:     group,
+		Node:      node,
+		Step:      step,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+
+	return j.write()
+}
+
+// Register a var stored by an hook and write the journal file.
+func (j *LxdCJournal) AddVar(name, node string, value interface{}) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.Vars = append(j.Vars, LxdCJournalVar{
+		Name:  name,
+		Node:  node,
+		Value: value,
+	})
+
+	return j.write()
+}
+
+// Drop the steps of the node registered from the first step
+// that matches with the input step. An empty step drops all
+// the steps of the node. The node could be the name of a group:
+// in this case the steps of the group and of its nodes are dropped.
+// The steps of the other nodes are maintained also when they are
+// registered later by the nodes processed in parallel.
+func (j *LxdCJournal) RestartFrom(node, step string) bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	isTarget := func(s *LxdCJournalStep) bool {
+		return s.Node == node || (s.Node == "" && s.Group == node)
+	}
+
+	start := -1
+	isGroup := false
+	for idx := range j.Steps {
+		s := &j.Steps[idx]
+		if isTarget(s) && (step == "" || s.Step == step) {
+			start = idx
+			isGroup = s.Node == ""
+			break
+		}
+	}
+
+	if start < 0 {
+		return false
+	}
+
+	steps := j.Steps[:start]
+	for idx := start; idx < len(j.Steps); idx++ {
+		s := &j.Steps[idx]
+		if isTarget(s) || (isGroup && s.Group == node) {
+			continue
+		}
+		steps = append(steps, *s)
+	}
+	j.Steps = steps
+
+	return true
+}
+
+func (j *LxdCJournal) Write() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.write()
+}
+
+func (j *LxdCJournal) write() error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(j.file), 0755)
+	if err != nil {
+		return err
+	}
+
+	// The journal could contain the vars of the hooks.
+	return os.WriteFile(j.file, data, 0600)
+}
+
+func (j *LxdCJournal) Remove() error {
+	err := os.Remove(j.file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Parse the value of the restart-from option in the format node/event.
+func ParseJournalRestartFrom(s string) (string, string, error) {
+	node, step, _ := strings.Cut(s, "/")
+	if node == "" {
+		return "", "", fmt.Errorf("invalid restart point %s, expected node/event", s)
+	}
+	return node, step, nil
+}
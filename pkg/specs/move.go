@@ -0,0 +1,45 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// The new placement of a node moved between groups or remotes.
+type LxdCNodePlacement struct {
+	FromGroup      string `json:"-" yaml:"-"`
+	Group          string `json:"name" yaml:"name"`
+	Connection     string `json:"connection,omitempty" yaml:"connection,omitempty"`
+	ConnectionType string `json:"connection_type,omitempty" yaml:"connection_type,omitempty"`
+
+	Nodes []LxdCNode `json:"nodes" yaml:"nodes"`
+}
+
+// Return the YAML change to apply on the environment file.
+func (p *LxdCNodePlacement) ToYaml() (string, error) {
+	data, err := yaml.Marshal([]*LxdCNodePlacement{p})
+	if err != nil {
+		return "", err
+	}
+
+	names := []string{}
+	for _, n := range p.Nodes {
+		names = append(names, n.Name)
+	}
+
+	header := fmt.Sprintf("# Remove the nodes %s from the group %s and add them to the group:\n",
+		strings.Join(names, ", "), p.FromGroup)
+	if p.Group == p.FromGroup {
+		header = fmt.Sprintf(
+			"# Move the nodes %s from the group %s to a group with connection %s:\n",
+			strings.Join(names, ", "), p.FromGroup, p.Connection)
+	}
+
+	return header + string(data), nil
+}
@@ -22,6 +22,7 @@ package specs
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/ghodss/yaml"
 )
@@ -41,6 +42,37 @@ func (n *LxdCNode) IsSourcePathRelative() bool {
 
 func (n *LxdCNode) Wait4Ip() int64 { return n.WaitIp }
 
+func IsValidNodeType(t string) bool {
+	switch t {
+	case "", NodeTypeContainer, NodeTypeVirtualMachine:
+		return true
+	}
+	return false
+}
+
+func (n *LxdCNode) GetType() string {
+	if n.Type == "" {
+		return NodeTypeContainer
+	}
+	return n.Type
+}
+
+func (n *LxdCNode) IsVirtualMachine() bool {
+	return n.GetType() == NodeTypeVirtualMachine
+}
+
+// Return the timeout in seconds to wait for the agent of
+// the virtual machine. For the containers is always 0.
+func (n *LxdCNode) Wait4Agent() int64 {
+	if !n.IsVirtualMachine() {
+		return 0
+	}
+	if n.WaitIp > 0 {
+		return n.WaitIp
+	}
+	return DefaultAgentTimeout
+}
+
 func (n *LxdCNode) GetHooks(event string) []LxdCHook {
 	return getHooks(&n.Hooks, event)
 }
@@ -80,18 +112,97 @@ func (n *LxdCNode) AddHooks(h *LxdCHooks) {
 }
 
 func (n *LxdCNode) GetLxdConfig(groupMap map[string]string) map[string]string {
-	if groupMap == nil {
-		groupMap = make(map[string]string, 0)
+	// NOTE: the group map is shared between the nodes of the group
+	//       that could be processed in parallel. I use a new map.
+	ans := make(map[string]string, 0)
+
+	for k, v := range groupMap {
+		ans[k] = v
 	}
 
 	for k, v := range n.Config {
-		groupMap[k] = v
+		ans[k] = v
 	}
 
 	// Add labels as user properties
 	for k, v := range n.Labels {
-		groupMap["user."+k] = v
+		ans["user."+k] = v
+	}
+
+	return ans
+}
+
+// Return the devices of the group with the devices of the node.
+// A device of the node replaces the group device with the same name.
+func (n *LxdCNode) GetLxdDevices(groupDevices map[string]map[string]string) map[string]map[string]string {
+	// NOTE: the devices are copied so the devices of the instance
+	//       never alias the device maps of the group definition.
+	ans := make(map[string]map[string]string, 0)
+
+	for name, device := range groupDevices {
+		ans[name] = copyDevice(device)
+	}
+
+	for name, device := range n.Devices {
+		ans[name] = copyDevice(device)
+	}
+
+	return ans
+}
+
+// Check that every device defines the type.
+func ValidateDevices(devices map[string]map[string]string) error {
+	for name, device := range devices {
+		if device["type"] == "" {
+			return fmt.Errorf("device %s without type", name)
+		}
+	}
+	return nil
+}
+
+func copyDevice(device map[string]string) map[string]string {
+	ans := make(map[string]string, len(device))
+	for k, v := range device {
+		ans[k] = v
+	}
+	return ans
+}
+
+// Return the upgrade strategy of the node or the strategy of the
+// group if not defined.
+func (n *LxdCNode) GetUpgradeStrategy(groupStrategy string) string {
+	if n.UpgradeStrategy != "" {
+		return n.UpgradeStrategy
+	}
+	return groupStrategy
+}
+
+// Return true if the update of the instance config key is applied
+// only after the restart of the instance.
+func ConfigKeyRequiresRestart(key string) bool {
+	prefixes := []string{
+		"raw.",
+		"environment.",
+		"security.idmap.",
+		"limits.kernel.",
+		"security.syscalls.",
+	}
+	keys := []string{
+		"security.privileged",
+		"security.nesting",
+		"linux.kernel_modules",
+	}
+
+	for _, p := range prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	for _, k := range keys {
+		if key == k {
+			return true
+		}
 	}
 
-	return groupMap
+	return false
 }
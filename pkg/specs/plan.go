@@ -0,0 +1,70 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+const (
+	PlanActionCreate  = "create"
+	PlanActionUpgrade = "upgrade"
+	PlanActionStart   = "start"
+	PlanActionUpdate  = "update"
+	PlanActionNone    = "none"
+)
+
+type LxdCPlanHook struct {
+	Event   string   `json:"event" yaml:"event"`
+	Node    string   `json:"node" yaml:"node"`
+	Command string   `json:"command" yaml:"command"`
+	Flags   []string `json:"flags,omitempty" yaml:"flags,omitempty"`
+	Out2Var string   `json:"out2var,omitempty" yaml:"out2var,omitempty"`
+	Err2Var string   `json:"err2var,omitempty" yaml:"err2var,omitempty"`
+}
+
+type LxdCNodePlan struct {
+	Name            string               `json:"name" yaml:"name"`
+	Action          string               `json:"action" yaml:"action"`
+	Present         bool                 `json:"present" yaml:"present"`
+	Running         bool                 `json:"running" yaml:"running"`
+	Hooks           []LxdCPlanHook       `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+	Operations      []string             `json:"operations,omitempty" yaml:"operations,omitempty"`
+	ConfigTemplates []LxdCConfigTemplate `json:"config_templates,omitempty" yaml:"config_templates,omitempty"`
+	SyncResources   []LxdCSyncResource   `json:"sync_resources,omitempty" yaml:"sync_resources,omitempty"`
+	FetchResources  []LxdCFetchResource  `json:"fetch_resources,omitempty" yaml:"fetch_resources,omitempty"`
+}
+
+type LxdCGroupPlan struct {
+	Name            string               `json:"name" yaml:"name"`
+	Connection      string               `json:"connection" yaml:"connection"`
+	PreHooks        []LxdCPlanHook       `json:"pre_hooks,omitempty" yaml:"pre_hooks,omitempty"`
+	ConfigTemplates []LxdCConfigTemplate `json:"config_templates,omitempty" yaml:"config_templates,omitempty"`
+	Nodes           []*LxdCNodePlan      `json:"nodes" yaml:"nodes"`
+	PostHooks       []LxdCPlanHook       `json:"post_hooks,omitempty" yaml:"post_hooks,omitempty"`
+}
+
+type LxdCProjectPlan struct {
+	Name            string               `json:"name" yaml:"name"`
+	PreHooks        []LxdCPlanHook       `json:"pre_hooks,omitempty" yaml:"pre_hooks,omitempty"`
+	ConfigTemplates []LxdCConfigTemplate `json:"config_templates,omitempty" yaml:"config_templates,omitempty"`
+	Groups          []*LxdCGroupPlan     `json:"groups" yaml:"groups"`
+	PostHooks       []LxdCPlanHook       `json:"post_hooks,omitempty" yaml:"post_hooks,omitempty"`
+}
+
+// Return the number of nodes for every action.
+func (p *LxdCProjectPlan) GetActionsSummary() map[string]int {
+	ans := map[string]int{
+		PlanActionCreate:  0,
+		PlanActionUpgrade: 0,
+		PlanActionStart:   0,
+		PlanActionUpdate:  0,
+		PlanActionNone:    0,
+	}
+
+	for _, g := range p.Groups {
+		for _, n := range g.Nodes {
+			ans[n.Action]++
+		}
+	}
+
+	return ans
+}
@@ -55,6 +55,42 @@ func (p *LxdCProject) AddEnvironment(e *LxdCEnvVars) {
 	p.Environments = append(p.Environments, *e)
 }
 
+// Store a var generated by the hooks. Without node the var is stored
+// in the last environment of the project, otherwise it's stored
+// under nodes.<node>.<name>.
+func (p *LxdCProject) SetHookVar(name string, value interface{}, node string) {
+	if len(p.Environments) == 0 {
+		p.AddEnvironment(&LxdCEnvVars{EnvVars: make(map[string]interface{}, 0)})
+	}
+	last := p.Environments[len(p.Environments)-1].EnvVars
+
+	if node == "" {
+		last[name] = value
+		return
+	}
+
+	// Reuse the nodes map already present to maintain the
+	// vars of the other nodes.
+	var nodes map[string]interface{}
+	for idx := len(p.Environments) - 1; idx >= 0; idx-- {
+		if v, ok := p.Environments[idx].EnvVars["nodes"]; ok {
+			nodes, _ = dyno.ConvertMapI2MapS(v).(map[string]interface{})
+			break
+		}
+	}
+	if nodes == nil {
+		nodes = make(map[string]interface{}, 0)
+	}
+
+	nodeVars, ok := nodes[node].(map[string]interface{})
+	if !ok {
+		nodeVars = make(map[string]interface{}, 0)
+	}
+	nodeVars[name] = value
+	nodes[node] = nodeVars
+	last["nodes"] = nodes
+}
+
 func (p *LxdCProject) GetGroupByName(name string) *LxdCGroup {
 	for idx := range p.Groups {
 		if p.Groups[idx].Name == name {
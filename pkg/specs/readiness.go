@@ -0,0 +1,70 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ProbeCommand = "command"
+	ProbeTcp     = "tcp"
+	ProbeHttp    = "http"
+)
+
+func (r *LxdCReadiness) GetInterval() int {
+	if r.Interval <= 0 {
+		return 2
+	}
+	return r.Interval
+}
+
+func (r *LxdCReadiness) GetTimeout() int {
+	if r.Timeout <= 0 {
+		return 5
+	}
+	return r.Timeout
+}
+
+func (r *LxdCReadiness) GetRetries() int {
+	if r.Retries <= 0 {
+		return 30
+	}
+	return r.Retries
+}
+
+func (p *LxdCProbe) GetType() string {
+	if p.Command != "" {
+		return ProbeCommand
+	} else if p.HttpPort > 0 {
+		return ProbeHttp
+	}
+	return ProbeTcp
+}
+
+// Return the URL of the HTTP probe for the input address.
+func (p *LxdCProbe) GetUrl(address string) string {
+	scheme := p.HttpScheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	path := p.HttpPath
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return fmt.Sprintf("%s://%s:%d%s", scheme, address, p.HttpPort, path)
+}
+
+func (p *LxdCProbe) String() string {
+	switch p.GetType() {
+	case ProbeCommand:
+		return fmt.Sprintf("command: %s", p.Command)
+	case ProbeHttp:
+		return fmt.Sprintf("http: %d%s", p.HttpPort, p.HttpPath)
+	default:
+		return fmt.Sprintf("tcp: %d", p.TcpPort)
+	}
+}
@@ -0,0 +1,279 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const (
+	// Label with the index of the expanded node starting from 1.
+	NodeLabelIndex = "index"
+	// Label with the number of the replica of the expanded node.
+	NodeLabelReplica = "replica"
+)
+
+var nodeNameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9-]+`)
+
+func (n *LxdCNode) IsToExpand() bool {
+	return n.Replicas > 0 || len(n.Matrix) > 0
+}
+
+// Return the combinations of the matrix values. The keys are
+// processed in alphabetical order.
+func (n *LxdCNode) GetMatrixCombinations() ([]string, [][]string) {
+	keys := []string{}
+	for k := range n.Matrix {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ans := [][]string{{}}
+	for _, k := range keys {
+		combinations := [][]string{}
+		for _, c := range ans {
+			for _, v := range n.Matrix[k] {
+				combination := append(append([]string{}, c...), v)
+				combinations = append(combinations, combination)
+			}
+		}
+		ans = combinations
+	}
+
+	return keys, ans
+}
+
+func (n *LxdCNode) ValidateExpansion() error {
+	if n.Replicas < 0 {
+		return fmt.Errorf("node %s with invalid replicas %d", n.Name, n.Replicas)
+	}
+
+	for k, values := range n.Matrix {
+		if k == "" {
+			return fmt.Errorf("node %s with an empty matrix key", n.Name)
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("node %s with matrix key %s without values", n.Name, k)
+		}
+	}
+
+	return nil
+}
+
+func (n *LxdCNode) clone() (*LxdCNode, error) {
+	ans := &LxdCNode{}
+
+	data, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, ans)
+	if err != nil {
+		return nil, err
+	}
+
+	return ans, nil
+}
+
+// Return the nodes generated by the replicas and the matrix
+// of the node. The replicas are generated for every combination
+// of the matrix.
+func (n *LxdCNode) Expand() ([]LxdCNode, error) {
+	ans := []LxdCNode{}
+
+	if !n.IsToExpand() {
+		return append(ans, *n), nil
+	}
+
+	err := n.ValidateExpansion()
+	if err != nil {
+		return ans, err
+	}
+
+	replicas := n.Replicas
+	if replicas == 0 {
+		replicas = 1
+	}
+
+	keys, combinations := n.GetMatrixCombinations()
+	index := 1
+	for _, combination := range combinations {
+		for r := 1; r <= replicas; r++ {
+			node, err := n.clone()
+			if err != nil {
+				return ans, fmt.Errorf("error on expand node %s: %s",
+					n.Name, err.Error())
+			}
+			node.Replicas = 0
+			node.Matrix = nil
+
+			if node.Labels == nil {
+				node.Labels = make(map[string]string, 0)
+			}
+
+			for idx, v := range combination {
+				node.Name += "-" + nodeNameInvalidChars.ReplaceAllString(v, "-")
+				node.Labels[keys[idx]] = v
+			}
+
+			if n.Replicas > 0 {
+				node.Name += "-" + strconv.Itoa(r)
+				node.Labels[NodeLabelReplica] = strconv.Itoa(r)
+			}
+			node.Labels[NodeLabelIndex] = strconv.Itoa(index)
+			index++
+
+			ans = append(ans, *node)
+		}
+	}
+
+	return ans, nil
+}
+
+func expandNames(names []string, expanded map[string][]string) []string {
+	ans := []string{}
+	for _, name := range names {
+		if e, ok := expanded[name]; ok {
+			ans = append(ans, e...)
+		} else {
+			ans = append(ans, name)
+		}
+	}
+	return ans
+}
+
+func expandHooksNodes(hooks []LxdCHook, expanded map[string][]string) []LxdCHook {
+	ans := []LxdCHook{}
+	for idx := range hooks {
+		names, ok := expanded[hooks[idx].Node]
+		if !ok {
+			ans = append(ans, hooks[idx])
+			continue
+		}
+
+		for _, name := range names {
+			h := hooks[idx].Clone()
+			h.SetNode(name)
+			ans = append(ans, *h)
+		}
+	}
+	return ans
+}
+
+// Replace the hooks of the expanded node related to the original
+// node with hooks of the expanded node.
+func setHooksNode(hooks []LxdCHook, orig, node *LxdCNode) []LxdCHook {
+	for idx := range hooks {
+		switch hooks[idx].Node {
+		case orig.Name:
+			hooks[idx].SetNode(node.Name)
+		case orig.GetName():
+			hooks[idx].SetNode(node.GetName())
+		}
+	}
+	return hooks
+}
+
+// Merge the expanded nodes of the groups. The nodes with the same
+// name on multiple groups are expanded with all the nodes.
+func mergeExpandedNodes(dst, src map[string][]string) {
+	for name, nodes := range src {
+		dst[name] = append(dst[name], nodes...)
+	}
+}
+
+// Replace the nodes with replicas or matrix with the expanded nodes
+// and return the names of the expanded nodes of the group. The
+// dependencies with the original node are replaced with all the
+// expanded nodes.
+func (g *LxdCGroup) ExpandNodes() (map[string][]string, error) {
+	nodes := []LxdCNode{}
+	groupExpanded := make(map[string][]string, 0)
+
+	for idx := range g.Nodes {
+		orig := &g.Nodes[idx]
+		if !orig.IsToExpand() {
+			nodes = append(nodes, *orig)
+			continue
+		}
+
+		enodes, err := orig.Expand()
+		if err != nil {
+			return groupExpanded, fmt.Errorf("group %s: %s", g.Name, err.Error())
+		}
+
+		for eidx := range enodes {
+			n := &enodes[eidx]
+			// The hooks of the node are related to the expanded node.
+			n.Hooks = setHooksNode(n.Hooks, orig, n)
+
+			groupExpanded[orig.Name] = append(groupExpanded[orig.Name], n.Name)
+			if orig.GetName() != orig.Name {
+				groupExpanded[orig.GetName()] = append(
+					groupExpanded[orig.GetName()], n.GetName())
+			}
+		}
+
+		nodes = append(nodes, enodes...)
+	}
+
+	if len(groupExpanded) > 0 {
+		for idx := range nodes {
+			if len(nodes[idx].DependsOn) > 0 {
+				nodes[idx].DependsOn = expandNames(nodes[idx].DependsOn, groupExpanded)
+			}
+		}
+		g.Nodes = nodes
+	}
+
+	return groupExpanded, nil
+}
+
+// Expand the nodes with replicas or matrix of all groups. The hooks
+// of the project, of the groups and of the nodes related to the
+// original node are replicated for every expanded node. The hooks of
+// a group and of its nodes use the nodes expanded of the same group.
+func (p *LxdCProject) ExpandNodes() error {
+	expanded := make(map[string][]string, 0)
+	groupsExpanded := make([]map[string][]string, len(p.Groups))
+
+	for idx := range p.Groups {
+		groupExpanded, err := p.Groups[idx].ExpandNodes()
+		if err != nil {
+			return fmt.Errorf("project %s: %s", p.Name, err.Error())
+		}
+		groupsExpanded[idx] = groupExpanded
+		mergeExpandedNodes(expanded, groupExpanded)
+	}
+
+	if len(expanded) == 0 {
+		return nil
+	}
+
+	p.Hooks = expandHooksNodes(p.Hooks, expanded)
+	for idx := range p.Groups {
+		grp := &p.Groups[idx]
+
+		gexpanded := make(map[string][]string, 0)
+		for name, nodes := range expanded {
+			gexpanded[name] = nodes
+		}
+		for name, nodes := range groupsExpanded[idx] {
+			gexpanded[name] = nodes
+		}
+
+		grp.Hooks = expandHooksNodes(grp.Hooks, gexpanded)
+		for nidx := range grp.Nodes {
+			grp.Nodes[nidx].Hooks = expandHooksNodes(grp.Nodes[nidx].Hooks, gexpanded)
+		}
+	}
+
+	return nil
+}
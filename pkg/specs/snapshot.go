@@ -0,0 +1,27 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"time"
+)
+
+const (
+	SnapshotNamePrefix = "lxdc-"
+)
+
+type LxdCSnapshot struct {
+	Node      string    `json:"node,omitempty" yaml:"node,omitempty"`
+	Name      string    `json:"name" yaml:"name"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	Stateful  bool      `json:"stateful" yaml:"stateful"`
+	Size      int64     `json:"size,omitempty" yaml:"size,omitempty"`
+}
+
+// Return the name used for the snapshots of all the nodes of
+// a project created at the input time.
+func GetSnapshotName(t time.Time) string {
+	return SnapshotNamePrefix + t.Format("20060102-150405")
+}
@@ -0,0 +1,127 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+)
+
+// The current status of an instance retrieved from the server.
+type LxdCInstanceInfo struct {
+	Name             string                       `json:"name" yaml:"name"`
+	Status           string                       `json:"status" yaml:"status"`
+	Profiles         []string                     `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+	Config           map[string]string            `json:"config,omitempty" yaml:"config,omitempty"`
+	Devices          map[string]map[string]string `json:"devices,omitempty" yaml:"devices,omitempty"`
+	Addresses        []string                     `json:"addresses,omitempty" yaml:"addresses,omitempty"`
+	ImageFingerprint string                       `json:"image_fingerprint,omitempty" yaml:"image_fingerprint,omitempty"`
+}
+
+type LxdCDrift struct {
+	Field    string `json:"field" yaml:"field"`
+	Expected string `json:"expected" yaml:"expected"`
+	Current  string `json:"current" yaml:"current"`
+}
+
+type LxdCNodeStatus struct {
+	Name             string      `json:"name" yaml:"name"`
+	Group            string      `json:"group" yaml:"group"`
+	Present          bool        `json:"present" yaml:"present"`
+	Running          bool        `json:"running" yaml:"running"`
+	Addresses        []string    `json:"addresses,omitempty" yaml:"addresses,omitempty"`
+	ImageSource      string      `json:"image_source" yaml:"image_source"`
+	ImageFingerprint string      `json:"image_fingerprint,omitempty" yaml:"image_fingerprint,omitempty"`
+	Drifts           []LxdCDrift `json:"drifts,omitempty" yaml:"drifts,omitempty"`
+	Errors           []string    `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+func (s *LxdCNodeStatus) HasDrift() bool { return len(s.Drifts) > 0 }
+
+func (s *LxdCNodeStatus) AddDrift(field, expected, current string) {
+	s.Drifts = append(s.Drifts, LxdCDrift{
+		Field:    field,
+		Expected: expected,
+		Current:  current,
+	})
+}
+
+// Compare the profiles, the config and the devices of the instance
+// with the expected values and register the drifts found.
+func (s *LxdCNodeStatus) CompareInstance(info *LxdCInstanceInfo,
+	profiles []string, config map[string]string,
+	devices map[string]map[string]string) {
+
+	if len(profiles) > 0 &&
+		strings.Join(profiles, ",") != strings.Join(info.Profiles, ",") {
+		s.AddDrift("profiles",
+			strings.Join(profiles, ","), strings.Join(info.Profiles, ","))
+	}
+
+	keys := []string{}
+	for k := range config {
+		if !isTrackingKey(k) {
+			keys = append(keys, k)
+		}
+	}
+	// Config keys and labels removed from the node are yet present in
+	// the instance. The keys not set by lxd-compose are ignored.
+	for _, k := range GetManagedConfigKeys(info.Config) {
+		if _, ok := config[k]; !ok {
+			if _, ok := info.Config[k]; ok {
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		expected, current := config[k], info.Config[k]
+		if expected == current {
+			continue
+		}
+
+		field := fmt.Sprintf("config.%s", k)
+		if strings.HasPrefix(k, "user.") {
+			field = fmt.Sprintf("label.%s", strings.TrimPrefix(k, "user."))
+		}
+		s.AddDrift(field, expected, current)
+	}
+
+	names := []string{}
+	for d := range devices {
+		names = append(names, d)
+	}
+	// Devices removed from the node are yet present in the instance.
+	// The devices not set by lxd-compose are ignored.
+	for _, d := range GetManagedDevices(info.Config) {
+		if _, ok := devices[d]; !ok {
+			if _, ok := info.Devices[d]; ok {
+				names = append(names, d)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	for _, d := range names {
+		expected, current := devices[d], info.Devices[d]
+		if maps.Equal(expected, current) {
+			continue
+		}
+		s.AddDrift(fmt.Sprintf("devices.%s", d),
+			deviceToString(expected), deviceToString(current))
+	}
+}
+
+func deviceToString(device map[string]string) string {
+	opts := []string{}
+	for k, v := range device {
+		opts = append(opts, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(opts)
+	return strings.Join(opts, ",")
+}
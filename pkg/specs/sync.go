@@ -0,0 +1,257 @@
+/*
+Copyright © 2020-2026 Daniele Rondina <geaaru@macaronios.org>
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Script executed on the node to retrieve the manifest of the files
+// of the destination of an incremental sync with size and mtime.
+const SyncManifestScript = `[ -e "$LXDC_SYNC_ROOT" ] || exit 0
+cd "$LXDC_SYNC_ROOT" || exit 0
+find . -mindepth 1 $LXDC_SYNC_DEPTH -type d -exec printf 'd %s\n' {} +
+find . -mindepth 1 $LXDC_SYNC_DEPTH \( -type f -o -type l \) -exec stat -c 'f %s %Y %n' {} +
+exit 0`
+
+// Script executed on the node to retrieve the sha256 checksums of the
+// files in LXDC_SYNC_FILES (one path for line). The checksums are
+// optional, without sha256sum on the node the files are pushed.
+const SyncChecksumScript = `cd "$LXDC_SYNC_ROOT" || exit 0
+printf '%s\n' "$LXDC_SYNC_FILES" | while IFS= read -r f; do
+  [ -n "$f" ] && sha256sum "./$f" 2>/dev/null
+done
+exit 0`
+
+// A file or a directory of an incremental sync with the path
+// relative to the root of the sync.
+type LxdCSyncFile struct {
+	Path   string `json:"path" yaml:"path"`
+	Dir    bool   `json:"dir,omitempty" yaml:"dir,omitempty"`
+	Size   int64  `json:"size,omitempty" yaml:"size,omitempty"`
+	Mtime  int64  `json:"mtime,omitempty" yaml:"mtime,omitempty"`
+	Sha256 string `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+}
+
+type LxdCSyncSummary struct {
+	Added     []string `json:"added,omitempty" yaml:"added,omitempty"`
+	Updated   []string `json:"updated,omitempty" yaml:"updated,omitempty"`
+	Deleted   []string `json:"deleted,omitempty" yaml:"deleted,omitempty"`
+	NewDirs   []string `json:"new_dirs,omitempty" yaml:"new_dirs,omitempty"`
+	Unchanged int      `json:"unchanged" yaml:"unchanged"`
+}
+
+func (r *LxdCSyncResource) IsIncremental() bool {
+	return r.Incremental || r.Delete
+}
+
+// Return the path of the node where the source is pushed. It follows
+// the logic of RecursivePushFile: a source with the final slash is
+// copied inside the destination.
+func GetSyncRemoteRoot(source, target string, sourceIsDir bool) string {
+	if strings.HasSuffix(source, "/") {
+		return path.Clean(target)
+	}
+
+	if sourceIsDir || strings.HasSuffix(target, "/") {
+		return path.Join(target, filepath.Base(source))
+	}
+
+	return path.Clean(target)
+}
+
+// Parse the output of the SyncManifestScript.
+func ParseSyncManifest(out string) (map[string]*LxdCSyncFile, error) {
+	ans := make(map[string]*LxdCSyncFile, 0)
+
+	getFile := func(p string) *LxdCSyncFile {
+		p = strings.TrimPrefix(p, "./")
+		if _, ok := ans[p]; !ok {
+			ans[p] = &LxdCSyncFile{Path: p}
+		}
+		return ans[p]
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "d "):
+			getFile(line[2:]).Dir = true
+
+		case strings.HasPrefix(line, "f "):
+			fields := strings.SplitN(line[2:], " ", 3)
+			if len(fields) != 3 {
+				return ans, fmt.Errorf("invalid manifest line: %s", line)
+			}
+			size, err := strconv.ParseInt(fields[0], 10, 64)
+			if err != nil {
+				return ans, fmt.Errorf("invalid size on manifest line: %s", line)
+			}
+			mtime, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return ans, fmt.Errorf("invalid mtime on manifest line: %s", line)
+			}
+			f := getFile(fields[2])
+			f.Size = size
+			f.Mtime = mtime
+
+		case len(line) > 66 && line[64:66] == "  ":
+			getFile(line[66:]).Sha256 = line[:64]
+
+		default:
+			return ans, fmt.Errorf("invalid manifest line: %s", line)
+		}
+	}
+
+	return ans, nil
+}
+
+// Compare the local files with the files of the node. The files with
+// the same size and a local mtime newer than the remote mtime are
+// compared with the checksums: the remote checksums are retrieved with
+// remoteChecksum only for these files and the checksum function is
+// called for the local files. Without the remote checksum the file is
+// updated.
+func CompareSyncFiles(local []*LxdCSyncFile, remote map[string]*LxdCSyncFile,
+	delete bool,
+	remoteChecksum func(paths []string) (map[string]*LxdCSyncFile, error),
+	checksum func(f *LxdCSyncFile) (string, error)) (*LxdCSyncSummary, error) {
+
+	ans := &LxdCSyncSummary{
+		Added:   []string{},
+		Updated: []string{},
+		Deleted: []string{},
+		NewDirs: []string{},
+	}
+	mlocal := make(map[string]bool, len(local))
+	toVerify := []*LxdCSyncFile{}
+
+	compareSha256 := func(l *LxdCSyncFile, remoteSha256 string) error {
+		if l.Sha256 == "" {
+			sum, err := checksum(l)
+			if err != nil {
+				return err
+			}
+			l.Sha256 = sum
+		}
+		if l.Sha256 != remoteSha256 {
+			ans.Updated = append(ans.Updated, l.Path)
+		} else {
+			ans.Unchanged++
+		}
+		return nil
+	}
+
+	for _, l := range local {
+		mlocal[l.Path] = true
+		r, ok := remote[l.Path]
+
+		if l.Dir {
+			if !ok {
+				ans.NewDirs = append(ans.NewDirs, l.Path)
+			} else if !r.Dir {
+				// A file replaced by a directory.
+				ans.Deleted = append(ans.Deleted, l.Path)
+				ans.NewDirs = append(ans.NewDirs, l.Path)
+			}
+			continue
+		}
+
+		switch {
+		case !ok:
+			ans.Added = append(ans.Added, l.Path)
+		case r.Dir:
+			ans.Deleted = append(ans.Deleted, l.Path)
+			ans.Added = append(ans.Added, l.Path)
+		case r.Size != l.Size:
+			ans.Updated = append(ans.Updated, l.Path)
+		case r.Sha256 != "":
+			err := compareSha256(l, r.Sha256)
+			if err != nil {
+				return ans, err
+			}
+		case l.Mtime > r.Mtime:
+			toVerify = append(toVerify, l)
+		default:
+			ans.Unchanged++
+		}
+	}
+
+	if len(toVerify) > 0 {
+		sums := make(map[string]*LxdCSyncFile, 0)
+		if remoteChecksum != nil {
+			paths := []string{}
+			for _, l := range toVerify {
+				paths = append(paths, l.Path)
+			}
+
+			var err error
+			sums, err = remoteChecksum(paths)
+			if err != nil {
+				return ans, err
+			}
+		}
+
+		for _, l := range toVerify {
+			r, ok := sums[l.Path]
+			if !ok || r.Sha256 == "" {
+				ans.Updated = append(ans.Updated, l.Path)
+				continue
+			}
+			err := compareSha256(l, r.Sha256)
+			if err != nil {
+				return ans, err
+			}
+		}
+	}
+
+	if delete {
+		for p := range remote {
+			if !mlocal[p] {
+				ans.Deleted = append(ans.Deleted, p)
+			}
+		}
+	}
+
+	// Remove the entries inside the directories already deleted.
+	sort.Strings(ans.Deleted)
+	deleted := []string{}
+	mdeleted := make(map[string]bool, 0)
+	for _, p := range ans.Deleted {
+		if mdeleted[p] {
+			continue
+		}
+		inDeletedDir := false
+		for d := path.Dir(p); d != "." && d != "/"; d = path.Dir(d) {
+			if mdeleted[d] {
+				inDeletedDir = true
+				break
+			}
+		}
+		if !inDeletedDir {
+			deleted = append(deleted, p)
+		}
+		mdeleted[p] = true
+	}
+	ans.Deleted = deleted
+
+	sort.Strings(ans.Updated)
+	sort.Strings(ans.NewDirs)
+
+	return ans, nil
+}
+
+func (s *LxdCSyncSummary) String() string {
+	return fmt.Sprintf("added %d, updated %d, deleted %d, unchanged %d",
+		len(s.Added), len(s.Updated), len(s.Deleted), s.Unchanged)
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -154,6 +155,11 @@ func CompileGroupFiles(group *specs.LxdCGroup, compiler LxdCTemplateCompiler, op
 			return err
 		}
 
+		err = applyFileOpts(destFile, &s.LxdCFileOpts)
+		if err != nil {
+			return err
+		}
+
 		log.GetDefaultLogger().Info(" " + sourceFile + " -> " + destFile + " OK")
 	}
 
@@ -198,6 +204,11 @@ func CompileProjectFiles(proj *specs.LxdCProject, compiler LxdCTemplateCompiler,
 			return err
 		}
 
+		err = applyFileOpts(destFile, &s.LxdCFileOpts)
+		if err != nil {
+			return err
+		}
+
 		log.GetDefaultLogger().Info(" " + sourceFile + " -> " + destFile + " OK")
 	}
 
@@ -205,7 +216,7 @@ func CompileProjectFiles(proj *specs.LxdCProject, compiler LxdCTemplateCompiler,
 }
 
 func CompileNodeFiles(node specs.LxdCNode, compiler LxdCTemplateCompiler, opts CompilerOpts) error {
-	var sourceFile, destFile, baseDir string
+	var sourceFile, destFile string
 	var targets []specs.LxdCConfigTemplate = []specs.LxdCConfigTemplate{}
 	logger := log.GetDefaultLogger()
 
@@ -223,6 +234,10 @@ func CompileNodeFiles(node specs.LxdCNode, compiler LxdCTemplateCompiler, opts C
 		targets = node.ConfigTemplates
 	}
 
+	// The templates with push enabled are compiled in memory
+	// and pushed directly to the node.
+	targets = filterPushTemplates(targets, false)
+
 	if len(targets) == 0 {
 		return nil
 	}
@@ -240,20 +255,11 @@ func CompileNodeFiles(node specs.LxdCNode, compiler LxdCTemplateCompiler, opts C
 		}
 	}
 
-	envBaseAbs, err := filepath.Abs(compiler.GetEnvBaseDir())
+	baseDir, err := getNodeBaseDir(&node, compiler)
 	if err != nil {
 		return err
 	}
 
-	if filepath.IsAbs(node.SourceDir) {
-		baseDir, err = filepath.Abs(node.SourceDir)
-		if err != nil {
-			return err
-		}
-	} else {
-		baseDir = filepath.Join(envBaseAbs, node.SourceDir)
-	}
-
 	waitGroup := &sync.WaitGroup{}
 	sem := semaphore.NewWeighted(int64(opts.Concurrency))
 	ctx := context.TODO()
@@ -277,7 +283,7 @@ func CompileNodeFiles(node specs.LxdCNode, compiler LxdCTemplateCompiler, opts C
 
 		waitGroup.Add(1)
 		go compileRouting(compiler, ch, sem, waitGroup,
-			sourceFile, destFile, &ctx)
+			sourceFile, destFile, &s.LxdCFileOpts, &ctx)
 	}
 
 	nTargets := len(targets)
@@ -321,7 +327,8 @@ func CompileNodeFiles(node specs.LxdCNode, compiler LxdCTemplateCompiler, opts C
 func compileRouting(compiler LxdCTemplateCompiler,
 	channel chan helpers.ChannelError,
 	sem *semaphore.Weighted, waitGroup *sync.WaitGroup,
-	sourceFile, destFile string, ctx *context.Context) {
+	sourceFile, destFile string, fileOpts *specs.LxdCFileOpts,
+	ctx *context.Context) {
 
 	defer waitGroup.Done()
 	err := sem.Acquire(*ctx, 1)
@@ -335,6 +342,9 @@ func compileRouting(compiler LxdCTemplateCompiler,
 	defer sem.Release(1)
 
 	err = compiler.Compile(sourceFile, destFile)
+	if err == nil {
+		err = applyFileOpts(destFile, fileOpts)
+	}
 	if err != nil {
 		channel <- helpers.ChannelError{
 			Error:   err,
@@ -349,3 +359,103 @@ func compileRouting(compiler LxdCTemplateCompiler,
 	}
 	return
 }
+
+// Set the permissions of the compiled file. The ownership is applied
+// only on the templates pushed to the nodes.
+func applyFileOpts(destFile string, opts *specs.LxdCFileOpts) error {
+	mode, err := opts.GetMode()
+	if err != nil {
+		return err
+	}
+
+	if mode != nil {
+		err = os.Chmod(destFile, *mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func filterPushTemplates(targets []specs.LxdCConfigTemplate, push bool) []specs.LxdCConfigTemplate {
+	ans := []specs.LxdCConfigTemplate{}
+	for _, ct := range targets {
+		if ct.Push == push {
+			ans = append(ans, ct)
+		}
+	}
+	return ans
+}
+
+func getNodeBaseDir(node *specs.LxdCNode, compiler LxdCTemplateCompiler) (string, error) {
+	envBaseAbs, err := filepath.Abs(compiler.GetEnvBaseDir())
+	if err != nil {
+		return "", err
+	}
+
+	if filepath.IsAbs(node.SourceDir) {
+		return filepath.Abs(node.SourceDir)
+	}
+
+	return filepath.Join(envBaseAbs, node.SourceDir), nil
+}
+
+type LxdCRenderedTemplate struct {
+	Template *specs.LxdCConfigTemplate
+	Content  []byte
+}
+
+// Compile in memory the config templates of the node with push enabled.
+// The destination of the templates is a path of the node.
+func RenderNodePushFiles(node specs.LxdCNode, compiler LxdCTemplateCompiler) ([]LxdCRenderedTemplate, error) {
+	ans := []LxdCRenderedTemplate{}
+
+	targets := filterPushTemplates(node.ConfigTemplates, true)
+	if len(targets) == 0 {
+		return ans, nil
+	}
+
+	// The jinja2 engine writes the source, the vars and the
+	// compiled file on the host.
+	if _, ok := compiler.(*Jinja2Compiler); ok {
+		return ans, errors.New("push is not supported with the jinja2 engine")
+	}
+
+	// Set node key with current node
+	(*compiler.GetVars())["node"] = node
+
+	for k, v := range node.Labels {
+		(*compiler.GetVars())[k] = v
+	}
+
+	baseDir, err := getNodeBaseDir(&node, compiler)
+	if err != nil {
+		return ans, err
+	}
+
+	for idx := range targets {
+		sourceFile := filepath.Join(baseDir, targets[idx].Source)
+		if filepath.IsAbs(targets[idx].Source) {
+			sourceFile = targets[idx].Source
+		}
+
+		data, err := os.ReadFile(sourceFile)
+		if err != nil {
+			return ans, err
+		}
+
+		content, err := compiler.CompileRaw(string(data))
+		if err != nil {
+			return ans, fmt.Errorf("error on compile template %s: %s",
+				sourceFile, err.Error())
+		}
+
+		ans = append(ans, LxdCRenderedTemplate{
+			Template: &targets[idx],
+			Content:  []byte(content),
+		})
+	}
+
+	return ans, nil
+}
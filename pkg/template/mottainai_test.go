@@ -20,6 +20,11 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package template_test
 
 import (
+	"os"
+	"path/filepath"
+	"syscall"
+
+	log "github.com/MottainaiCI/lxd-compose/pkg/logger"
 	specs "github.com/MottainaiCI/lxd-compose/pkg/specs"
 	. "github.com/MottainaiCI/lxd-compose/pkg/template"
 
@@ -85,4 +90,113 @@ k3: foo
 		})
 
 	})
+
+	Context("Push templates", func() {
+
+		proj := &specs.LxdCProject{
+			Name: "project1",
+			Environments: []specs.LxdCEnvVars{
+				{
+					EnvVars: map[string]interface{}{
+						"key1": "value1",
+					},
+				},
+			},
+		}
+
+		It("Render in memory", func() {
+			dir, err := os.MkdirTemp("", "lxd-compose-test")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(dir)
+
+			err = os.WriteFile(filepath.Join(dir, "app.conf.tmpl"),
+				[]byte("k1: {{ .key1 }}\nnode: {{ .node.Name }}\n"), 0644)
+			Expect(err).Should(BeNil())
+
+			c := NewMottainaiCompiler(proj)
+			c.SetEnvBaseDir(dir)
+			c.InitVars()
+
+			node := specs.LxdCNode{
+				Name: "node1",
+				ConfigTemplates: []specs.LxdCConfigTemplate{
+					{Source: "app.conf.tmpl", Destination: filepath.Join(dir, "local.conf")},
+					{Source: "app.conf.tmpl", Destination: "/etc/app.conf", Push: true},
+				},
+			}
+
+			files, err := RenderNodePushFiles(node, c)
+			Expect(err).Should(BeNil())
+			Expect(len(files)).To(Equal(1))
+			Expect(files[0].Template.Destination).To(Equal("/etc/app.conf"))
+			Expect(string(files[0].Content)).To(Equal("k1: value1\nnode: node1\n"))
+
+			_, err = os.Stat(filepath.Join(dir, "local.conf"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("Reject the jinja2 engine", func() {
+			dir, err := os.MkdirTemp("", "lxd-compose-test")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(dir)
+
+			c := NewJinja2Compiler(proj)
+			c.SetEnvBaseDir(dir)
+			c.InitVars()
+
+			node := specs.LxdCNode{
+				Name: "node1",
+				ConfigTemplates: []specs.LxdCConfigTemplate{
+					{Source: "app.conf.tmpl", Destination: "/etc/app.conf", Push: true},
+				},
+			}
+
+			files, err := RenderNodePushFiles(node, c)
+			Expect(err).ShouldNot(BeNil())
+			Expect(files).To(BeEmpty())
+		})
+
+		It("Compile on host without ownership", func() {
+			dir, err := os.MkdirTemp("", "lxd-compose-test")
+			Expect(err).Should(BeNil())
+			defer os.RemoveAll(dir)
+
+			log.NewLxdCLogger(specs.NewLxdComposeConfig(nil)).SetAsDefault()
+
+			err = os.WriteFile(filepath.Join(dir, "app.conf.tmpl"),
+				[]byte("k1: {{ .key1 }}\n"), 0644)
+			Expect(err).Should(BeNil())
+
+			c := NewMottainaiCompiler(proj)
+			c.SetEnvBaseDir(dir)
+			c.InitVars()
+
+			uid := int64(12345)
+			node := specs.LxdCNode{
+				Name: "node1",
+				ConfigTemplates: []specs.LxdCConfigTemplate{
+					{
+						Source:       "app.conf.tmpl",
+						Destination:  "local.conf",
+						LxdCFileOpts: specs.LxdCFileOpts{Mode: "0600"},
+					},
+					{
+						Source:       "app.conf.tmpl",
+						Destination:  "/etc/app.conf",
+						Push:         true,
+						LxdCFileOpts: specs.LxdCFileOpts{Uid: &uid, Gid: &uid},
+					},
+				},
+			}
+
+			err = CompileNodeFiles(node, c, CompilerOpts{Concurrency: 1})
+			Expect(err).Should(BeNil())
+
+			info, err := os.Stat(filepath.Join(dir, "local.conf"))
+			Expect(err).Should(BeNil())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+			Expect(info.Sys().(*syscall.Stat_t).Uid).To(Equal(uint32(os.Getuid())))
+		})
+
+	})
 })